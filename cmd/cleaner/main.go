@@ -1,27 +1,152 @@
+// Package main contains the main entrypoint for the cleaner.
 package main
 
 import (
 	"fmt"
+	"log"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/sergiotejon/pipeManagerLauncher/internal/app/cleaner"
+	"github.com/sergiotejon/pipeManagerLauncher/internal/pkg/configcmd"
+	"github.com/sergiotejon/pipeManagerLauncher/internal/pkg/k8s"
+	"github.com/sergiotejon/pipeManagerLauncher/internal/pkg/logging"
+	"github.com/sergiotejon/pipeManagerLauncher/internal/pkg/version"
+	"github.com/sergiotejon/pipeManagerLauncher/pkg/config"
 )
 
-var version = "dev"
+const (
+	defaultConfigFile = "/etc/pipe-manager/config.yaml" // defaultConfigFile is the default configuration file
+	defaultOutput     = cleaner.OutputText              // defaultOutput is the default format of the report
+)
 
-//TIP To run your code, right-click the code and select <b>Run</b>. Alternatively, click
-// the <icon src="AllIcons.Actions.Execute"/> icon in the gutter and select the <b>Run</b> menu item from here.
+var (
+	configFile  string // configFile is the path to the configuration file
+	dryRun      bool   // dryRun is a flag to report the resources to delete without deleting them
+	selector    string // selector is the label selector of the resources to delete, it overrides the configuration
+	output      string // output is the format of the report (text or json)
+	showVersion bool   // showVersion is a flag to show the version
+)
 
+// main is the entrypoint for the cleaner
+// It sets up the root command and executes the application
 func main() {
-	//TIP Press <shortcut actionId="ShowIntentionActions"/> when your caret is at the underlined or highlighted text
-	// to see how GoLand suggests fixing it.
-	s := "gopher"
-	fmt.Println("Hello and welcome, %s!", s)
+	rootCmd := &cobra.Command{
+		Use:   "cleaner",
+		Short: "Delete the expired pipelines, namespaces and launcher jobs",
+		Long: "Delete the Pipeline resources older than the configured age or whose branch was deleted, the namespaces " +
+			"managed by pipe-manager without pipelines and the finished launcher jobs. It is meant to run as a CronJob.",
+		Run: func(cmd *cobra.Command, args []string) {
+			// Show version
+			if showVersion {
+				fmt.Println(version.GetVersion())
+				os.Exit(0)
+			}
 
-	for i := 1; i <= 5; i++ {
-		//TIP You can try debugging your code. We have set one <icon src="AllIcons.Debugger.Db_set_breakpoint"/> breakpoint
-		// for you, but you can always add more by pressing <shortcut actionId="ToggleLineBreakpoint"/>. To start your debugging session,
-		// right-click your code in the editor and select the <b>Debug</b> option.
-		fmt.Println("i =", 100/i)
+			// Run the application
+			app(cmd.Flags().Changed("selector"))
+		},
+	}
+
+	rootCmd.Flags().StringVarP(&configFile, "config", "c", defaultConfigFile, "Path to the config file")
+	rootCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Report the resources to delete without deleting them")
+	rootCmd.Flags().StringVarP(&selector, "selector", "l", "", "Label selector of the pipelines and namespaces to delete (overrides cleaner.selector)")
+	rootCmd.Flags().StringVarP(&output, "output", "o", defaultOutput, "Format of the report: text or json")
+	rootCmd.Flags().BoolVarP(&showVersion, "version", "v", false, "Print the version")
+	configcmd.AddFlags(rootCmd.Flags())
+
+	rootCmd.AddCommand(configcmd.NewCommand())
+
+	if err := rootCmd.Execute(); err != nil {
+		log.Fatalf("Error executing command: %v", err)
 	}
 }
 
-//TIP See GoLand help at <a href="https://www.jetbrains.com/help/go/">jetbrains.com/help/go/</a>.
-// Also, you can try interactive lessons for GoLand by selecting 'Help | Learn IDE Features' from the main menu.
+// app is the main application function
+// It loads the configuration, sets up the logger, deletes the expired resources and prints the report
+// It exits with a non-zero code if any resource could not be deleted
+func app(selectorChanged bool) {
+	var err error
+
+	if output != cleaner.OutputText && output != cleaner.OutputJSON {
+		log.Fatalf("Invalid output format '%s': must be text or json", output)
+	}
+
+	// Load configuration
+	err = configcmd.ApplyFlags()
+	if err != nil {
+		log.Fatalf("Error in configuration flags: %v", err)
+	}
+
+	err = config.LoadCommonConfig(configFile)
+	if err != nil {
+		log.Fatalf("Error loading common config: %v", err)
+	}
+
+	// The webhook and launcher configurations give the namespace and the retention of the launcher jobs
+	err = config.LoadWebhookConfig(configFile)
+	if err != nil {
+		log.Fatalf("Error loading webhook config: %v", err)
+	}
+
+	err = config.LoadLauncherConfig(configFile)
+	if err != nil {
+		log.Fatalf("Error loading launcher config: %v", err)
+	}
+
+	err = config.LoadCleanerConfig(configFile)
+	if err != nil {
+		log.Fatalf("Error loading cleaner config: %v", err)
+	}
+
+	configcmd.PrintEffectiveConfig()
+
+	// Setup Logger
+	err = logging.SetupLogger(config.Common.Data.Log.Level, config.Common.Data.Log.Format, config.Common.Data.Log.File)
+	if err != nil {
+		log.Fatalf("Error configuring the logger: %v", err)
+	}
+
+	if !selectorChanged {
+		selector = config.Cleaner.Data.Selector
+	}
+
+	logging.Logger.Info("Cleaner starting up...")
+	logging.Logger.Info("Setup", "configFile", configFile,
+		"dryRun", dryRun,
+		"selector", selector,
+		"pipelineMaxAge", config.Cleaner.Data.PipelineMaxAge,
+		"deletedBranches", config.Cleaner.Data.DeletedBranches,
+		"namespaceMaxAge", config.Cleaner.Data.NamespaceMaxAge,
+		"launcherJobs", config.Cleaner.Data.LauncherJobs)
+
+	client, err := k8s.GetKubernetesClient()
+	if err != nil {
+		logging.Logger.Error("Error creating the Kubernetes client", "error", fmt.Sprintf("%v", err))
+		os.Exit(1)
+	}
+
+	dynamicClient, err := k8s.GetDynamicClient()
+	if err != nil {
+		logging.Logger.Error("Error creating the Kubernetes dynamic client", "error", fmt.Sprintf("%v", err))
+		os.Exit(1)
+	}
+
+	report, err := cleaner.Run(client, dynamicClient, cleaner.Options{
+		DryRun:   dryRun,
+		Selector: selector,
+	})
+	if err != nil {
+		logging.Logger.Error("Error cleaning", "error", fmt.Sprintf("%v", err))
+	}
+	if report != nil {
+		if err := report.Write(os.Stdout, output); err != nil {
+			log.Fatalf("Error writing the report: %v", err)
+		}
+	}
+
+	if err != nil || report == nil || len(report.Errors) > 0 {
+		os.Exit(1)
+	}
+}
@@ -1,27 +1,153 @@
+// Package main contains the main entrypoint for the dashboard.
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/sergiotejon/pipeManagerLauncher/internal/app/dashboard"
+	"github.com/sergiotejon/pipeManagerLauncher/internal/pkg/configcmd"
+	"github.com/sergiotejon/pipeManagerLauncher/internal/pkg/k8s"
+	"github.com/sergiotejon/pipeManagerLauncher/internal/pkg/launcherjob"
+	"github.com/sergiotejon/pipeManagerLauncher/internal/pkg/logging"
+	"github.com/sergiotejon/pipeManagerLauncher/internal/pkg/version"
+	"github.com/sergiotejon/pipeManagerLauncher/pkg/config"
 )
 
-var version = "dev"
+const (
+	defaultConfigFile = "/etc/pipe-manager/config.yaml" // defaultConfigFile is the default configuration file
+	defaultListenAddr = ":8080"                         // defaultListenAddr is the default listen address
+	defaultLimit      = 100                             // defaultLimit is the default number of requests and pipelines shown
+)
 
-//TIP To run your code, right-click the code and select <b>Run</b>. Alternatively, click
-// the <icon src="AllIcons.Actions.Execute"/> icon in the gutter and select the <b>Run</b> menu item from here.
+var (
+	configFile  string // configFile is the path to the configuration file
+	listenAddr  string // listenAddr is the address to listen on
+	limit       int    // limit is the maximum number of requests and pipelines shown
+	showVersion bool   // showVersion is a flag to show the version
+)
 
+// main is the entrypoint for the dashboard
+// It sets up the root command and executes the application
 func main() {
-	//TIP Press <shortcut actionId="ShowIntentionActions"/> when your caret is at the underlined or highlighted text
-	// to see how GoLand suggests fixing it.
-	s := "gopher"
-	fmt.Println("Hello and welcome, %s!", s)
+	rootCmd := &cobra.Command{
+		Use:   "dashboard",
+		Short: "Read-only web UI of the launcher jobs and pipelines",
+		Run: func(cmd *cobra.Command, args []string) {
+			// Show version
+			if showVersion {
+				fmt.Println(version.GetVersion())
+				os.Exit(0)
+			}
+
+			// Run the application
+			app()
+		},
+	}
+
+	rootCmd.Flags().StringVarP(&configFile, "config", "c", defaultConfigFile, "Path to the config file")
+	rootCmd.Flags().StringVarP(&listenAddr, "listen", "l", defaultListenAddr, "Listen address")
+	rootCmd.Flags().IntVar(&limit, "limit", defaultLimit, "Maximum number of requests and pipelines shown, the newest ones. 0 shows all of them")
+	rootCmd.Flags().BoolVarP(&showVersion, "version", "v", false, "Print the version")
+	configcmd.AddFlags(rootCmd.Flags())
+
+	rootCmd.AddCommand(configcmd.NewCommand())
 
-	for i := 1; i <= 5; i++ {
-		//TIP You can try debugging your code. We have set one <icon src="AllIcons.Debugger.Db_set_breakpoint"/> breakpoint
-		// for you, but you can always add more by pressing <shortcut actionId="ToggleLineBreakpoint"/>. To start your debugging session,
-		// right-click your code in the editor and select the <b>Debug</b> option.
-		fmt.Println("i =", 100/i)
+	if err := rootCmd.Execute(); err != nil {
+		log.Fatalf("Error executing command: %v", err)
 	}
 }
 
-//TIP See GoLand help at <a href="https://www.jetbrains.com/help/go/">jetbrains.com/help/go/</a>.
-// Also, you can try interactive lessons for GoLand by selecting 'Help | Learn IDE Features' from the main menu.
+// app is the main application function
+// It loads the configuration, sets up the logger and starts the web server of the dashboard
+func app() {
+	var err error
+
+	// Load configuration
+	err = configcmd.ApplyFlags()
+	if err != nil {
+		log.Fatalf("Error in configuration flags: %v", err)
+	}
+
+	err = config.LoadCommonConfig(configFile)
+	if err != nil {
+		log.Fatalf("Error loading common config: %v", err)
+	}
+
+	// The launcher configuration gives the namespace of the launcher jobs
+	err = config.LoadLauncherConfig(configFile)
+	if err != nil {
+		log.Fatalf("Error loading launcher config: %v", err)
+	}
+
+	// The cleaner configuration gives the parameter of the pipelines with the branch
+	err = config.LoadCleanerConfig(configFile)
+	if err != nil {
+		log.Fatalf("Error loading cleaner config: %v", err)
+	}
+
+	configcmd.PrintEffectiveConfig()
+
+	// Setup Logger
+	err = logging.SetupLogger(config.Common.Data.Log.Level, config.Common.Data.Log.Format, config.Common.Data.Log.File)
+	if err != nil {
+		log.Fatalf("Error configuring the logger: %v", err)
+	}
+
+	namespace := launcherjob.GetNamespace()
+	logging.Logger.Info("Dashboard starting up...")
+	logging.Logger.Info("Setup", "configFile", configFile,
+		"listenAddr", listenAddr,
+		"namespace", namespace,
+		"refParam", config.Cleaner.Data.RefParam,
+		"limit", limit,
+		"logLevel", config.Common.Data.Log.Level,
+		"logFormat", config.Common.Data.Log.Format,
+		"logFile", config.Common.Data.Log.File)
+
+	client, err := k8s.GetKubernetesClient()
+	if err != nil {
+		logging.Logger.Error("Error creating the Kubernetes client", "error", fmt.Sprintf("%v", err))
+		os.Exit(1)
+	}
+
+	dynamicClient, err := k8s.GetDynamicClient()
+	if err != nil {
+		logging.Logger.Error("Error creating the Kubernetes dynamic client", "error", fmt.Sprintf("%v", err))
+		os.Exit(1)
+	}
+
+	server := &http.Server{
+		Addr:    listenAddr,
+		Handler: dashboard.New(client, dynamicClient, namespace, config.Cleaner.Data.RefParam, limit).Handler(),
+	}
+	go func() {
+		logging.Logger.Info("Starting HTTP server", "listenAddr", listenAddr)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logging.Logger.Error("HTTP server error", "error", fmt.Sprintf("%v", err))
+			os.Exit(1)
+		}
+	}()
+
+	// Wait for a termination signal
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGTERM, syscall.SIGINT)
+	<-sigChan
+	logging.Logger.Info("Received shutdown signal, stopping server...")
+
+	// Gracefully shutdown the server with a timeout
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		logging.Logger.Error("Error shutting down the server", "error", fmt.Sprintf("%v", err))
+	}
+}
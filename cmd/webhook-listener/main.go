@@ -8,7 +8,9 @@ import (
 
 	"github.com/spf13/cobra"
 
+	"github.com/sergiotejon/pipeManagerLauncher/internal/app/webhook-listener/databuilder"
 	"github.com/sergiotejon/pipeManagerLauncher/internal/app/webhook-listener/httpServer"
+	"github.com/sergiotejon/pipeManagerLauncher/internal/pkg/configcmd"
 	"github.com/sergiotejon/pipeManagerLauncher/internal/pkg/logging"
 	"github.com/sergiotejon/pipeManagerLauncher/internal/pkg/version"
 	"github.com/sergiotejon/pipeManagerLauncher/pkg/config"
@@ -28,6 +30,9 @@ var (
 // main is the entrypoint for the application
 // It sets up the root command and executes the application
 func main() {
+	// The CEL expressions of the configuration are compiled when it is loaded, validated or reloaded
+	config.SetExpressionCompiler(databuilder.CompileExpression, databuilder.ResetExpressions)
+
 	rootCmd := &cobra.Command{
 		Use:   "pipe-manager",
 		Short: "Pipe Manager CLI",
@@ -46,6 +51,10 @@ func main() {
 	rootCmd.Flags().StringVarP(&configFile, "config", "c", defaultConfigFile, "Path to the config file")
 	rootCmd.Flags().StringVarP(&listenAddr, "listen", "l", defaultListenAddr, "Listen address")
 	rootCmd.Flags().BoolVarP(&showVersion, "version", "v", false, "Print the version")
+	configcmd.AddFlags(rootCmd.Flags())
+
+	rootCmd.AddCommand(newTestCmd())
+	rootCmd.AddCommand(configcmd.NewCommand())
 
 	if err := rootCmd.Execute(); err != nil {
 		log.Fatalf("Error executing command: %v", err)
@@ -58,6 +67,11 @@ func app() {
 	var err error
 
 	// Load configuration
+	err = configcmd.ApplyFlags()
+	if err != nil {
+		log.Fatalf("Error in configuration flags: %v", err)
+	}
+
 	err = config.LoadCommonConfig(configFile)
 	if err != nil {
 		log.Fatalf("Error loading common config: %v", err)
@@ -73,6 +87,8 @@ func app() {
 		log.Fatalf("Error loading launcher config: %v", err)
 	}
 
+	configcmd.PrintEffectiveConfig()
+
 	// Setup Logger
 	err = logging.SetupLogger(config.Common.Data.Log.Level, config.Common.Data.Log.Format, config.Common.Data.Log.File)
 	if err != nil {
@@ -89,7 +105,7 @@ func app() {
 		"launcherImage", config.Launcher.Data.GetLauncherImage())
 
 	// Launch web server
-	err = httpServer.HttpServer(listenAddr)
+	err = httpServer.HttpServer(listenAddr, configFile)
 	if err != nil {
 		logging.Logger.Error("Error starting server", "error", fmt.Sprintf("%v", err))
 		panic(err)
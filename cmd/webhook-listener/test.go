@@ -0,0 +1,187 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/sergiotejon/pipeManagerLauncher/internal/app/webhook-listener/databuilder"
+	"github.com/sergiotejon/pipeManagerLauncher/internal/app/webhook-listener/httpServer"
+	"github.com/sergiotejon/pipeManagerLauncher/internal/pkg/configcmd"
+	"github.com/sergiotejon/pipeManagerLauncher/internal/pkg/logging"
+	"github.com/sergiotejon/pipeManagerLauncher/pkg/config"
+)
+
+var (
+	testRoute   string   // testRoute is the path of the route to test
+	testPayload string   // testPayload is the file with the payload of the webhook
+	testHeaders []string // testHeaders are the headers of the webhook in "Name: value" format
+	testGolden  string   // testGolden is the file with the expected result
+	testUpdate  bool     // testUpdate writes the result into the golden file instead of checking it
+	testVerbose bool     // testVerbose shows the logs of the data builder
+)
+
+// TestResult is the result of running the data builder with a payload
+// It contains the pipelines that would be launched, or the reason why the delivery would be filtered
+type TestResult struct {
+	Filtered  string                      `json:"filtered,omitempty"`
+	Pipelines []*databuilder.PipelineData `json:"pipelines,omitempty"`
+}
+
+// newTestCmd returns the test command
+// It runs the data builder of a route with a payload file locally and prints the resulting pipelines, or checks them
+// against a golden file, so the routes configuration can be tested without a cluster
+func newTestCmd() *cobra.Command {
+	testCmd := &cobra.Command{
+		Use:   "test",
+		Short: "Test a route of the configuration with a webhook payload",
+		Example: "  webhook-listener test -c configs/config_example.yaml --route /custom \\\n" +
+			"    --payload docs/webhook-samples/custom/my_custom_webhook.json \\\n" +
+			"    --golden docs/webhook-samples/custom/my_custom_webhook.golden.json",
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := runTest(); err != nil {
+				log.Fatalf("Test failed: %v", err)
+			}
+		},
+	}
+
+	testCmd.Flags().StringVarP(&configFile, "config", "c", defaultConfigFile, "Path to the config file")
+	testCmd.Flags().StringVarP(&testRoute, "route", "r", "", "Path of the route to test (e.g., /github)")
+	testCmd.Flags().StringVarP(&testPayload, "payload", "p", "", "File with the JSON payload of the webhook")
+	testCmd.Flags().StringArrayVarP(&testHeaders, "header", "H", []string{}, "Header of the webhook in 'Name: value' format. Can be repeated")
+	testCmd.Flags().StringVarP(&testGolden, "golden", "g", "", "File with the expected result to check")
+	testCmd.Flags().BoolVar(&testUpdate, "update", false, "Write the result into the golden file instead of checking it")
+	testCmd.Flags().BoolVar(&testVerbose, "verbose", false, "Show the logs of the data builder")
+	configcmd.AddFlags(testCmd.Flags())
+
+	_ = testCmd.MarkFlagRequired("route")
+	_ = testCmd.MarkFlagRequired("payload")
+
+	return testCmd
+}
+
+// runTest loads the webhook configuration, runs the data builder and prints or checks the result
+func runTest() error {
+	if err := configcmd.ApplyFlags(); err != nil {
+		return err
+	}
+	if err := config.LoadWebhookConfig(configFile); err != nil {
+		return fmt.Errorf("error loading webhook config: %w", err)
+	}
+	configcmd.PrintEffectiveConfig()
+
+	// Logs are sent to stderr, so the output can be redirected to a golden file
+	logLevel := "error"
+	if testVerbose {
+		logLevel = "debug"
+	}
+	if err := logging.SetupLogger(logLevel, "text", "stderr"); err != nil {
+		return err
+	}
+
+	result, err := buildTestResult()
+	if err != nil {
+		return err
+	}
+
+	output, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return err
+	}
+	output = append(output, '\n')
+
+	switch {
+	case testGolden == "":
+		fmt.Print(string(output))
+	case testUpdate:
+		if err = os.WriteFile(testGolden, output, 0o644); err != nil {
+			return err
+		}
+		fmt.Printf("Golden file %s updated\n", testGolden)
+	default:
+		if err = checkGolden(testGolden, output); err != nil {
+			return err
+		}
+		fmt.Printf("Route %s matches golden file %s\n", testRoute, testGolden)
+	}
+
+	return nil
+}
+
+// buildTestResult builds the request as the HTTP server does and runs the data builder
+func buildTestResult() (*TestResult, error) {
+	body, err := os.ReadFile(testPayload)
+	if err != nil {
+		return nil, err
+	}
+	var jsonCheck interface{}
+	if err = json.Unmarshal(body, &jsonCheck); err != nil {
+		return nil, fmt.Errorf("invalid JSON payload: %w", err)
+	}
+
+	headers := make(map[string][]string)
+	for _, header := range testHeaders {
+		name, value, found := strings.Cut(header, ":")
+		if !found {
+			return nil, fmt.Errorf("invalid header '%s', expected 'Name: value'", header)
+		}
+		name = http.CanonicalHeaderKey(strings.TrimSpace(name))
+		headers[name] = append(headers[name], strings.TrimSpace(value))
+	}
+
+	job := httpServer.Job{
+		RequestURL: testRoute,
+		Method:     http.MethodPost,
+		Path:       testRoute,
+		Args:       map[string][]string{},
+		Headers:    headers,
+		Body:       json.RawMessage(body),
+	}
+	jsonData, err := json.MarshalIndent(job, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	pipelines, err := databuilder.Run(jsonData, testRoute, config.Webhook.Data.Routes)
+	var filtered *databuilder.FilteredError
+	if errors.As(err, &filtered) {
+		return &TestResult{Filtered: err.Error()}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &TestResult{Pipelines: pipelines}, nil
+}
+
+// checkGolden compares the output with the content of the golden file
+// Both are compared as JSON values, so the formatting of the golden file does not matter
+func checkGolden(golden string, output []byte) error {
+	expectedData, err := os.ReadFile(golden)
+	if err != nil {
+		return err
+	}
+
+	var expected, actual interface{}
+	if err = json.Unmarshal(expectedData, &expected); err != nil {
+		return fmt.Errorf("invalid golden file %s: %w", golden, err)
+	}
+	if err = json.Unmarshal(output, &actual); err != nil {
+		return err
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		return fmt.Errorf("result does not match golden file %s\n--- expected\n%s\n+++ actual\n%s",
+			golden, bytes.TrimSpace(expectedData), bytes.TrimSpace(output))
+	}
+
+	return nil
+}
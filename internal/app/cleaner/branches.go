@@ -0,0 +1,85 @@
+package cleaner
+
+import (
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	gitconfig "github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/storage/memory"
+
+	"github.com/sergiotejon/pipeManagerLauncher/internal/pkg/gitauth"
+	"github.com/sergiotejon/pipeManagerLauncher/pkg/config"
+)
+
+// branchChecker checks if the branches exist in the remote repositories
+// The references of each repository are read only once for each git authentication
+type branchChecker struct {
+	refs map[repositoryKey]map[string]bool // refs contains the references of each repository
+	errs map[repositoryKey]error           // errs contains the error reading each repository
+}
+
+// repositoryKey identifies a repository read with a git authentication
+type repositoryKey struct {
+	repository string
+	auth       *config.GitAuth
+}
+
+// newBranchChecker returns a new branchChecker
+func newBranchChecker() *branchChecker {
+	return &branchChecker{
+		refs: make(map[repositoryKey]map[string]bool),
+		errs: make(map[repositoryKey]error),
+	}
+}
+
+// exists returns true if the ref is a branch or a tag of the repository
+// The ref can be the full name of the reference (e.g., refs/heads/main) or its short name (e.g., main). The repository
+// is read with the given git authentication, or without authentication if it is nil
+func (b *branchChecker) exists(repository, ref string, auth *config.GitAuth) (bool, error) {
+	key := repositoryKey{repository: repository, auth: auth}
+	if err, ok := b.errs[key]; ok {
+		return false, err
+	}
+
+	refs, ok := b.refs[key]
+	if !ok {
+		var err error
+		refs, err = listRefs(repository, auth)
+		if err != nil {
+			b.errs[key] = err
+			return false, err
+		}
+		b.refs[key] = refs
+	}
+
+	if strings.HasPrefix(ref, "refs/") {
+		return refs[ref], nil
+	}
+	return refs["refs/heads/"+ref] || refs["refs/tags/"+ref], nil
+}
+
+// listRefs returns the names of the references of the remote repository
+// The relative paths of the credentials files of the git authentication are read from gitauth.CredentialsDir, where
+// the git secret must be mounted, as in the launcher jobs
+func listRefs(repository string, auth *config.GitAuth) (map[string]bool, error) {
+	authMethod, err := gitauth.AuthMethod(auth, repository)
+	if err != nil {
+		return nil, err
+	}
+
+	remote := git.NewRemote(memory.NewStorage(), &gitconfig.RemoteConfig{
+		Name: "origin",
+		URLs: []string{repository},
+	})
+
+	references, err := remote.List(&git.ListOptions{Auth: authMethod})
+	if err != nil {
+		return nil, err
+	}
+
+	refs := make(map[string]bool, len(references))
+	for _, reference := range references {
+		refs[reference.Name().String()] = true
+	}
+	return refs, nil
+}
@@ -0,0 +1,229 @@
+// Package cleaner deletes the resources left behind by the pipelines: the finished launcher jobs, the Pipeline
+// resources older than a threshold or whose branch was deleted, and the namespaces managed by pipe-manager that no
+// longer have pipelines.
+package cleaner
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/sergiotejon/pipeManagerLauncher/internal/pkg/launcherjob"
+	"github.com/sergiotejon/pipeManagerLauncher/internal/pkg/logging"
+	"github.com/sergiotejon/pipeManagerLauncher/pkg/config"
+)
+
+// managedBySelector selects the namespaces created by the launcher for the pipelines
+const managedBySelector = "app.kubernetes.io/managed-by=pipe-manager"
+
+// Kinds of the resources of the report
+const (
+	KindJob       = "Job"
+	KindPipeline  = "Pipeline"
+	KindNamespace = "Namespace"
+)
+
+// Item is a resource deleted, or skipped, by the cleaner along with the reason
+type Item struct {
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+	Reason    string `json:"reason"`
+}
+
+// Report is the summary of a run of the cleaner
+// In dry run mode the deleted resources are the ones that would be deleted
+type Report struct {
+	DryRun  bool     `json:"dryRun"`
+	Deleted []Item   `json:"deleted"`
+	Skipped []Item   `json:"skipped"`
+	Errors  []string `json:"errors"`
+}
+
+// Options are the options of a run of the cleaner
+type Options struct {
+	DryRun   bool   // DryRun reports the resources to delete without deleting them
+	Selector string // Selector is a label selector restricting the pipelines and namespaces to delete
+}
+
+// Run deletes the expired resources according to the cleaner configuration and returns the report
+// The errors deleting a resource are added to the report, so the rest of the resources are processed anyway. It only
+// returns an error if the resources cannot be listed
+func Run(client kubernetes.Interface, dynamicClient dynamic.Interface, options Options) (*Report, error) {
+	report := &Report{
+		DryRun:  options.DryRun,
+		Deleted: []Item{},
+		Skipped: []Item{},
+		Errors:  []string{},
+	}
+
+	selector, err := labels.Parse(options.Selector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid selector: %w", err)
+	}
+
+	if config.Cleaner.Data.LauncherJobs {
+		if err = cleanJobs(client, report); err != nil {
+			return report, err
+		}
+	}
+
+	// Namespaces with pipelines left, so they are not deleted
+	remaining, err := cleanPipelines(dynamicClient, selector, report)
+	if err != nil {
+		return report, err
+	}
+
+	if config.Cleaner.Data.NamespaceMaxAge > 0 {
+		if err = cleanNamespaces(client, selector, remaining, report); err != nil {
+			return report, err
+		}
+	}
+
+	return report, nil
+}
+
+// cleanJobs deletes the finished launcher jobs according to the retention of the launcher and the routes
+func cleanJobs(client kubernetes.Interface, report *Report) error {
+	jobs, err := launcherjob.Clean(client, launcherjob.GetNamespace(), launcherjob.GetRetention, report.DryRun)
+	for _, job := range jobs {
+		report.Deleted = append(report.Deleted, Item{
+			Kind:      KindJob,
+			Namespace: job.Namespace,
+			Name:      job.Name,
+			Reason:    fmt.Sprintf("retention of route '%s'", job.Labels[launcherjob.LabelRoute]),
+		})
+	}
+	if err != nil {
+		report.Errors = append(report.Errors, fmt.Sprintf("launcher jobs: %v", err))
+	}
+	return nil
+}
+
+// cleanPipelines deletes the pipelines older than the maximum age or whose branch no longer exists
+// It returns the namespaces that still have pipelines
+func cleanPipelines(dynamicClient dynamic.Interface, selector labels.Selector, report *Report) (map[string]bool, error) {
+	list, err := dynamicClient.Resource(launcherjob.PipelineResource).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error listing pipelines: %w", err)
+	}
+
+	maxAge := time.Duration(config.Cleaner.Data.PipelineMaxAge) * time.Second
+	branches := newBranchChecker()
+	remaining := make(map[string]bool)
+
+	for _, item := range list.Items {
+		if !selector.Matches(labels.Set(item.GetLabels())) {
+			remaining[item.GetNamespace()] = true
+			continue
+		}
+
+		reason := ""
+		age := time.Since(item.GetCreationTimestamp().Time)
+		if maxAge > 0 && age > maxAge {
+			reason = fmt.Sprintf("older than %s", maxAge)
+		} else if config.Cleaner.Data.DeletedBranches {
+			reason = deletedBranchReason(&item, branches, report)
+		}
+		if reason == "" {
+			remaining[item.GetNamespace()] = true
+			continue
+		}
+
+		pipelineItem := Item{Kind: KindPipeline, Namespace: item.GetNamespace(), Name: item.GetName(), Reason: reason}
+		if isActive(&item) {
+			pipelineItem.Reason = reason + ", but it is running"
+			report.Skipped = append(report.Skipped, pipelineItem)
+			remaining[item.GetNamespace()] = true
+			continue
+		}
+
+		if !report.DryRun {
+			err = dynamicClient.Resource(launcherjob.PipelineResource).Namespace(item.GetNamespace()).Delete(context.TODO(), item.GetName(), metav1.DeleteOptions{})
+			if err != nil {
+				report.Errors = append(report.Errors, fmt.Sprintf("pipeline %s/%s: %v", item.GetNamespace(), item.GetName(), err))
+				remaining[item.GetNamespace()] = true
+				continue
+			}
+			logging.Logger.Info("Pipeline deleted", "pipeline", item.GetName(), "namespace", item.GetNamespace(), "reason", reason)
+		}
+		report.Deleted = append(report.Deleted, pipelineItem)
+	}
+
+	return remaining, nil
+}
+
+// deletedBranchReason returns the reason to delete the pipeline if its branch no longer exists in the repository,
+// or an empty string otherwise
+// The repository is read with the git authentication of the route of the pipeline, or the one of the launcher if the
+// pipeline has no route label. The pipelines without repository or branch are kept, and so are the ones whose
+// repository cannot be read, which are reported as errors
+func deletedBranchReason(item *unstructured.Unstructured, branches *branchChecker, report *Report) string {
+	params, _, _ := unstructured.NestedStringMap(item.Object, "spec", "params")
+	repository := params["REPOSITORY"]
+	ref := params[config.Cleaner.Data.RefParam]
+	if repository == "" || ref == "" {
+		return ""
+	}
+
+	auth := launcherjob.GetGitAuth(item.GetLabels()[launcherjob.LabelRoute])
+	exists, err := branches.exists(repository, ref, auth)
+	if err != nil {
+		report.Errors = append(report.Errors, fmt.Sprintf("pipeline %s/%s: cannot read the branches of %s: %v",
+			item.GetNamespace(), item.GetName(), repository, err))
+		return ""
+	}
+	if exists {
+		return ""
+	}
+	return fmt.Sprintf("branch '%s' deleted from %s", ref, repository)
+}
+
+// isActive returns true if the pipeline has running resources
+func isActive(item *unstructured.Unstructured) bool {
+	active, _, _ := unstructured.NestedSlice(item.Object, "status", "active")
+	return len(active) > 0
+}
+
+// cleanNamespaces deletes the namespaces managed by pipe-manager older than the maximum age without pipelines
+func cleanNamespaces(client kubernetes.Interface, selector labels.Selector, remaining map[string]bool, report *Report) error {
+	list, err := client.CoreV1().Namespaces().List(context.TODO(), metav1.ListOptions{
+		LabelSelector: managedBySelector,
+	})
+	if err != nil {
+		return fmt.Errorf("error listing namespaces: %w", err)
+	}
+
+	maxAge := time.Duration(config.Cleaner.Data.NamespaceMaxAge) * time.Second
+	for _, namespace := range list.Items {
+		if !selector.Matches(labels.Set(namespace.Labels)) || remaining[namespace.Name] {
+			continue
+		}
+		if namespace.Status.Phase == corev1.NamespaceTerminating || time.Since(namespace.CreationTimestamp.Time) <= maxAge {
+			continue
+		}
+
+		if !report.DryRun {
+			err = client.CoreV1().Namespaces().Delete(context.TODO(), namespace.Name, metav1.DeleteOptions{})
+			if err != nil {
+				report.Errors = append(report.Errors, fmt.Sprintf("namespace %s: %v", namespace.Name, err))
+				continue
+			}
+			logging.Logger.Info("Namespace deleted", "namespace", namespace.Name)
+		}
+		report.Deleted = append(report.Deleted, Item{
+			Kind:   KindNamespace,
+			Name:   namespace.Name,
+			Reason: fmt.Sprintf("no pipelines and older than %s", maxAge),
+		})
+	}
+
+	return nil
+}
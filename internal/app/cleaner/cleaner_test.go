@@ -0,0 +1,322 @@
+package cleaner
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/sergiotejon/pipeManagerLauncher/internal/pkg/launcherjob"
+	"github.com/sergiotejon/pipeManagerLauncher/internal/pkg/logging"
+	"github.com/sergiotejon/pipeManagerLauncher/pkg/config"
+)
+
+// testRefParam is a ref parameter other than the default one, so the tests check the configured one is read
+const testRefParam = "VARIABLE-BRANCH"
+
+func TestMain(m *testing.M) {
+	if err := logging.SetupLogger("error", "text", "stderr"); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+// newRepository returns the path of a local repository with the main branch
+func newRepository(t *testing.T) string {
+	t.Helper()
+	localDir := t.TempDir()
+	repository, err := git.PlainInit(localDir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	workTree, err := repository.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(localDir+"/README.md", []byte("readme"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = workTree.Add("README.md"); err != nil {
+		t.Fatal(err)
+	}
+	hash, err := workTree.Commit("commit", &git.CommitOptions{
+		Author: &object.Signature{Name: "test", Email: "test@localhost", When: time.Now()},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = repository.Storer.SetReference(plumbing.NewHashReference("refs/heads/main", hash)); err != nil {
+		t.Fatal(err)
+	}
+	return localDir
+}
+
+// newPipeline returns a Pipeline resource of the repository and branch created the given time ago
+func newPipeline(name, namespace, team, repository, branch string, age time.Duration, active bool) *unstructured.Unstructured {
+	item := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": launcherjob.PipelineResource.GroupVersion().String(),
+		"kind":       "Pipeline",
+		"spec": map[string]interface{}{
+			"params": map[string]interface{}{"REPOSITORY": repository, testRefParam: branch},
+		},
+	}}
+	if active {
+		item.Object["status"] = map[string]interface{}{"active": []interface{}{map[string]interface{}{"name": "task"}}}
+	}
+	item.SetName(name)
+	item.SetNamespace(namespace)
+	item.SetLabels(map[string]string{"team": team})
+	item.SetCreationTimestamp(metav1.NewTime(time.Now().Add(-age)))
+	return item
+}
+
+// newNamespace returns a namespace created the given time ago, managed by pipe-manager if the team is not empty
+func newNamespace(name, team string, age time.Duration) *corev1.Namespace {
+	namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+		Name:              name,
+		CreationTimestamp: metav1.NewTime(time.Now().Add(-age)),
+	}}
+	if team != "" {
+		namespace.Labels = map[string]string{"app.kubernetes.io/managed-by": "pipe-manager", "team": team}
+	}
+	return namespace
+}
+
+// items returns the sorted kinds, names and reasons of the items
+func items(list []Item) string {
+	lines := make([]string, 0, len(list))
+	for _, item := range list {
+		lines = append(lines, fmt.Sprintf("%s %s: %s", item.Kind, item.name(), item.Reason))
+	}
+	sort.Strings(lines)
+	return strings.Join(lines, "\n")
+}
+
+// deletions returns the number of delete calls of the actions
+func deletions(actions []k8stesting.Action) int {
+	count := 0
+	for _, action := range actions {
+		if action.GetVerb() == "delete" {
+			count++
+		}
+	}
+	return count
+}
+
+func TestRun(t *testing.T) {
+	repository := newRepository(t)
+	missing := t.TempDir() + "/missing"
+
+	tests := []struct {
+		name        string
+		cleaner     config.CleanerStruct
+		options     Options
+		wantDeleted []string
+		wantSkipped []string
+		wantErrors  int
+	}{
+		{
+			name:    "pipeline age",
+			cleaner: config.CleanerStruct{PipelineMaxAge: 3600, RefParam: testRefParam},
+			wantDeleted: []string{
+				"Pipeline repo-old/old: older than 1h0m0s",
+				"Pipeline repo-other/other-team: older than 1h0m0s",
+			},
+			wantSkipped: []string{"Pipeline repo-running/running: older than 1h0m0s, but it is running"},
+		},
+		{
+			name:    "namespace age",
+			cleaner: config.CleanerStruct{PipelineMaxAge: 3600, NamespaceMaxAge: 3600, RefParam: testRefParam},
+			wantDeleted: []string{
+				"Namespace empty: no pipelines and older than 1h0m0s",
+				"Namespace repo-old: no pipelines and older than 1h0m0s",
+				"Namespace repo-other: no pipelines and older than 1h0m0s",
+				"Pipeline repo-old/old: older than 1h0m0s",
+				"Pipeline repo-other/other-team: older than 1h0m0s",
+			},
+			wantSkipped: []string{"Pipeline repo-running/running: older than 1h0m0s, but it is running"},
+		},
+		{
+			name:    "label selector",
+			cleaner: config.CleanerStruct{PipelineMaxAge: 3600, NamespaceMaxAge: 3600, RefParam: testRefParam},
+			options: Options{Selector: "team=a"},
+			wantDeleted: []string{
+				"Namespace empty: no pipelines and older than 1h0m0s",
+				"Namespace repo-old: no pipelines and older than 1h0m0s",
+				"Pipeline repo-old/old: older than 1h0m0s",
+			},
+			wantSkipped: []string{"Pipeline repo-running/running: older than 1h0m0s, but it is running"},
+		},
+		{
+			name:    "dry run",
+			cleaner: config.CleanerStruct{PipelineMaxAge: 3600, NamespaceMaxAge: 3600, RefParam: testRefParam},
+			options: Options{DryRun: true},
+			wantDeleted: []string{
+				"Namespace empty: no pipelines and older than 1h0m0s",
+				"Namespace repo-old: no pipelines and older than 1h0m0s",
+				"Namespace repo-other: no pipelines and older than 1h0m0s",
+				"Pipeline repo-old/old: older than 1h0m0s",
+				"Pipeline repo-other/other-team: older than 1h0m0s",
+			},
+			wantSkipped: []string{"Pipeline repo-running/running: older than 1h0m0s, but it is running"},
+		},
+		{
+			name:    "deleted branches",
+			cleaner: config.CleanerStruct{DeletedBranches: true, RefParam: testRefParam},
+			wantDeleted: []string{
+				fmt.Sprintf("Pipeline repo-gone/gone: branch 'feature' deleted from %s", repository),
+			},
+			wantErrors: 1,
+		},
+		{
+			name:       "deleted branches with another ref parameter",
+			cleaner:    config.CleanerStruct{DeletedBranches: true, RefParam: "VARIABLE-REF"},
+			wantErrors: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config.Cleaner.Data = tt.cleaner
+			t.Cleanup(func() { config.Cleaner.Data = config.CleanerStruct{} })
+
+			client := fake.NewSimpleClientset(
+				newNamespace("repo-old", "a", 3*time.Hour),
+				newNamespace("repo-new", "a", 3*time.Hour),
+				newNamespace("repo-running", "a", 3*time.Hour),
+				newNamespace("repo-gone", "a", 3*time.Hour),
+				newNamespace("repo-broken", "a", 3*time.Hour),
+				newNamespace("repo-other", "b", 3*time.Hour),
+				newNamespace("empty", "a", 3*time.Hour),
+				newNamespace("young", "a", time.Minute),
+				newNamespace("kube-system", "", 3*time.Hour),
+			)
+			dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+				map[schema.GroupVersionResource]string{launcherjob.PipelineResource: "PipelineList"},
+				newPipeline("old", "repo-old", "a", repository, "main", 2*time.Hour, false),
+				newPipeline("new", "repo-new", "a", repository, "main", time.Minute, false),
+				newPipeline("running", "repo-running", "a", repository, "main", 2*time.Hour, true),
+				newPipeline("gone", "repo-gone", "a", repository, "feature", time.Minute, false),
+				newPipeline("broken", "repo-broken", "a", missing, "main", time.Minute, false),
+				newPipeline("other-team", "repo-other", "b", "", "", 2*time.Hour, false),
+			)
+
+			report, err := Run(client, dynamicClient, tt.options)
+			if err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+
+			if got := items(report.Deleted); got != strings.Join(tt.wantDeleted, "\n") {
+				t.Errorf("deleted =\n%s\nwant\n%s", got, strings.Join(tt.wantDeleted, "\n"))
+			}
+			if got := items(report.Skipped); got != strings.Join(tt.wantSkipped, "\n") {
+				t.Errorf("skipped =\n%s\nwant\n%s", got, strings.Join(tt.wantSkipped, "\n"))
+			}
+			if len(report.Errors) != tt.wantErrors {
+				t.Errorf("errors = %v, want %d", report.Errors, tt.wantErrors)
+			}
+
+			wantDeletions := len(tt.wantDeleted)
+			if tt.options.DryRun {
+				wantDeletions = 0
+			}
+			if got := deletions(client.Actions()) + deletions(dynamicClient.Actions()); got != wantDeletions {
+				t.Errorf("delete calls = %d, want %d", got, wantDeletions)
+			}
+		})
+	}
+}
+
+func TestRunInvalidSelector(t *testing.T) {
+	_, err := Run(fake.NewSimpleClientset(), dynamicfake.NewSimpleDynamicClient(runtime.NewScheme()), Options{Selector: "team in ("})
+	if err == nil {
+		t.Errorf("Run() error = nil, want an invalid selector error")
+	}
+}
+
+func TestReportWrite(t *testing.T) {
+	report := &Report{
+		Deleted: []Item{
+			{Kind: KindPipeline, Namespace: "repo-old", Name: "old", Reason: "older than 1h0m0s"},
+			{Kind: KindNamespace, Name: "repo-old", Reason: "no pipelines and older than 1h0m0s"},
+			{Kind: KindJob, Namespace: "pipe-manager", Name: "pipeline-launcher-1", Reason: "retention of route 'github'"},
+		},
+		Skipped: []Item{{Kind: KindPipeline, Namespace: "repo-running", Name: "running", Reason: "older than 1h0m0s, but it is running"}},
+		Errors:  []string{"namespace repo-new: forbidden"},
+	}
+
+	tests := []struct {
+		name   string
+		dryRun bool
+		want   string
+	}{
+		{
+			name: "deleted",
+			want: `Deleted Pipeline repo-old/old: older than 1h0m0s
+Deleted Namespace repo-old: no pipelines and older than 1h0m0s
+Deleted Job pipe-manager/pipeline-launcher-1: retention of route 'github'
+Skipped Pipeline repo-running/running: older than 1h0m0s, but it is running
+Error: namespace repo-new: forbidden
+Deleted: 1 pipelines, 1 namespaces, 1 launcher jobs (1 skipped, 1 errors)
+`,
+		},
+		{
+			name:   "dry run",
+			dryRun: true,
+			want: `Would delete Pipeline repo-old/old: older than 1h0m0s
+Would delete Namespace repo-old: no pipelines and older than 1h0m0s
+Would delete Job pipe-manager/pipeline-launcher-1: retention of route 'github'
+Skipped Pipeline repo-running/running: older than 1h0m0s, but it is running
+Error: namespace repo-new: forbidden
+Would delete: 1 pipelines, 1 namespaces, 1 launcher jobs (1 skipped, 1 errors)
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report.DryRun = tt.dryRun
+			var buffer bytes.Buffer
+			if err := report.Write(&buffer, OutputText); err != nil {
+				t.Fatal(err)
+			}
+			if buffer.String() != tt.want {
+				t.Errorf("Write() =\n%s\nwant\n%s", buffer.String(), tt.want)
+			}
+		})
+	}
+
+	var buffer bytes.Buffer
+	if err := report.Write(&buffer, OutputJSON); err != nil {
+		t.Fatal(err)
+	}
+	var decoded Report
+	if err := json.Unmarshal(buffer.Bytes(), &decoded); err != nil {
+		t.Fatalf("invalid JSON report %s: %v", buffer.String(), err)
+	}
+	if !decoded.DryRun || items(decoded.Deleted) != items(report.Deleted) || items(decoded.Skipped) != items(report.Skipped) ||
+		strings.Join(decoded.Errors, ",") != strings.Join(report.Errors, ",") {
+		t.Errorf("Write() JSON = %s, want the report", buffer.String())
+	}
+	for _, field := range []string{`"dryRun": true`, `"deleted": [`, `"kind": "Namespace"`, `"errors": [`} {
+		if !strings.Contains(buffer.String(), field) {
+			t.Errorf("Write() JSON = %s, want %s", buffer.String(), field)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package cleaner
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Formats of the report
+const (
+	OutputText = "text"
+	OutputJSON = "json"
+)
+
+// Write writes the report to the writer in the given format
+// The text format has a line for each resource and error, and a summary line with the number of resources of each kind
+func (r *Report) Write(w io.Writer, format string) error {
+	if format == OutputJSON {
+		data, err := json.MarshalIndent(r, "", "  ")
+		if err != nil {
+			return fmt.Errorf("error encoding the report: %w", err)
+		}
+		_, err = fmt.Fprintln(w, string(data))
+		return err
+	}
+
+	action := "Deleted"
+	if r.DryRun {
+		action = "Would delete"
+	}
+	for _, item := range r.Deleted {
+		if _, err := fmt.Fprintf(w, "%s %s %s: %s\n", action, item.Kind, item.name(), item.Reason); err != nil {
+			return err
+		}
+	}
+	for _, item := range r.Skipped {
+		if _, err := fmt.Fprintf(w, "Skipped %s %s: %s\n", item.Kind, item.name(), item.Reason); err != nil {
+			return err
+		}
+	}
+	for _, e := range r.Errors {
+		if _, err := fmt.Fprintf(w, "Error: %s\n", e); err != nil {
+			return err
+		}
+	}
+
+	counts := make(map[string]int)
+	for _, item := range r.Deleted {
+		counts[item.Kind]++
+	}
+	_, err := fmt.Fprintf(w, "%s: %d pipelines, %d namespaces, %d launcher jobs (%d skipped, %d errors)\n", action,
+		counts[KindPipeline], counts[KindNamespace], counts[KindJob], len(r.Skipped), len(r.Errors))
+	return err
+}
+
+// name returns the name of the item with its namespace, if any
+func (i Item) name() string {
+	if i.Namespace == "" {
+		return i.Name
+	}
+	return i.Namespace + "/" + i.Name
+}
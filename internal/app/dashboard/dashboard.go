@@ -0,0 +1,421 @@
+// Package dashboard collects the launcher jobs and the pipelines deployed by them from the Kubernetes API, so they can
+// be shown in a read-only web UI. The jobs are grouped by their request ID label, and so are the pipelines they deploy.
+package dashboard
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/sergiotejon/pipeManagerLauncher/internal/pkg/launcherjob"
+)
+
+const (
+	managedByLabel = "app.kubernetes.io/managed-by" // managedByLabel is the label of the namespaces created by the launcher
+	managedByValue = "pipe-manager"                 // managedByValue is the value of the managedByLabel
+
+	// Environment variables of the launcher container and parameters of the pipelines with the data of the request
+	// The branch is in the configurable parameter of the cleaner, see refEnvName
+	envPrefix       = "PIPELINE_"
+	repositoryEnv   = envPrefix + "REPOSITORY"
+	commitEnv       = envPrefix + "COMMIT"
+	repositoryParam = "REPOSITORY"
+	commitParam     = "COMMIT"
+	eventParam      = "EVENT"
+
+	// Phases of the pipelines
+	PipelineRunning  = "running"
+	PipelineFinished = "finished"
+)
+
+// Pod is a pod of a launcher job or of a pipeline, with the link to its logs
+type Pod struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	Phase     string `json:"phase"`
+	LogsURL   string `json:"logsURL"`
+}
+
+// Job is a launcher job
+type Job struct {
+	Name           string     `json:"name"`
+	Namespace      string     `json:"namespace"`
+	Phase          string     `json:"phase"`
+	Reason         string     `json:"reason,omitempty"`
+	StartTime      *time.Time `json:"startTime,omitempty"`
+	CompletionTime *time.Time `json:"completionTime,omitempty"`
+	Pods           []Pod      `json:"pods"`
+}
+
+// Pipeline is a Pipeline resource deployed by a launcher job
+type Pipeline struct {
+	Name           string    `json:"name"`
+	Namespace      string    `json:"namespace"`
+	NamespacePhase string    `json:"namespacePhase"` // NamespacePhase is the phase of the namespace, or empty if it does not exist
+	RequestID      string    `json:"requestID,omitempty"`
+	Repository     string    `json:"repository,omitempty"`
+	Branch         string    `json:"branch,omitempty"`
+	Commit         string    `json:"commit,omitempty"`
+	Event          string    `json:"event,omitempty"`
+	Phase          string    `json:"phase"`
+	Active         int       `json:"active"` // Active is the number of running resources of the pipeline
+	CreationTime   time.Time `json:"creationTime"`
+	Pods           []Pod     `json:"pods"`
+}
+
+// Request is the group of launcher jobs launched by a webhook request
+type Request struct {
+	RequestID    string     `json:"requestID"`
+	Route        string     `json:"route"`
+	Event        string     `json:"event"`
+	Repository   string     `json:"repository,omitempty"`
+	Branch       string     `json:"branch,omitempty"`
+	Commit       string     `json:"commit,omitempty"`
+	Phase        string     `json:"phase"`
+	CreationTime time.Time  `json:"creationTime"`
+	Jobs         []Job      `json:"jobs"`
+	Pipelines    []Pipeline `json:"pipelines"`
+}
+
+// Filter restricts the requests and pipelines returned by the dashboard
+// The empty fields do not filter. The repository matches if it contains the given value (e.g., "org/repo")
+type Filter struct {
+	Repository string `json:"repository,omitempty"`
+	Branch     string `json:"branch,omitempty"`
+	Route      string `json:"route,omitempty"`
+	Event      string `json:"event,omitempty"`
+}
+
+// Dashboard reads the state of the pipelines from the Kubernetes API
+type Dashboard struct {
+	client        kubernetes.Interface
+	dynamicClient dynamic.Interface
+	namespace     string // namespace is the namespace of the launcher jobs
+	refParam      string // refParam is the parameter of the pipelines with the branch
+	refEnv        string // refEnv is the environment variable of the launcher container with the branch
+	limit         int    // limit is the maximum number of requests and pipelines returned, the newest ones
+}
+
+// New returns a dashboard reading the launcher jobs of the given namespace and the pipelines of the cluster
+// The ref parameter is the parameter of the pipelines with the branch (e.g., VARIABLE-REF, see the cleaner
+// configuration). The limit is the maximum number of requests and pipelines returned, zero returns all of them
+func New(client kubernetes.Interface, dynamicClient dynamic.Interface, namespace, refParam string, limit int) *Dashboard {
+	return &Dashboard{
+		client:        client,
+		dynamicClient: dynamicClient,
+		namespace:     namespace,
+		refParam:      refParam,
+		refEnv:        refEnvName(refParam),
+		limit:         limit,
+	}
+}
+
+// refEnvName returns the environment variable of the launcher container with the value of the pipeline parameter
+// The launcher names the parameters after its PIPELINE_ variables, replacing the underscores with dashes
+// (e.g., PIPELINE_VARIABLE_REF is the VARIABLE-REF parameter)
+func refEnvName(refParam string) string {
+	return envPrefix + strings.ReplaceAll(refParam, "-", "_")
+}
+
+// Requests returns the requests matching the filter, the newest first
+func (d *Dashboard) Requests(ctx context.Context, filter Filter) ([]Request, error) {
+	return d.requests(ctx, filter, "")
+}
+
+// Request returns the request with the given ID, or false if it has no launcher jobs
+func (d *Dashboard) Request(ctx context.Context, requestID string) (Request, bool, error) {
+	requests, err := d.requests(ctx, Filter{}, requestID)
+	if err != nil || len(requests) == 0 {
+		return Request{}, false, err
+	}
+	return requests[0], true, nil
+}
+
+// Pipelines returns the pipelines matching the filter, the newest first, along with the phase of their namespaces
+// and their pods
+// The pipelines have no route, so the route of the filter is ignored
+func (d *Dashboard) Pipelines(ctx context.Context, filter Filter) ([]Pipeline, error) {
+	return d.pipelines(ctx, filter, "")
+}
+
+// requests returns the requests matching the filter, the newest first. If the request ID is not empty, only the
+// request with that ID is returned
+func (d *Dashboard) requests(ctx context.Context, filter Filter, requestID string) ([]Request, error) {
+	selector := getSelector(requestID)
+	jobs, err := d.client.BatchV1().Jobs(d.namespace).List(ctx, metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return nil, fmt.Errorf("error listing launcher jobs: %w", err)
+	}
+
+	pods, err := d.launcherPods(ctx, selector)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[string]*Request)
+	for i := range jobs.Items {
+		job := &jobs.Items[i]
+		id := job.Labels[launcherjob.LabelRequestID]
+		if id == "" {
+			continue
+		}
+
+		req, ok := byID[id]
+		if !ok {
+			env := getLauncherEnv(job)
+			req = &Request{
+				RequestID:    id,
+				Route:        job.Labels[launcherjob.LabelRoute],
+				Event:        job.Labels[launcherjob.LabelEvent],
+				Repository:   env[repositoryEnv],
+				Branch:       env[d.refEnv],
+				Commit:       env[commitEnv],
+				CreationTime: job.CreationTimestamp.Time,
+				Jobs:         []Job{},
+				Pipelines:    []Pipeline{},
+			}
+			byID[id] = req
+		}
+		if job.CreationTimestamp.Time.Before(req.CreationTime) {
+			req.CreationTime = job.CreationTimestamp.Time
+		}
+		req.Jobs = append(req.Jobs, newJob(job, pods[job.Name]))
+	}
+
+	requests := make([]Request, 0, len(byID))
+	for _, req := range byID {
+		if !filter.matches(req.Repository, req.Branch, req.Route, req.Event) {
+			continue
+		}
+		phases := make([]string, 0, len(req.Jobs))
+		for _, job := range req.Jobs {
+			phases = append(phases, job.Phase)
+		}
+		req.Phase = launcherjob.AggregatePhase(phases)
+		sort.Slice(req.Jobs, func(i, j int) bool { return req.Jobs[i].Name < req.Jobs[j].Name })
+		requests = append(requests, *req)
+	}
+	sort.Slice(requests, func(i, j int) bool {
+		return requests[i].CreationTime.After(requests[j].CreationTime)
+	})
+	if d.limit > 0 && len(requests) > d.limit {
+		requests = requests[:d.limit]
+	}
+	if len(requests) == 0 {
+		return requests, nil
+	}
+
+	// The pipelines are listed once for all the requests
+	pipelines, err := d.pipelines(ctx, Filter{}, requestID)
+	if err != nil {
+		return nil, err
+	}
+	index := make(map[string]int, len(requests))
+	for i := range requests {
+		index[requests[i].RequestID] = i
+	}
+	for _, p := range pipelines {
+		if i, ok := index[p.RequestID]; ok {
+			requests[i].Pipelines = append(requests[i].Pipelines, p)
+		}
+	}
+
+	return requests, nil
+}
+
+// pipelines returns the pipelines matching the filter, the newest first. If the request ID is not empty, only the
+// pipelines of that request are returned
+func (d *Dashboard) pipelines(ctx context.Context, filter Filter, requestID string) ([]Pipeline, error) {
+	options := metav1.ListOptions{}
+	if requestID != "" {
+		options.LabelSelector = fmt.Sprintf("%s=%s", launcherjob.LabelRequestID, requestID)
+	}
+	list, err := d.dynamicClient.Resource(launcherjob.PipelineResource).List(ctx, options)
+	if err != nil {
+		return nil, fmt.Errorf("error listing pipelines: %w", err)
+	}
+
+	filter.Route = ""
+	pipelines := make([]Pipeline, 0, len(list.Items))
+	for i := range list.Items {
+		p := d.newPipeline(&list.Items[i])
+		if filter.matches(p.Repository, p.Branch, "", p.Event) {
+			pipelines = append(pipelines, p)
+		}
+	}
+	sort.Slice(pipelines, func(i, j int) bool {
+		return pipelines[i].CreationTime.After(pipelines[j].CreationTime)
+	})
+	if requestID == "" && d.limit > 0 && len(pipelines) > d.limit {
+		pipelines = pipelines[:d.limit]
+	}
+	if len(pipelines) == 0 {
+		return pipelines, nil
+	}
+
+	namespaces, err := d.client.CoreV1().Namespaces().List(ctx, metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s=%s", managedByLabel, managedByValue),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing namespaces: %w", err)
+	}
+	phases := make(map[string]string, len(namespaces.Items))
+	for _, namespace := range namespaces.Items {
+		phases[namespace.Name] = string(namespace.Status.Phase)
+	}
+
+	// The pods are listed once for each namespace with pipelines
+	pods := make(map[string][]Pod)
+	for i := range pipelines {
+		p := &pipelines[i]
+		p.NamespacePhase = phases[p.Namespace]
+		if p.NamespacePhase == "" {
+			continue
+		}
+		if _, ok := pods[p.Namespace]; !ok {
+			list, err := d.client.CoreV1().Pods(p.Namespace).List(ctx, metav1.ListOptions{})
+			if err != nil {
+				return nil, fmt.Errorf("error listing the pods of namespace %s: %w", p.Namespace, err)
+			}
+			pods[p.Namespace] = newPods(list.Items)
+		}
+		p.Pods = pods[p.Namespace]
+	}
+
+	return pipelines, nil
+}
+
+// launcherPods returns the pods of the launcher jobs matching the selector indexed by job name
+func (d *Dashboard) launcherPods(ctx context.Context, selector string) (map[string][]Pod, error) {
+	list, err := d.client.CoreV1().Pods(d.namespace).List(ctx, metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return nil, fmt.Errorf("error listing launcher pods: %w", err)
+	}
+
+	pods := make(map[string][]Pod)
+	for _, pod := range list.Items {
+		jobName := pod.Labels[batchv1.JobNameLabel]
+		if jobName == "" {
+			jobName = pod.Labels["job-name"]
+		}
+		pods[jobName] = append(pods[jobName], newPods([]corev1.Pod{pod})...)
+	}
+	return pods, nil
+}
+
+// canReadLogs returns true if the dashboard serves the logs of the pod: the pods of the launcher jobs and the pods of
+// the namespaces created by the launcher
+func (d *Dashboard) canReadLogs(ctx context.Context, pod *corev1.Pod) (bool, error) {
+	if pod.Namespace == d.namespace && pod.Labels[launcherjob.LabelHandleBy] == launcherjob.HandleByValue {
+		return true, nil
+	}
+
+	namespace, err := d.client.CoreV1().Namespaces().Get(ctx, pod.Namespace, metav1.GetOptions{})
+	if err != nil {
+		return false, err
+	}
+	return namespace.Labels[managedByLabel] == managedByValue, nil
+}
+
+// getSelector returns the label selector of the launcher jobs and their pods, only of the given request if not empty
+func getSelector(requestID string) string {
+	selector := fmt.Sprintf("%s=%s", launcherjob.LabelHandleBy, launcherjob.HandleByValue)
+	if requestID != "" {
+		selector += fmt.Sprintf(",%s=%s", launcherjob.LabelRequestID, requestID)
+	}
+	return selector
+}
+
+// matches returns true if the values match the filter
+func (f Filter) matches(repository, branch, route, event string) bool {
+	return (f.Repository == "" || strings.Contains(repository, f.Repository)) &&
+		(f.Branch == "" || f.Branch == branch) &&
+		(f.Route == "" || f.Route == route) &&
+		(f.Event == "" || f.Event == event)
+}
+
+// newJob returns the launcher job of the dashboard for the Kubernetes Job and its pods
+func newJob(job *batchv1.Job, pods []Pod) Job {
+	phase, reason := launcherjob.GetJobPhase(job)
+	result := Job{
+		Name:      job.Name,
+		Namespace: job.Namespace,
+		Phase:     phase,
+		Reason:    reason,
+		Pods:      pods,
+	}
+	if result.Pods == nil {
+		result.Pods = []Pod{}
+	}
+	if job.Status.StartTime != nil {
+		result.StartTime = &job.Status.StartTime.Time
+	}
+	if job.Status.CompletionTime != nil {
+		result.CompletionTime = &job.Status.CompletionTime.Time
+	}
+	return result
+}
+
+// newPipeline returns the pipeline of the dashboard for the Pipeline resource
+func (d *Dashboard) newPipeline(item *unstructured.Unstructured) Pipeline {
+	params, _, _ := unstructured.NestedStringMap(item.Object, "spec", "params")
+	active, _, _ := unstructured.NestedSlice(item.Object, "status", "active")
+
+	phase := PipelineFinished
+	if len(active) > 0 {
+		phase = PipelineRunning
+	}
+
+	return Pipeline{
+		Name:         item.GetName(),
+		Namespace:    item.GetNamespace(),
+		RequestID:    item.GetLabels()[launcherjob.LabelRequestID],
+		Repository:   params[repositoryParam],
+		Branch:       params[d.refParam],
+		Commit:       params[commitParam],
+		Event:        params[eventParam],
+		Phase:        phase,
+		Active:       len(active),
+		CreationTime: item.GetCreationTimestamp().Time,
+		Pods:         []Pod{},
+	}
+}
+
+// newPods returns the pods of the dashboard for the Kubernetes pods
+func newPods(pods []corev1.Pod) []Pod {
+	result := make([]Pod, 0, len(pods))
+	for _, pod := range pods {
+		result = append(result, Pod{
+			Name:      pod.Name,
+			Namespace: pod.Namespace,
+			Phase:     string(pod.Status.Phase),
+			LogsURL:   fmt.Sprintf("/logs/%s/%s", url.PathEscape(pod.Namespace), url.PathEscape(pod.Name)),
+		})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+// getLauncherEnv returns the environment variables with a value of the launcher container of the job
+func getLauncherEnv(job *batchv1.Job) map[string]string {
+	env := make(map[string]string)
+	for _, container := range job.Spec.Template.Spec.Containers {
+		if container.Name != launcherjob.ContainerName {
+			continue
+		}
+		for _, envVar := range container.Env {
+			env[envVar.Name] = envVar.Value
+		}
+	}
+	return env
+}
@@ -0,0 +1,330 @@
+package dashboard
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/sergiotejon/pipeManagerLauncher/internal/pkg/launcherjob"
+	"github.com/sergiotejon/pipeManagerLauncher/internal/pkg/logging"
+)
+
+const (
+	testNamespace = "pipe-manager"
+	// testRefParam is a ref parameter other than the default one, so the tests check the configured one is read
+	testRefParam = "VARIABLE-BRANCH"
+	testRefEnv   = "PIPELINE_VARIABLE_BRANCH"
+)
+
+// testTime is the creation time of the oldest objects of the tests
+var testTime = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func TestMain(m *testing.M) {
+	if err := logging.SetupLogger("error", "text", "stderr"); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+// newLauncherJob returns a launcher job of the request, created the given minutes after testTime
+// The job is running if it has no condition
+func newLauncherJob(name, requestID, route, event, repository, branch string, minutes int, condition batchv1.JobConditionType) *batchv1.Job {
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			Namespace:         testNamespace,
+			CreationTimestamp: metav1.NewTime(testTime.Add(time.Duration(minutes) * time.Minute)),
+			Labels: map[string]string{
+				launcherjob.LabelHandleBy:  launcherjob.HandleByValue,
+				launcherjob.LabelRequestID: requestID,
+				launcherjob.LabelRoute:     route,
+				launcherjob.LabelEvent:     event,
+			},
+		},
+		Spec: batchv1.JobSpec{Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{
+				Name: launcherjob.ContainerName,
+				Env: []corev1.EnvVar{
+					{Name: repositoryEnv, Value: repository},
+					{Name: testRefEnv, Value: branch},
+					{Name: commitEnv, Value: "abc123"},
+				},
+			}},
+		}}},
+	}
+	if condition != "" {
+		job.Status.Conditions = []batchv1.JobCondition{{Type: condition, Status: corev1.ConditionTrue}}
+	} else {
+		ready := int32(1)
+		job.Status.Ready = &ready
+	}
+	return job
+}
+
+// newPipelineObject returns a Pipeline resource of the request, created the given minutes after testTime
+func newPipelineObject(name, namespace, requestID, repository, branch, event string, minutes int, active int) *unstructured.Unstructured {
+	item := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": launcherjob.PipelineResource.GroupVersion().String(),
+		"kind":       "Pipeline",
+		"spec": map[string]interface{}{
+			"params": map[string]interface{}{
+				repositoryParam: repository,
+				testRefParam:    branch,
+				commitParam:     "abc123",
+				eventParam:      event,
+			},
+		},
+	}}
+	if active > 0 {
+		running := make([]interface{}, active)
+		for i := range running {
+			running[i] = map[string]interface{}{"name": "task"}
+		}
+		item.Object["status"] = map[string]interface{}{"active": running}
+	}
+	item.SetName(name)
+	item.SetNamespace(namespace)
+	item.SetCreationTimestamp(metav1.NewTime(testTime.Add(time.Duration(minutes) * time.Minute)))
+	item.SetLabels(map[string]string{launcherjob.LabelRequestID: requestID})
+	return item
+}
+
+// newTestDashboard returns a dashboard reading fake clients with two requests: one of the github route with two jobs
+// and two pipelines, and a newer one of the gitlab route with a pipeline whose namespace no longer exists
+func newTestDashboard(limit int) *Dashboard {
+	client := fake.NewSimpleClientset(
+		newLauncherJob("job-1a", "req-1", "github", "push", "https://github.com/org/repo.git", "main", 0, batchv1.JobComplete),
+		newLauncherJob("job-1b", "req-1", "github", "push", "https://github.com/org/repo.git", "main", 1, ""),
+		newLauncherJob("job-2", "req-2", "gitlab", "Merge Request Hook", "https://gitlab.com/team/app.git", "feature", 5, batchv1.JobFailed),
+		&batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: testNamespace}},
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+			Name:      "job-1a-pod",
+			Namespace: testNamespace,
+			Labels: map[string]string{
+				launcherjob.LabelHandleBy:  launcherjob.HandleByValue,
+				launcherjob.LabelRequestID: "req-1",
+				batchv1.JobNameLabel:       "job-1a",
+			},
+		}, Status: corev1.PodStatus{Phase: corev1.PodSucceeded}},
+		&corev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{Name: "repo-main", Labels: map[string]string{managedByLabel: managedByValue}},
+			Status:     corev1.NamespaceStatus{Phase: corev1.NamespaceActive},
+		},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system"}},
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "build", Namespace: "repo-main"}, Status: corev1.PodStatus{Phase: corev1.PodRunning}},
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "coredns", Namespace: "kube-system"}},
+	)
+
+	scheme := runtime.NewScheme()
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(scheme,
+		map[schema.GroupVersionResource]string{launcherjob.PipelineResource: "PipelineList"},
+		newPipelineObject("build", "repo-main", "req-1", "https://github.com/org/repo.git", "main", "push", 2, 1),
+		newPipelineObject("test", "repo-main", "req-1", "https://github.com/org/repo.git", "main", "push", 3, 0),
+		newPipelineObject("review", "app-feature", "req-2", "https://gitlab.com/team/app.git", "feature", "Merge Request Hook", 6, 0),
+	)
+
+	return New(client, dynamicClient, testNamespace, testRefParam, limit)
+}
+
+// requestIDs returns the IDs of the requests
+func requestIDs(requests []Request) string {
+	ids := make([]string, 0, len(requests))
+	for _, req := range requests {
+		ids = append(ids, req.RequestID)
+	}
+	return strings.Join(ids, ",")
+}
+
+// pipelineNames returns the names of the pipelines
+func pipelineNames(pipelines []Pipeline) string {
+	names := make([]string, 0, len(pipelines))
+	for _, p := range pipelines {
+		names = append(names, p.Name)
+	}
+	return strings.Join(names, ",")
+}
+
+func TestRequests(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter Filter
+		limit  int
+		want   string
+	}{
+		{"all, newest first", Filter{}, 0, "req-2,req-1"},
+		{"limit", Filter{}, 1, "req-2"},
+		{"repository contains", Filter{Repository: "org/repo"}, 0, "req-1"},
+		{"branch", Filter{Branch: "feature"}, 0, "req-2"},
+		{"route", Filter{Route: "github"}, 0, "req-1"},
+		{"event", Filter{Event: "Merge Request Hook"}, 0, "req-2"},
+		{"no match", Filter{Route: "github", Branch: "feature"}, 0, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests, err := newTestDashboard(tt.limit).Requests(context.Background(), tt.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := requestIDs(requests); got != tt.want {
+				t.Errorf("Requests() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequest(t *testing.T) {
+	d := newTestDashboard(0)
+
+	req, ok, err := d.Request(context.Background(), "req-1")
+	if err != nil || !ok {
+		t.Fatalf("Request() = %v, %v", ok, err)
+	}
+	if req.Route != "github" || req.Event != "push" || req.Branch != "main" || req.Commit != "abc123" {
+		t.Errorf("Request() = %+v, want the data of the launcher jobs", req)
+	}
+	if !req.CreationTime.Equal(testTime) {
+		t.Errorf("Request() creation time = %v, want the one of the oldest job", req.CreationTime)
+	}
+	if req.Phase != launcherjob.PhaseRunning {
+		t.Errorf("Request() phase = %s, want %s", req.Phase, launcherjob.PhaseRunning)
+	}
+	if len(req.Jobs) != 2 || req.Jobs[0].Name != "job-1a" || len(req.Jobs[0].Pods) != 1 || len(req.Jobs[1].Pods) != 0 {
+		t.Errorf("Request() jobs = %+v, want both jobs with their pods", req.Jobs)
+	}
+	if got := pipelineNames(req.Pipelines); got != "test,build" {
+		t.Errorf("Request() pipelines = %s, want test,build", got)
+	}
+
+	if _, ok, err := d.Request(context.Background(), "unknown"); err != nil || ok {
+		t.Errorf("Request() of an unknown ID = %v, %v, want not found", ok, err)
+	}
+}
+
+func TestPipelines(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter Filter
+		limit  int
+		want   string
+	}{
+		{"all, newest first", Filter{}, 0, "review,test,build"},
+		{"limit", Filter{}, 2, "review,test"},
+		{"repository contains", Filter{Repository: "gitlab.com"}, 0, "review"},
+		{"branch", Filter{Branch: "main"}, 0, "test,build"},
+		{"route ignored", Filter{Route: "gitlab"}, 0, "review,test,build"},
+		{"event", Filter{Event: "push"}, 0, "test,build"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pipelines, err := newTestDashboard(tt.limit).Pipelines(context.Background(), tt.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := pipelineNames(pipelines); got != tt.want {
+				t.Errorf("Pipelines() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPipelinesState(t *testing.T) {
+	pipelines, err := newTestDashboard(0).Pipelines(context.Background(), Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	byName := make(map[string]Pipeline)
+	for _, p := range pipelines {
+		byName[p.Name] = p
+	}
+	build := byName["build"]
+	if build.Phase != PipelineRunning || build.Active != 1 || build.NamespacePhase != string(corev1.NamespaceActive) {
+		t.Errorf("build = %+v, want running in an active namespace", build)
+	}
+	if len(build.Pods) != 1 || build.Pods[0].LogsURL != "/logs/repo-main/build" {
+		t.Errorf("build pods = %+v, want the pods of its namespace", build.Pods)
+	}
+	review := byName["review"]
+	if review.Phase != PipelineFinished || review.NamespacePhase != "" || len(review.Pods) != 0 {
+		t.Errorf("review = %+v, want finished without namespace", review)
+	}
+}
+
+func TestLogsHandler(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "launcher",
+				Namespace: testNamespace,
+				Labels:    map[string]string{launcherjob.LabelHandleBy: launcherjob.HandleByValue},
+			},
+			Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: launcherjob.ContainerName}}},
+		},
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: testNamespace}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "repo-main", Labels: map[string]string{managedByLabel: managedByValue}}},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "build", Namespace: "repo-main"},
+			Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "step"}}},
+		},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system"}},
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "coredns", Namespace: "kube-system"}},
+	)
+	handler := New(client, dynamicfake.NewSimpleDynamicClient(runtime.NewScheme()), testNamespace, testRefParam, 0).Handler()
+
+	tests := []struct {
+		name string
+		path string
+		want int
+	}{
+		{"launcher pod", "/logs/pipe-manager/launcher", http.StatusOK},
+		{"pipeline pod", "/logs/repo-main/build?container=step", http.StatusOK},
+		{"pod of the namespace not managed by the launcher", "/logs/pipe-manager/other", http.StatusForbidden},
+		{"pod of another namespace", "/logs/kube-system/coredns", http.StatusForbidden},
+		{"unknown pod", "/logs/kube-system/unknown", http.StatusNotFound},
+		{"invalid tail lines", "/logs/repo-main/build?tailLines=-1", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if recorder.Code != tt.want {
+				body, _ := io.ReadAll(recorder.Body)
+				t.Errorf("GET %s = %d %s, want %d", tt.path, recorder.Code, body, tt.want)
+			}
+		})
+	}
+}
+
+func TestRefEnvName(t *testing.T) {
+	tests := []struct {
+		refParam string
+		want     string
+	}{
+		{"VARIABLE-REF", "PIPELINE_VARIABLE_REF"},
+		{testRefParam, testRefEnv},
+		{"BRANCH", "PIPELINE_BRANCH"},
+	}
+
+	for _, tt := range tests {
+		if got := refEnvName(tt.refParam); got != tt.want {
+			t.Errorf("refEnvName(%s) = %s, want %s", tt.refParam, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package dashboard
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/sergiotejon/pipeManagerLauncher/internal/pkg/logging"
+)
+
+// defaultTailLines is the number of lines of the logs returned when the tailLines parameter is not set
+const defaultTailLines = 1000
+
+// pageTemplate is the template of the HTML page of the dashboard
+var pageTemplate = template.Must(template.New("page").Parse(pageHTML))
+
+// page is the data of the HTML page
+type page struct {
+	Filter    Filter
+	Requests  []Request
+	Pipelines []Pipeline
+}
+
+// Handler returns the HTTP handler of the dashboard
+// It serves the HTML page on /, the JSON API on /api and the logs of the pods on /logs. All of them are read-only
+func (d *Dashboard) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", d.pageHandler)
+	mux.HandleFunc("GET /api/requests", d.requestsHandler)
+	mux.HandleFunc("GET /api/requests/{id}", d.requestHandler)
+	mux.HandleFunc("GET /api/pipelines", d.pipelinesHandler)
+	mux.HandleFunc("GET /logs/{namespace}/{pod}", d.logsHandler)
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	return mux
+}
+
+// pageHandler renders the HTML page with the requests and pipelines matching the filter of the query
+func (d *Dashboard) pageHandler(w http.ResponseWriter, r *http.Request) {
+	filter := getFilter(r)
+	requests, err := d.Requests(r.Context(), filter)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	pipelines, err := d.Pipelines(r.Context(), filter)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	err = pageTemplate.Execute(w, page{Filter: filter, Requests: requests, Pipelines: pipelines})
+	if err != nil {
+		logging.Logger.Error("Error writing response", "error", fmt.Sprintf("%v", err))
+	}
+}
+
+// requestsHandler answers the requests matching the filter of the query
+func (d *Dashboard) requestsHandler(w http.ResponseWriter, r *http.Request) {
+	requests, err := d.Requests(r.Context(), getFilter(r))
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	writeJSON(w, requests)
+}
+
+// requestHandler answers the request with the ID of the path
+func (d *Dashboard) requestHandler(w http.ResponseWriter, r *http.Request) {
+	request, ok, err := d.Request(r.Context(), r.PathValue("id"))
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	if !ok {
+		http.Error(w, "Request not found", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, request)
+}
+
+// pipelinesHandler answers the pipelines matching the filter of the query
+func (d *Dashboard) pipelinesHandler(w http.ResponseWriter, r *http.Request) {
+	pipelines, err := d.Pipelines(r.Context(), getFilter(r))
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	writeJSON(w, pipelines)
+}
+
+// logsHandler answers the logs of the pod of the path as plain text
+// The container is given by the container parameter of the query. Otherwise, the logs of all the containers are
+// returned one after the other. The tailLines parameter limits the number of lines of each container
+func (d *Dashboard) logsHandler(w http.ResponseWriter, r *http.Request) {
+	pod, err := d.client.CoreV1().Pods(r.PathValue("namespace")).Get(r.Context(), r.PathValue("pod"), metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		http.Error(w, "Pod not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+
+	allowed, err := d.canReadLogs(r.Context(), pod)
+	if err != nil && !errors.IsNotFound(err) {
+		internalError(w, err)
+		return
+	}
+	if !allowed {
+		http.Error(w, "Pod not managed by pipe-manager", http.StatusForbidden)
+		return
+	}
+
+	tailLines := int64(defaultTailLines)
+	if value := r.URL.Query().Get("tailLines"); value != "" {
+		tailLines, err = strconv.ParseInt(value, 10, 64)
+		if err != nil || tailLines <= 0 {
+			http.Error(w, "Invalid tailLines", http.StatusBadRequest)
+			return
+		}
+	}
+
+	containers := []string{r.URL.Query().Get("container")}
+	if containers[0] == "" {
+		containers = containers[:0]
+		for _, container := range append(pod.Spec.InitContainers, pod.Spec.Containers...) {
+			containers = append(containers, container.Name)
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	for _, container := range containers {
+		if len(containers) > 1 {
+			_, _ = fmt.Fprintf(w, "==> %s <==\n", container)
+		}
+		stream, err := d.client.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, &corev1.PodLogOptions{
+			Container: container,
+			TailLines: &tailLines,
+		}).Stream(r.Context())
+		if err != nil {
+			_, _ = fmt.Fprintf(w, "Error reading the logs: %v\n", err)
+			continue
+		}
+		_, err = io.Copy(w, stream)
+		_ = stream.Close()
+		if err != nil {
+			logging.Logger.Error("Error writing response", "error", fmt.Sprintf("%v", err))
+			return
+		}
+	}
+}
+
+// getFilter returns the filter of the query parameters of the request
+func getFilter(r *http.Request) Filter {
+	query := r.URL.Query()
+	return Filter{
+		Repository: query.Get("repository"),
+		Branch:     query.Get("branch"),
+		Route:      query.Get("route"),
+		Event:      query.Get("event"),
+	}
+}
+
+// writeJSON writes the value as indented JSON
+func writeJSON(w http.ResponseWriter, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(value); err != nil {
+		logging.Logger.Error("Error writing response", "error", fmt.Sprintf("%v", err))
+	}
+}
+
+// internalError logs the error and answers it with a 500 status
+func internalError(w http.ResponseWriter, err error) {
+	logging.Logger.Error("Error reading the Kubernetes API", "error", fmt.Sprintf("%v", err))
+	http.Error(w, fmt.Sprintf("Error reading the Kubernetes API: %v", err), http.StatusInternalServerError)
+}
@@ -0,0 +1,75 @@
+package dashboard
+
+// pageHTML is the HTML page of the dashboard
+// It has no scripts, the filters are submitted as query parameters
+const pageHTML = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Pipe Manager</title>
+<style>
+body { font-family: sans-serif; margin: 1em 2em; color: #222; }
+table { border-collapse: collapse; width: 100%; margin-bottom: 2em; }
+th, td { border-bottom: 1px solid #ddd; padding: 4px 8px; text-align: left; vertical-align: top; font-size: 14px; }
+th { background: #f4f4f4; }
+form input { margin-right: 1em; }
+.succeeded, .finished { color: #2a7a2a; }
+.failed, .error { color: #b00020; }
+.running { color: #1a5fb4; }
+.pending, .queued { color: #8a6d00; }
+code { font-size: 12px; }
+</style>
+</head>
+<body>
+<h1>Pipe Manager</h1>
+<form method="get" action="/">
+<label>Repository <input name="repository" value="{{.Filter.Repository}}"></label>
+<label>Branch <input name="branch" value="{{.Filter.Branch}}"></label>
+<label>Route <input name="route" value="{{.Filter.Route}}"></label>
+<label>Event <input name="event" value="{{.Filter.Event}}"></label>
+<button type="submit">Filter</button> <a href="/">Clear</a>
+</form>
+
+<h2>Launcher jobs</h2>
+<table>
+<tr><th>Created</th><th>Request</th><th>Route</th><th>Event</th><th>Repository</th><th>Branch</th><th>Commit</th><th>Phase</th><th>Jobs</th><th>Pipelines</th></tr>
+{{range .Requests}}
+<tr>
+<td>{{.CreationTime.Format "2006-01-02 15:04:05"}}</td>
+<td><a href="/api/requests/{{.RequestID}}"><code>{{.RequestID}}</code></a></td>
+<td>{{.Route}}</td>
+<td>{{.Event}}</td>
+<td>{{.Repository}}</td>
+<td>{{.Branch}}</td>
+<td><code>{{.Commit}}</code></td>
+<td class="{{.Phase}}">{{.Phase}}</td>
+<td>{{range .Jobs}}{{.Name}} <span class="{{.Phase}}">{{.Phase}}</span>{{if .Reason}} ({{.Reason}}){{end}}{{range .Pods}} <a href="{{.LogsURL}}">logs</a>{{end}}<br>{{end}}</td>
+<td>{{range .Pipelines}}{{.Namespace}}/{{.Name}} <span class="{{.Phase}}">{{.Phase}}</span><br>{{end}}</td>
+</tr>
+{{else}}
+<tr><td colspan="10">No launcher jobs</td></tr>
+{{end}}
+</table>
+
+<h2>Pipelines</h2>
+<table>
+<tr><th>Created</th><th>Pipeline</th><th>Namespace</th><th>Repository</th><th>Branch</th><th>Commit</th><th>Event</th><th>Phase</th><th>Pods</th></tr>
+{{range .Pipelines}}
+<tr>
+<td>{{.CreationTime.Format "2006-01-02 15:04:05"}}</td>
+<td>{{.Name}}{{if .RequestID}}<br><a href="/api/requests/{{.RequestID}}"><code>{{.RequestID}}</code></a>{{end}}</td>
+<td>{{.Namespace}}{{if .NamespacePhase}} ({{.NamespacePhase}}){{else}} (deleted){{end}}</td>
+<td>{{.Repository}}</td>
+<td>{{.Branch}}</td>
+<td><code>{{.Commit}}</code></td>
+<td>{{.Event}}</td>
+<td class="{{.Phase}}">{{.Phase}}{{if .Active}} ({{.Active}} active){{end}}</td>
+<td>{{range .Pods}}<a href="{{.LogsURL}}">{{.Name}}</a> {{.Phase}}<br>{{end}}</td>
+</tr>
+{{else}}
+<tr><td colspan="9">No pipelines</td></tr>
+{{end}}
+</table>
+</body>
+</html>
+`
@@ -9,31 +9,29 @@ import (
 	"os"
 	"path/filepath"
 
+	"github.com/go-git/go-git/v5/plumbing/transport"
+
 	"github.com/sergiotejon/pipeManagerLauncher/internal/app/launcher/convert"
 	"github.com/sergiotejon/pipeManagerLauncher/internal/app/launcher/deploy"
 	"github.com/sergiotejon/pipeManagerLauncher/internal/app/launcher/namespace"
 	"github.com/sergiotejon/pipeManagerLauncher/internal/app/launcher/pipelineprocessor"
 	"github.com/sergiotejon/pipeManagerLauncher/internal/app/launcher/repository"
+	"github.com/sergiotejon/pipeManagerLauncher/internal/pkg/configcmd"
+	"github.com/sergiotejon/pipeManagerLauncher/internal/pkg/exitcode"
+	"github.com/sergiotejon/pipeManagerLauncher/internal/pkg/launcherjob"
 	"github.com/sergiotejon/pipeManagerLauncher/internal/pkg/logging"
 	"github.com/sergiotejon/pipeManagerLauncher/pkg/config"
 	"github.com/sergiotejon/pipeManagerLauncher/pkg/envvars"
 )
 
-const (
-	ErrCodeOK                 = 0
-	ErrCodeLoadConfig         = 1
-	ErrCodeCloneRepo          = 2
-	ErrCodeMixFiles           = 3
-	ErrCodeConvertingPipeline = 4
-	ErrCodeBucketDownload     = 6
-	ErrCodeBucketUpload       = 7
-	ErrCodeDeploy             = 8
-)
-
 const (
 	templateFolder = "/etc/pipe-manager/templates" // templateFolder is the folder where the templates are stored
 	repoDir        = "/tmp/repo"                   // repoDir is the directory where the repository is cloned
+	pipelineDir    = ".pipelines"                  // pipelineDir is the directory of the repository with the pipeline files
 	envvar_prefix  = "PIPELINE_"
+	requestIDEnv   = "REQUEST_ID" // requestIDEnv is the environment variable with the request ID of the webhook
+	jobNameEnv     = "JOB_NAME"   // jobNameEnv is the environment variable with the name of the launcher job
+	routeNameEnv   = "ROUTE_NAME" // routeNameEnv is the environment variable with the route of the webhook
 )
 
 var (
@@ -45,22 +43,28 @@ func setup() {
 	var err error
 
 	// Load configuration
+	err = configcmd.ApplyFlags()
+	if err != nil {
+		log.Printf("Error in configuration flags: %v", err)
+		os.Exit(exitcode.ErrCodeLoadConfig)
+	}
 	err = config.LoadLauncherConfig(configFile)
 	if err != nil {
 		log.Printf("Error loading launcher config: %v", err)
-		os.Exit(ErrCodeLoadConfig)
+		os.Exit(exitcode.ErrCodeLoadConfig)
 	}
 	err = config.LoadCommonConfig(configFile)
 	if err != nil {
 		log.Printf("Error loading common config: %v", err)
-		os.Exit(ErrCodeLoadConfig)
+		os.Exit(exitcode.ErrCodeLoadConfig)
 	}
+	configcmd.PrintEffectiveConfig()
 
 	// Setup Logger
 	err = logging.SetupLogger(config.Common.Data.Log.Level, config.Common.Data.Log.Format, config.Common.Data.Log.File)
 	if err != nil {
 		log.Printf("Error configuring the logger: %v", err)
-		os.Exit(ErrCodeLoadConfig)
+		os.Exit(exitcode.ErrCodeLoadConfig)
 	}
 
 	logging.Logger.Info("Pipe Manager starting up...")
@@ -81,28 +85,48 @@ func setup() {
 func app() {
 	var err error
 
+	// Only the pipeline files are needed to find the pipelines, the pipelines do their own checkout
+	var sparseDirs []string
+	if config.Launcher.Data.SparseCheckout {
+		sparseDirs = []string{pipelineDir}
+	}
+
 	// Clone the repository
-	err = repository.Clone(envvars.Variables["REPOSITORY"],
-		config.Launcher.Data.CloneDepth,
-		envvars.Variables["COMMIT"],
-		repoDir)
+	auth, sshCommand := getAuth(envvars.Variables["REPOSITORY"])
+	err = repository.Clone(repoDir, repository.Options{
+		URL:         envvars.Variables["REPOSITORY"],
+		Depth:       config.Launcher.Data.CloneDepth,
+		Commit:      envvars.Variables["COMMIT"],
+		Ref:         envvars.Variables["REF"],
+		MergeTarget: envvars.Variables["MERGE_TARGET"],
+		SparseDirs:  sparseDirs,
+		Submodules:  config.Launcher.Data.Submodules,
+		LFS:         config.Launcher.Data.LFS.Enabled,
+		LFSEndpoint: config.Launcher.Data.LFS.Endpoint,
+		Auth:        auth,
+		SSHCommand:  sshCommand,
+	})
 	if err != nil {
 		logging.Logger.Error("Error cloning repository", "msg", err,
 			"repository", envvars.Variables["REPOSITORY"],
 			"commit", envvars.Variables["COMMIT"],
-			"depth", config.Launcher.Data.CloneDepth)
-		os.Exit(ErrCodeCloneRepo)
+			"ref", envvars.Variables["REF"],
+			"mergeTarget", envvars.Variables["MERGE_TARGET"],
+			"depth", config.Launcher.Data.CloneDepth,
+			"sparseCheckout", config.Launcher.Data.SparseCheckout,
+			"submodules", config.Launcher.Data.Submodules,
+			"lfs", config.Launcher.Data.LFS.Enabled)
+		os.Exit(getCloneExitCode(err))
 	}
 
 	logging.Logger.Info("Repository cloned successfully", "repository", envvars.Variables["REPOSITORY"], "commit", envvars.Variables["COMMIT"])
 
 	// Mix all the pipeline files
-	const pipelineDir = ".pipelines"
 	pipelineFolder := filepath.Join(repoDir, pipelineDir)
 	err, combinedData := pipelineprocessor.MixPipelineFiles(pipelineFolder)
 	if err != nil {
 		logging.Logger.Error("Error mixing pipeline files", "msg", err, "folder", pipelineFolder)
-		os.Exit(ErrCodeMixFiles)
+		os.Exit(exitcode.ErrCodeMixFiles)
 	}
 	logging.Logger.Info("Pipeline files mixed successfully", "folder", pipelineFolder)
 	for key, _ := range combinedData {
@@ -116,14 +140,18 @@ func app() {
 	var rawPipelines map[string]interface{}
 	if envvars.Variables["NAME"] == "" { // If no pipeline name is provided, launch all pipelines that match the triggers
 		logging.Logger.Info("Looking for pipelines using triggers")
-		rawPipelines = pipelineprocessor.FindPipelineByRegex(combinedData, envvars.Variables)
+		var changedFiles []string
+		if pipelineprocessor.HasPathTriggers(combinedData) {
+			changedFiles = getChangedFiles(auth)
+		}
+		rawPipelines = pipelineprocessor.FindPipelineByRegex(combinedData, envvars.Variables, changedFiles)
 	} else { // If a pipeline name is provided, launch the pipeline with that name
 		logging.Logger.Info("Looking for pipeline using name", "name", envvars.Variables["NAME"])
 		rawPipelines = pipelineprocessor.FindPipelineByName(combinedData, envvars.Variables, envvars.Variables["NAME"])
 	}
 	if len(rawPipelines) == 0 {
 		logging.Logger.Warn("No pipelines found")
-		os.Exit(ErrCodeOK)
+		os.Exit(exitcode.ErrCodeOK)
 	}
 
 	// Launch the pipelines
@@ -159,7 +187,7 @@ func app() {
 		}
 
 		// Deploy the pipeline
-		resourceName, resourceNamespace, err := deploy.Pipeline(name, namespaceName, spec)
+		resourceName, resourceNamespace, err := deploy.Pipeline(name, namespaceName, pipelineLabels(), spec)
 		if err != nil {
 			logging.Logger.Error("Error deploying pipeline", "error", err)
 			continue
@@ -172,8 +200,48 @@ func app() {
 	return
 }
 
+// getChangedFiles returns the files changed between the diff commit and the commit of the clone
+// If the diff commit cannot be fetched (e.g., it is gone after a force push), all the files of the commit are
+// considered changed, so the path triggers do not miss any change
+func getChangedFiles(auth transport.AuthMethod) []string {
+	diffCommit := envvars.Variables["DIFF_COMMIT"]
+	files, err := repository.ChangedFiles(repoDir, diffCommit, auth)
+	if err != nil {
+		logging.Logger.Warn("Error getting the changed files, all the files of the commit are considered changed",
+			"error", err, "diffCommit", diffCommit)
+		files, err = repository.Files(repoDir)
+		if err != nil {
+			logging.Logger.Error("Error listing the files of the commit", "error", err)
+			os.Exit(exitcode.ErrCodeCloneRepo)
+		}
+	}
+
+	logging.Logger.Info("Changed files found", "diffCommit", diffCommit, "files", len(files))
+	for _, file := range files {
+		logging.Logger.Debug("Changed file", "file", file)
+	}
+	return files
+}
+
 // getMD5Hash returns the MD5 hash of the text
 func getMD5Hash(text string) string {
 	hash := md5.Sum([]byte(text))
 	return hex.EncodeToString(hash[:])
 }
+
+// pipelineLabels returns the labels of the deployed pipelines: the request ID of the webhook, the name of the launcher
+// job and the route, if set. The webhook listener finds the pipelines of a job with them, and the cleaner the git
+// authentication of their repositories
+func pipelineLabels() map[string]string {
+	labels := make(map[string]string)
+	for label, env := range map[string]string{
+		launcherjob.LabelRequestID: requestIDEnv,
+		launcherjob.LabelJobName:   jobNameEnv,
+		launcherjob.LabelRoute:     routeNameEnv,
+	} {
+		if value := os.Getenv(env); value != "" {
+			labels[label] = value
+		}
+	}
+	return labels
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/sergiotejon/pipeManagerLauncher/internal/app/launcher/artifacts"
+	"github.com/sergiotejon/pipeManagerLauncher/internal/pkg/exitcode"
 	"github.com/sergiotejon/pipeManagerLauncher/internal/pkg/logging"
 )
 
@@ -57,7 +58,7 @@ var artifactDownloadCmd = &cobra.Command{
 		err := artifacts.Download(artifactsPaths, bucketFolder, artifactDestination)
 		if err != nil {
 			logging.Logger.Error("Artifact download from bucket failed", "error", err)
-			os.Exit(ErrCodeBucketDownload)
+			os.Exit(exitcode.ErrCodeBucketDownload)
 		}
 		logging.Logger.Info("Artifact download from bucket successfully")
 	},
@@ -95,7 +96,7 @@ var artifactUploadCmd = &cobra.Command{
 		err := artifacts.Upload(artifactsPaths, destination)
 		if err != nil {
 			logging.Logger.Error("Artifacts upload to bucket failed", "error", err)
-			os.Exit(ErrCodeBucketUpload)
+			os.Exit(exitcode.ErrCodeBucketUpload)
 		}
 		logging.Logger.Info("Artifacts upload to bucket successfully")
 	},
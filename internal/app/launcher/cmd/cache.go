@@ -9,6 +9,7 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/sergiotejon/pipeManagerLauncher/internal/app/launcher/artifacts"
+	"github.com/sergiotejon/pipeManagerLauncher/internal/pkg/exitcode"
 	"github.com/sergiotejon/pipeManagerLauncher/internal/pkg/logging"
 )
 
@@ -50,7 +51,7 @@ var cacheDownloadCmd = &cobra.Command{
 		err := artifacts.Download(cachePaths, bucketFolder, cacheDestination)
 		if err != nil {
 			logging.Logger.Error("Cache download from bucket failed", "error", err)
-			os.Exit(ErrCodeBucketDownload)
+			os.Exit(exitcode.ErrCodeBucketDownload)
 		}
 		logging.Logger.Info("Cache download from bucket successful")
 	},
@@ -85,7 +86,7 @@ var cacheUploadCmd = &cobra.Command{
 		err := artifacts.Upload(cachePaths, destination)
 		if err != nil {
 			logging.Logger.Error("Cache upload to bucket failed", "error", err)
-			os.Exit(ErrCodeBucketUpload)
+			os.Exit(exitcode.ErrCodeBucketUpload)
 		}
 		logging.Logger.Info("Cache upload to bucket successful")
 	},
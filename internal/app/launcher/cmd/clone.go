@@ -1,20 +1,30 @@
 package cmd
 
 import (
+	"errors"
 	"os"
 
+	"github.com/go-git/go-git/v5/plumbing/transport"
 	"github.com/spf13/cobra"
 
 	"github.com/sergiotejon/pipeManagerLauncher/internal/app/launcher/repository"
+	"github.com/sergiotejon/pipeManagerLauncher/internal/pkg/exitcode"
+	"github.com/sergiotejon/pipeManagerLauncher/internal/pkg/gitauth"
 	"github.com/sergiotejon/pipeManagerLauncher/internal/pkg/logging"
 	"github.com/sergiotejon/pipeManagerLauncher/pkg/config"
 )
 
 var (
-	repoURL     string
-	cloneDepth  int
-	cloneCommit string
-	destination string
+	repoURL          string
+	cloneDepth       int
+	cloneCommit      string
+	cloneRef         string
+	cloneMergeTarget string
+	cloneSparseDirs  []string
+	cloneSubmodules  bool
+	cloneLFS         bool
+	cloneLFSEndpoint string
+	destination      string
 )
 
 // cloneCmd represents the clone command
@@ -24,23 +34,95 @@ var cloneCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		// Set up the application
 		setup()
+		setCloneDefaults(cmd)
 
 		// Run the clone command
-		err := repository.Clone(repoURL, cloneDepth, cloneCommit, destination)
+		auth, sshCommand := getAuth(repoURL)
+		err := repository.Clone(destination, repository.Options{
+			URL:         repoURL,
+			Depth:       cloneDepth,
+			Commit:      cloneCommit,
+			Ref:         cloneRef,
+			MergeTarget: cloneMergeTarget,
+			SparseDirs:  cloneSparseDirs,
+			Submodules:  cloneSubmodules,
+			LFS:         cloneLFS,
+			LFSEndpoint: cloneLFSEndpoint,
+			Auth:        auth,
+			SSHCommand:  sshCommand,
+		})
 		if err != nil {
 			logging.Logger.Error("Error cloning repository", "error", err)
-			os.Exit(ErrCodeCloneRepo)
+			os.Exit(getCloneExitCode(err))
 		}
-		logging.Logger.Info("Repository cloned successfully", "destination", destination, "commit", cloneCommit, "depth", cloneDepth, "repository", repoURL)
+		logging.Logger.Info("Repository cloned successfully", "destination", destination, "commit", cloneCommit,
+			"ref", cloneRef, "mergeTarget", cloneMergeTarget, "sparseDirs", cloneSparseDirs, "submodules", cloneSubmodules, "lfs", cloneLFS, "depth", cloneDepth, "repository", repoURL)
 	},
 }
 
+// setCloneDefaults sets the flags not given in the command line to the values of the launcher configuration
+// The configuration is loaded by setup, after the flags are defined, so their defaults cannot be taken from it
+func setCloneDefaults(cmd *cobra.Command) {
+	flags := cmd.Flags()
+	if !flags.Changed("depth") {
+		cloneDepth = config.Launcher.Data.CloneDepth
+	}
+	if !flags.Changed("submodules") {
+		cloneSubmodules = config.Launcher.Data.Submodules
+	}
+	if !flags.Changed("lfs") {
+		cloneLFS = config.Launcher.Data.LFS.Enabled
+	}
+	if !flags.Changed("lfs-endpoint") {
+		cloneLFSEndpoint = config.Launcher.Data.LFS.Endpoint
+	}
+}
+
+// getAuth returns the authentication to clone the repository from the GIT_AUTH_* environment variables set by
+// the webhook listener, or nil if they are not set, along with the ssh command of the git commands for the SSH
+// authentication. It exits if the credentials cannot be read
+func getAuth(repositoryURL string) (transport.AuthMethod, string) {
+	gitAuth, err := gitauth.FromEnv()
+	if err != nil {
+		logging.Logger.Error("Error reading the git authentication", "error", err)
+		os.Exit(exitcode.ErrCodeGitAuth)
+	}
+
+	auth, err := gitauth.AuthMethod(gitAuth, repositoryURL)
+	if err != nil {
+		logging.Logger.Error("Error getting the git credentials", "error", err, "type", gitAuth.Type)
+		os.Exit(exitcode.ErrCodeGitAuth)
+	}
+
+	return auth, gitauth.SSHCommand(gitAuth)
+}
+
+// getCloneExitCode returns the exit code of the launcher for the error of the clone
+func getCloneExitCode(err error) int {
+	switch {
+	case errors.Is(err, repository.ErrAuth):
+		return exitcode.ErrCodeGitAuth
+	case errors.Is(err, repository.ErrRefNotFound):
+		return exitcode.ErrCodeRefNotFound
+	case errors.Is(err, repository.ErrMergeConflict):
+		return exitcode.ErrCodeMergeConflict
+	default:
+		return exitcode.ErrCodeCloneRepo
+	}
+}
+
 func init() {
 	var err error
 
 	cloneCmd.Flags().StringVar(&repoURL, "repository", "", "Repository URL")
-	cloneCmd.Flags().IntVar(&cloneDepth, "depth", config.Launcher.Data.CloneDepth, "Depth of the clone")
+	cloneCmd.Flags().IntVar(&cloneDepth, "depth", 0, "Depth of the clone (default launcher.cloneDepth of the configuration)")
 	cloneCmd.Flags().StringVar(&cloneCommit, "commit", "", "Commit to checkout")
+	cloneCmd.Flags().StringVar(&cloneRef, "ref", "", "Branch, tag or ref to fetch (e.g., refs/pull/1/head)")
+	cloneCmd.Flags().StringVar(&cloneMergeTarget, "merge-target", "", "Branch to merge the commit with")
+	cloneCmd.Flags().StringSliceVar(&cloneSparseDirs, "sparse", nil, "Directories to check out, fetching only the commit (e.g., .pipelines)")
+	cloneCmd.Flags().BoolVar(&cloneSubmodules, "submodules", false, "Initialize and check out the submodules recursively (default launcher.submodules of the configuration)")
+	cloneCmd.Flags().BoolVar(&cloneLFS, "lfs", false, "Download the LFS objects of the checkout with git-lfs (default launcher.lfs.enabled of the configuration)")
+	cloneCmd.Flags().StringVar(&cloneLFSEndpoint, "lfs-endpoint", "", "URL of the LFS server (default launcher.lfs.endpoint of the configuration, or the one of git-lfs)")
 	cloneCmd.Flags().StringVar(&destination, "destination", repoDir, "Destination directory")
 
 	err = cloneCmd.MarkFlagRequired("repository")
@@ -48,9 +130,4 @@ func init() {
 		logging.Logger.Error("Error marking flag as required", "error", err)
 		return
 	}
-	err = cloneCmd.MarkFlagRequired("commit")
-	if err != nil {
-		logging.Logger.Error("Error marking flag as required", "error", err)
-		return
-	}
 }
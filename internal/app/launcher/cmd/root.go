@@ -7,6 +7,7 @@ import (
 
 	"github.com/spf13/cobra"
 
+	"github.com/sergiotejon/pipeManagerLauncher/internal/pkg/configcmd"
 	"github.com/sergiotejon/pipeManagerLauncher/internal/pkg/version"
 )
 
@@ -34,6 +35,7 @@ func init() {
 	// Persistent flags
 	rootCmd.PersistentFlags().StringVarP(&configFile, "config", "c", "", "Path to the config file")
 	rootCmd.PersistentFlags().BoolP("version", "v", false, "Print the version")
+	configcmd.AddFlags(rootCmd.PersistentFlags())
 
 	// Bind the version flag
 	rootCmd.PersistentPreRun = func(cmd *cobra.Command, args []string) {
@@ -48,4 +50,5 @@ func init() {
 	rootCmd.AddCommand(cloneCmd)
 	rootCmd.AddCommand(artifactsCmd)
 	rootCmd.AddCommand(cacheCmd)
+	rootCmd.AddCommand(configcmd.NewCommand())
 }
@@ -20,12 +20,15 @@ const (
 	APIVersion = "pipemanager.sergiotejon.github.io/v1alpha1"
 )
 
-// Pipeline deploys a pipeline object to the Kubernetes cluster
-func Pipeline(name, namespace string, spec pipemanagerv1alpha1.PipelineSpec) (string, string, error) {
+// Pipeline deploys a pipeline object to the Kubernetes cluster with the given labels
+func Pipeline(name, namespace string, labels map[string]string, spec pipemanagerv1alpha1.PipelineSpec) (string, string, error) {
 	spec.Name = name
 
 	// Generate the pipeline object
 	pipeline := generatePipelineObject(name, namespace, spec)
+	if len(labels) > 0 {
+		pipeline.Labels = labels
+	}
 
 	// Deploy the pipeline object to the Kubernetes cluster
 	err := deployPipelineObject(pipeline)
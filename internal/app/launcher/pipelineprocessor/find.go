@@ -33,8 +33,9 @@ func FindPipelineByName(data map[string]interface{}, variables map[string]string
 	return pipelines
 }
 
-// FindPipelineByRegex finds the pipeline to launch based on the variables
-func FindPipelineByRegex(data map[string]interface{}, variables map[string]string) map[string]interface{} {
+// FindPipelineByRegex finds the pipeline to launch based on the variables and the changed files
+// The changed files are only used by the path triggers, see HasPathTriggers
+func FindPipelineByRegex(data map[string]interface{}, variables map[string]string, changedFiles []string) map[string]interface{} {
 	// Create a map to store the pipelines that match the triggers
 	pipelines := make(map[string]interface{})
 
@@ -61,6 +62,16 @@ func FindPipelineByRegex(data map[string]interface{}, variables map[string]strin
 			addPipeline := true
 			for _, trigger := range triggerList {
 				triggerMap := trigger.(map[string]interface{})
+
+				// Check if the changed files match the paths
+				if isPathTrigger(triggerMap) {
+					if !matchPathTrigger(triggerMap, changedFiles, key) {
+						addPipeline = false
+						break
+					}
+					continue
+				}
+
 				variableName := strings.ToUpper(triggerMap["variableName"].(string))
 				variableRegex := triggerMap["valueRegex"].(string)
 
@@ -0,0 +1,102 @@
+package pipelineprocessor
+
+import (
+	"fmt"
+
+	"github.com/bmatcuk/doublestar/v4"
+
+	"github.com/sergiotejon/pipeManagerLauncher/internal/pkg/logging"
+)
+
+const (
+	pathsKey       = "paths"       // pathsKey is the trigger key with the globs of the files that launch the pipeline
+	pathsIgnoreKey = "pathsIgnore" // pathsIgnoreKey is the trigger key with the globs of the files that are not taken into account
+)
+
+// HasPathTriggers returns true if any pipeline has a trigger on the changed files
+// The changed files are only computed when needed, as the diff commit may have to be fetched
+func HasPathTriggers(data map[string]interface{}) bool {
+	for key, value := range data {
+		if key == "global" {
+			continue
+		}
+		pipeline, ok := value.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		triggers, ok := pipeline["pipelineTriggers"].([]interface{})
+		if !ok {
+			continue
+		}
+		for _, trigger := range triggers {
+			if triggerMap, ok := trigger.(map[string]interface{}); ok && isPathTrigger(triggerMap) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isPathTrigger returns true if the trigger is on the changed files instead of a variable
+func isPathTrigger(trigger map[string]interface{}) bool {
+	_, paths := trigger[pathsKey]
+	_, pathsIgnore := trigger[pathsIgnoreKey]
+	return paths || pathsIgnore
+}
+
+// matchPathTrigger returns true if any changed file matches the paths of the trigger and none of its pathsIgnore
+// Without paths, any changed file not ignored matches. The globs support "**" for any number of directories (e.g.,
+// "services/api/**" or "**/*.md"), and the invalid ones are skipped
+func matchPathTrigger(trigger map[string]interface{}, changedFiles []string, pipeline string) bool {
+	paths := getPatterns(trigger[pathsKey], pathsKey, pipeline)
+	pathsIgnore := getPatterns(trigger[pathsIgnoreKey], pathsIgnoreKey, pipeline)
+
+	for _, file := range changedFiles {
+		if matchAny(pathsIgnore, file) {
+			continue
+		}
+		if trigger[pathsKey] == nil || matchAny(paths, file) {
+			logging.Logger.Debug("Path trigger matched", "file", file, "paths", paths, "pathsIgnore", pathsIgnore,
+				"pipeline", pipeline)
+			return true
+		}
+	}
+
+	logging.Logger.Debug("Path trigger not matched", "paths", paths, "pathsIgnore", pathsIgnore,
+		"changedFiles", len(changedFiles), "pipeline", pipeline)
+	return false
+}
+
+// getPatterns returns the valid globs of a trigger key, which is a list of globs or a single one
+func getPatterns(value interface{}, key string, pipeline string) []string {
+	var values []interface{}
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case []interface{}:
+		values = v
+	default:
+		values = []interface{}{v}
+	}
+
+	var patterns []string
+	for _, v := range values {
+		pattern, ok := v.(string)
+		if !ok || !doublestar.ValidatePattern(pattern) {
+			logging.Logger.Warn("Invalid path pattern, it is skipped", "key", key, "pattern", fmt.Sprintf("%v", v), "pipeline", pipeline)
+			continue
+		}
+		patterns = append(patterns, pattern)
+	}
+	return patterns
+}
+
+// matchAny returns true if the file matches any of the globs
+func matchAny(patterns []string, file string) bool {
+	for _, pattern := range patterns {
+		if doublestar.MatchUnvalidated(pattern, file) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,96 @@
+package pipelineprocessor
+
+import (
+	"os"
+	"testing"
+
+	"github.com/sergiotejon/pipeManagerLauncher/internal/pkg/logging"
+)
+
+func TestMain(m *testing.M) {
+	if err := logging.SetupLogger("error", "text", "stderr"); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+func TestMatchPathTrigger(t *testing.T) {
+	changedFiles := []string{"README.md", "services/api/main.go", "services/api/docs/api.md"}
+
+	tests := []struct {
+		name    string
+		trigger map[string]interface{}
+		files   []string
+		want    bool
+	}{
+		{"paths match", map[string]interface{}{"paths": []interface{}{"services/api/**"}}, changedFiles, true},
+		{"paths do not match", map[string]interface{}{"paths": []interface{}{"services/web/**"}}, changedFiles, false},
+		{"single path", map[string]interface{}{"paths": "README.md"}, changedFiles, true},
+		{"any directory", map[string]interface{}{"paths": []interface{}{"**/*.go"}}, changedFiles, true},
+		{"single star does not cross directories", map[string]interface{}{"paths": []interface{}{"services/*"}}, changedFiles, false},
+		{
+			"all matching files ignored",
+			map[string]interface{}{"paths": []interface{}{"services/api/**"}, "pathsIgnore": []interface{}{"**/*.md", "**/*.go"}},
+			changedFiles,
+			false,
+		},
+		{
+			"some matching files not ignored",
+			map[string]interface{}{"paths": []interface{}{"services/api/**"}, "pathsIgnore": []interface{}{"**/*.md"}},
+			changedFiles,
+			true,
+		},
+		{"only pathsIgnore with other files", map[string]interface{}{"pathsIgnore": []interface{}{"**/*.md"}}, changedFiles, true},
+		{"only pathsIgnore with ignored files", map[string]interface{}{"pathsIgnore": []interface{}{"**/*.md"}}, []string{"README.md"}, false},
+		{"no changed files", map[string]interface{}{"pathsIgnore": []interface{}{"**/*.md"}}, nil, false},
+		{"invalid glob skipped", map[string]interface{}{"paths": []interface{}{"services/[api/**", "README.md"}}, changedFiles, true},
+		{"only invalid globs", map[string]interface{}{"paths": []interface{}{"services/[api/**", 42}}, changedFiles, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matchPathTrigger(tt.trigger, tt.files, "test"); got != tt.want {
+				t.Errorf("matchPathTrigger() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHasPathTriggers(t *testing.T) {
+	tests := []struct {
+		name string
+		data map[string]interface{}
+		want bool
+	}{
+		{
+			"path trigger",
+			map[string]interface{}{"build": map[string]interface{}{"pipelineTriggers": []interface{}{
+				map[string]interface{}{"paths": []interface{}{"src/**"}},
+			}}},
+			true,
+		},
+		{
+			"variable trigger",
+			map[string]interface{}{"build": map[string]interface{}{"pipelineTriggers": []interface{}{
+				map[string]interface{}{"ref": "main"},
+			}}},
+			false,
+		},
+		{
+			"global ignored",
+			map[string]interface{}{"global": map[string]interface{}{"pipelineTriggers": []interface{}{
+				map[string]interface{}{"pathsIgnore": []interface{}{"**/*.md"}},
+			}}},
+			false,
+		},
+		{"no triggers", map[string]interface{}{"build": map[string]interface{}{}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HasPathTriggers(tt.data); got != tt.want {
+				t.Errorf("HasPathTriggers() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
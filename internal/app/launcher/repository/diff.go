@@ -0,0 +1,150 @@
+package repository
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	gitconfig "github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+)
+
+const (
+	fetchedDiff = "refs/pipe-manager/diff" // fetchedDiff is the local reference of the fetched diff commit
+	hashLength  = 40                       // hashLength is the length of the full hexadecimal hash of a commit
+)
+
+// ChangedFiles returns the files changed between the diff commit and the checked out commit of the clone in localDir
+// The diff commit is a revision of the clone (e.g., HEAD~1) or a full commit hash, fetched if it is not in the clone.
+// Without a diff commit, or with the all-zero hash of a new branch, all the files of the commit are returned, as there
+// is nothing to compare with. The trees of both commits are compared, so the diff is also right after a force push,
+// where the diff commit is not an ancestor of the commit
+func ChangedFiles(localDir string, diffCommit string, auth transport.AuthMethod) ([]string, error) {
+	if strings.Trim(diffCommit, "0") == "" {
+		return Files(localDir)
+	}
+
+	repository, head, err := openHead(localDir)
+	if err != nil {
+		return nil, err
+	}
+
+	hash, err := resolveDiffCommit(repository, diffCommit, auth)
+	if err != nil {
+		return nil, err
+	}
+	diff, err := repository.CommitObject(hash)
+	if err != nil {
+		return nil, fmt.Errorf("%w: diff commit %s", ErrRefNotFound, diffCommit)
+	}
+
+	from, err := diff.Tree()
+	if err != nil {
+		return nil, err
+	}
+	to, err := head.Tree()
+	if err != nil {
+		return nil, err
+	}
+	changes, err := object.DiffTree(from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	// The renames are a deletion and an insertion, so both paths are changed
+	names := map[string]bool{}
+	for _, change := range changes {
+		for _, name := range []string{change.From.Name, change.To.Name} {
+			if name != "" {
+				names[name] = true
+			}
+		}
+	}
+	files := make([]string, 0, len(names))
+	for name := range names {
+		files = append(files, name)
+	}
+	sort.Strings(files)
+
+	return files, nil
+}
+
+// resolveDiffCommit returns the hash of the diff commit, which is any revision of the clone (e.g., HEAD~1 or a short
+// hash) or the full hash of a commit, fetched if it is not in the clone
+// The other revisions cannot be fetched, so it returns an error wrapping ErrRefNotFound if they are not in the clone
+func resolveDiffCommit(repository *git.Repository, diffCommit string, auth transport.AuthMethod) (plumbing.Hash, error) {
+	if hash, err := repository.ResolveRevision(plumbing.Revision(diffCommit)); err == nil {
+		return *hash, nil
+	}
+	if !isFullHash(diffCommit) {
+		return plumbing.ZeroHash, fmt.Errorf("%w: diff commit '%s' is not in the clone and only a full commit hash "+
+			"can be fetched (the clone depth may be too small for a relative revision)", ErrRefNotFound, diffCommit)
+	}
+
+	// The diff commit has no history, the trees of both commits are enough
+	hash := plumbing.NewHash(diffCommit)
+	spec := gitconfig.RefSpec(fmt.Sprintf("%s:%s", diffCommit, fetchedDiff))
+	err := fetch(repository, spec, 1, auth)
+	if errors.Is(err, git.ErrExactSHA1NotSupported) {
+		// The remote does not allow to fetch a commit by its hash, so the branches are deepened until it is found
+		err = deepen(repository, "+refs/heads/*:refs/remotes/origin/*", hash, 1, auth)
+	}
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("error fetching the diff commit %s: %w", diffCommit, err)
+	}
+	return hash, nil
+}
+
+// isFullHash returns true if the value is the full hexadecimal hash of a commit
+func isFullHash(value string) bool {
+	if len(value) != hashLength {
+		return false
+	}
+	_, err := hex.DecodeString(value)
+	return err == nil
+}
+
+// Files returns all the files of the checked out commit of the clone in localDir
+// The files are read from the tree of the commit, so the sparse clones return all of them too
+func Files(localDir string) ([]string, error) {
+	_, head, err := openHead(localDir)
+	if err != nil {
+		return nil, err
+	}
+	tree, err := head.Tree()
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	err = tree.Files().ForEach(func(file *object.File) error {
+		files = append(files, file.Name)
+		return nil
+	})
+	return files, err
+}
+
+// openHead opens the clone in localDir and returns the checked out commit
+func openHead(localDir string) (*git.Repository, *object.Commit, error) {
+	repository, err := git.PlainOpen(localDir)
+	if err != nil {
+		return nil, nil, err
+	}
+	head, err := repository.Head()
+	if errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return nil, nil, fmt.Errorf("%w: HEAD", ErrRefNotFound)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	commit, err := repository.CommitObject(head.Hash())
+	if err != nil {
+		return nil, nil, err
+	}
+	return repository, commit, nil
+}
@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// commitFiles writes the files to the worktree and commits them, returning the hash of the commit
+func commitFiles(t *testing.T, repository *git.Repository, localDir string, files map[string]string) plumbing.Hash {
+	t.Helper()
+	workTree, err := repository.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		path := filepath.Join(localDir, name)
+		if err = os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err = os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err = workTree.Add(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	hash, err := workTree.Commit("commit", &git.CommitOptions{
+		Author: &object.Signature{Name: "test", Email: "test@localhost", When: time.Now()},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hash
+}
+
+func TestChangedFiles(t *testing.T) {
+	localDir := t.TempDir()
+	repository, err := git.PlainInit(localDir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := commitFiles(t, repository, localDir, map[string]string{"README.md": "readme", "services/api/main.go": "v1"})
+	commitFiles(t, repository, localDir, map[string]string{"services/api/main.go": "v2", "docs/index.md": "docs"})
+
+	tests := []struct {
+		name       string
+		diffCommit string
+		want       string
+		wantErr    error
+	}{
+		{"no diff commit", "", "README.md,docs/index.md,services/api/main.go", nil},
+		{"new branch", strings.Repeat("0", hashLength), "README.md,docs/index.md,services/api/main.go", nil},
+		{"full hash", first.String(), "docs/index.md,services/api/main.go", nil},
+		{"short hash", first.String()[:7], "docs/index.md,services/api/main.go", nil},
+		{"relative revision", "HEAD~1", "docs/index.md,services/api/main.go", nil},
+		{"same commit", "HEAD", "", nil},
+		{"revision not in the clone", "HEAD~5", "", ErrRefNotFound},
+		{"not a revision", "not-a-commit", "", ErrRefNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files, err := ChangedFiles(localDir, tt.diffCommit, nil)
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Fatalf("ChangedFiles(%s) error = %v, want %v", tt.diffCommit, err, tt.wantErr)
+			}
+			if got := strings.Join(files, ","); got != tt.want {
+				t.Errorf("ChangedFiles(%s) = %s, want %s", tt.diffCommit, got, tt.want)
+			}
+		})
+	}
+
+	// A full hash not in the clone is fetched, which fails as the clone has no remote
+	missing := strings.Repeat("a", hashLength)
+	if _, err = ChangedFiles(localDir, missing, nil); err == nil || !strings.Contains(err.Error(), "error fetching the diff commit") {
+		t.Errorf("ChangedFiles(%s) error = %v, want a fetch error", missing, err)
+	}
+}
+
+func TestIsFullHash(t *testing.T) {
+	tests := []struct {
+		value string
+		want  bool
+	}{
+		{"0123456789abcdef0123456789abcdef01234567", true},
+		{"0123456789ABCDEF0123456789ABCDEF01234567", true},
+		{"0123456", false},
+		{"HEAD~1", false},
+		{"0123456789abcdef0123456789abcdef0123456g", false},
+	}
+
+	for _, tt := range tests {
+		if got := isFullHash(tt.value); got != tt.want {
+			t.Errorf("isFullHash(%s) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+)
+
+// pullLFS replaces the LFS pointer files of the checkout with their objects running git lfs pull, so the .lfsconfig
+// of the repository is honored and the SSH remotes are authenticated with git-lfs-authenticate
+// The HTTP credentials are only sent to the hosts of the repository and the configured LFS endpoint, and the SSH
+// remotes use the SSH command of the options. With sparse directories, only the objects of their files are downloaded.
+// The files of the submodules are left as they are, since they have their own LFS server
+func pullLFS(localDir string, options Options) error {
+	env := lfsEnv(options)
+	commands := [][]string{
+		// The clean filter keeps the replaced files unmodified for git, no hooks are needed in the checkout
+		{"lfs", "install", "--local", "--skip-smudge"},
+		lfsPullArgs(options.SparseDirs),
+	}
+	for _, args := range commands {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = localDir
+		cmd.Env = append(os.Environ(), env...)
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			message := strings.TrimSpace(string(output))
+			err = fmt.Errorf("error running git %s: %v: %s", strings.Join(args, " "), err, message)
+			if isLFSAuthError(message) {
+				return fmt.Errorf("%w: %v", ErrAuth, err)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// lfsPullArgs returns the arguments of git lfs pull, only including the files of the sparse directories if any
+func lfsPullArgs(sparseDirs []string) []string {
+	args := []string{"lfs", "pull", remoteName}
+	if len(sparseDirs) == 0 {
+		return args
+	}
+
+	include := make([]string, 0, len(sparseDirs))
+	for _, dir := range sparseDirs {
+		include = append(include, strings.Trim(dir, "/")+"/**")
+	}
+	return append(args, "--include", strings.Join(include, ","))
+}
+
+// lfsEnv returns the environment variables of the git lfs commands
+// The configuration is passed with the GIT_CONFIG_* variables, so the credentials are not in the arguments of the
+// commands nor written to the checkout. The prompts are disabled, since there is no terminal in the launcher
+func lfsEnv(options Options) []string {
+	var settings [][2]string
+	if options.LFSEndpoint != "" {
+		settings = append(settings, [2]string{"lfs.url", options.LFSEndpoint})
+	}
+	if header := lfsAuthHeader(options.Auth); header != "" {
+		for _, host := range lfsHosts(options.URL, options.LFSEndpoint) {
+			settings = append(settings, [2]string{fmt.Sprintf("http.%s/.extraHeader", host), header})
+		}
+	}
+
+	env := []string{"GIT_TERMINAL_PROMPT=0", "GIT_CONFIG_COUNT=" + strconv.Itoa(len(settings))}
+	for i, setting := range settings {
+		env = append(env, fmt.Sprintf("GIT_CONFIG_KEY_%d=%s", i, setting[0]), fmt.Sprintf("GIT_CONFIG_VALUE_%d=%s", i, setting[1]))
+	}
+	if options.SSHCommand != "" {
+		env = append(env, "GIT_SSH_COMMAND="+options.SSHCommand)
+	}
+	return env
+}
+
+// lfsAuthHeader returns the Authorization header of the HTTP authentication of the repository, or empty if it has
+// none. The SSH authentication is done by git-lfs with the SSH command
+func lfsAuthHeader(auth transport.AuthMethod) string {
+	switch a := auth.(type) {
+	case *githttp.BasicAuth:
+		return "Authorization: Basic " + base64.StdEncoding.EncodeToString([]byte(a.Username+":"+a.Password))
+	case *githttp.TokenAuth:
+		return "Authorization: Bearer " + a.Token
+	}
+	return ""
+}
+
+// lfsHosts returns the scheme and host of the HTTP URLs of the repository and the LFS endpoint, without duplicates
+// (e.g., https://github.com)
+func lfsHosts(urls ...string) []string {
+	var hosts []string
+	for _, rawURL := range urls {
+		parsed, err := url.Parse(rawURL)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			continue
+		}
+		host := parsed.Scheme + "://" + parsed.Host
+		if !contains(hosts, host) {
+			hosts = append(hosts, host)
+		}
+	}
+	return hosts
+}
+
+// isLFSAuthError returns true if the output of git-lfs shows the LFS server rejected the credentials
+func isLFSAuthError(output string) bool {
+	for _, message := range []string{"Authentication required", "Authorization error", "HTTP 401", "HTTP 403",
+		"Permission denied", "Host key verification failed"} {
+		if strings.Contains(output, message) {
+			return true
+		}
+	}
+	return false
+}
+
+// contains returns true if the list contains the value
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,129 @@
+package repository
+
+import (
+	"strings"
+	"testing"
+
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+)
+
+func TestLFSPullArgs(t *testing.T) {
+	tests := []struct {
+		name       string
+		sparseDirs []string
+		want       string
+	}{
+		{"whole checkout", nil, "lfs pull origin"},
+		{"sparse directories", []string{".pipelines", "/templates/"}, "lfs pull origin --include .pipelines/**,templates/**"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := strings.Join(lfsPullArgs(tt.sparseDirs), " "); got != tt.want {
+				t.Errorf("lfsPullArgs() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLFSEnv(t *testing.T) {
+	tests := []struct {
+		name    string
+		options Options
+		want    []string
+	}{
+		{
+			name:    "no authentication",
+			options: Options{URL: "https://github.com/owner/name.git"},
+			want:    []string{"GIT_TERMINAL_PROMPT=0", "GIT_CONFIG_COUNT=0"},
+		},
+		{
+			name: "basic authentication and endpoint",
+			options: Options{
+				URL:         "https://github.com/owner/name.git",
+				LFSEndpoint: "https://lfs.example.com:8443/owner/name.git/info/lfs",
+				Auth:        &githttp.BasicAuth{Username: "user", Password: "secret"},
+			},
+			want: []string{
+				"GIT_TERMINAL_PROMPT=0",
+				"GIT_CONFIG_COUNT=3",
+				"GIT_CONFIG_KEY_0=lfs.url",
+				"GIT_CONFIG_VALUE_0=https://lfs.example.com:8443/owner/name.git/info/lfs",
+				"GIT_CONFIG_KEY_1=http.https://github.com/.extraHeader",
+				"GIT_CONFIG_VALUE_1=Authorization: Basic dXNlcjpzZWNyZXQ=",
+				"GIT_CONFIG_KEY_2=http.https://lfs.example.com:8443/.extraHeader",
+				"GIT_CONFIG_VALUE_2=Authorization: Basic dXNlcjpzZWNyZXQ=",
+			},
+		},
+		{
+			name: "token authentication",
+			options: Options{
+				URL:  "https://gitlab.com/owner/name.git",
+				Auth: &githttp.TokenAuth{Token: "token"},
+			},
+			want: []string{
+				"GIT_TERMINAL_PROMPT=0",
+				"GIT_CONFIG_COUNT=1",
+				"GIT_CONFIG_KEY_0=http.https://gitlab.com/.extraHeader",
+				"GIT_CONFIG_VALUE_0=Authorization: Bearer token",
+			},
+		},
+		{
+			name: "ssh authentication",
+			options: Options{
+				URL:        "git@github.com:owner/name.git",
+				SSHCommand: "ssh -i '/root/.ssh/id_rsa'",
+			},
+			want: []string{"GIT_TERMINAL_PROMPT=0", "GIT_CONFIG_COUNT=0", "GIT_SSH_COMMAND=ssh -i '/root/.ssh/id_rsa'"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := lfsEnv(tt.options)
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("lfsEnv() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestLFSHosts(t *testing.T) {
+	tests := []struct {
+		name string
+		urls []string
+		want string
+	}{
+		{"https", []string{"https://github.com/owner/name.git"}, "https://github.com"},
+		{"duplicated", []string{"https://github.com/owner/name.git", "https://github.com/owner/name.git/info/lfs"}, "https://github.com"},
+		{"port", []string{"http://git.local:3000/owner/name"}, "http://git.local:3000"},
+		{"ssh and scp-like URLs", []string{"ssh://git@github.com/owner/name.git", "git@github.com:owner/name.git"}, ""},
+		{"empty", []string{""}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := strings.Join(lfsHosts(tt.urls...), ","); got != tt.want {
+				t.Errorf("lfsHosts() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsLFSAuthError(t *testing.T) {
+	tests := []struct {
+		output string
+		want   bool
+	}{
+		{"batch response: Authentication required: Authorization error: https://github.com/owner/name.git/info/lfs/objects/batch", true},
+		{"git@github.com: Permission denied (publickey).", true},
+		{"Host key verification failed.", true},
+		{"batch response: Repository or object not found", false},
+	}
+
+	for _, tt := range tests {
+		if got := isLFSAuthError(tt.output); got != tt.want {
+			t.Errorf("isLFSAuthError(%q) = %v, want %v", tt.output, got, tt.want)
+		}
+	}
+}
@@ -1,34 +1,399 @@
 package repository
 
 import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
 	"github.com/go-git/go-git/v5"
+	gitconfig "github.com/go-git/go-git/v5/config"
 	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+)
+
+const (
+	remoteName     = "origin"                   // remoteName is the name of the remote of the repository
+	fetchedRef     = "refs/pipe-manager/ref"    // fetchedRef is the local reference of the fetched ref
+	fetchedCommit  = "refs/pipe-manager/commit" // fetchedCommit is the local reference of the fetched commit
+	fetchedTarget  = "refs/pipe-manager/target" // fetchedTarget is the local reference of the fetched merge target
+	deepenFactor   = 4                          // deepenFactor multiplies the depth on each deepening of a shallow clone
+	deepenAttempts = 3                          // deepenAttempts is the number of deepenings before fetching the whole history
+	fullDepth      = math.MaxInt32              // fullDepth deepens a shallow clone to the whole history
+)
+
+var (
+	// ErrRefNotFound is returned when the ref or the commit is not in the repository
+	ErrRefNotFound = errors.New("ref not found")
+	// ErrAuth is returned when the credentials are missing or rejected by the remote
+	ErrAuth = errors.New("authentication failed")
+	// ErrMergeConflict is returned when the commit cannot be merged with the target branch
+	ErrMergeConflict = errors.New("merge conflict")
 )
 
-func Clone(repositoryURL string, cloneDepth int, commitHash string, localDir string) error {
-	// Clone the repository
-	repository, err := git.PlainClone(localDir, false, &git.CloneOptions{
-		URL:   repositoryURL,
-		Depth: cloneDepth,
+// Options are the options to clone a repository
+// Without ref nor commit, the default branch is cloned. With a ref, the ref is fetched and its commit is checked out,
+// unless a commit is given too. With only a commit, the exact commit is fetched if the remote allows it
+type Options struct {
+	URL         string               // URL of the repository
+	Depth       int                  // Depth of the history fetched, 0 fetches the whole history
+	Commit      string               // Commit is the hash of the commit to check out (optional)
+	Ref         string               // Ref is the branch, tag or full ref to fetch (e.g., main, v1.0, refs/pull/1/head) (optional)
+	MergeTarget string               // MergeTarget is the branch the commit is merged with after the checkout (optional)
+	SparseDirs  []string             // SparseDirs are the only directories checked out, the whole tree if empty (optional)
+	Submodules  bool                 // Submodules initializes and checks out the submodules recursively
+	LFS         bool                 // LFS downloads the LFS objects of the pointer files of the checkout with git-lfs
+	LFSEndpoint string               // LFSEndpoint is the URL of the LFS server, read by git-lfs from .lfsconfig or the URL if empty (optional)
+	Auth        transport.AuthMethod // Auth is the authentication, nil clones without authentication
+	SSHCommand  string               // SSHCommand is the ssh command of git-lfs with the key of the SSH authentication (optional)
+}
+
+// Clone clones the repository into the local directory and checks out the commit in detached mode
+// If the commit is not in the fetched history, the clone is deepened until it is found. If a merge target is given,
+// the commit is merged with the target branch, so the working tree is the result of merging a pull request
+// With sparse directories, only the commit is fetched, with no history, and only the files of those directories are
+// written.
+// The merge and the submodules need the whole working tree, so the sparse directories are ignored with them. The
+// submodules are fetched with the same authentication and depth as the repository
+// The errors wrap ErrRefNotFound, ErrAuth or ErrMergeConflict, so the caller can tell why the clone failed
+func Clone(localDir string, options Options) error {
+	if options.MergeTarget != "" || options.Submodules {
+		options.SparseDirs = nil
+	}
+	if len(options.SparseDirs) > 0 {
+		options.Depth = 1
+	}
+
+	repository, err := git.PlainInit(localDir, false)
+	if err != nil {
+		return err
+	}
+	remote, err := repository.CreateRemote(&gitconfig.RemoteConfig{
+		Name: remoteName,
+		URLs: []string{options.URL},
+	})
+	if err != nil {
+		return err
+	}
+
+	// The advertised refs resolve the short names of the refs and tell the missing refs from the auth errors
+	advertised, err := remote.List(&git.ListOptions{Auth: options.Auth})
+	if err != nil {
+		return wrapError(err)
+	}
+
+	var spec gitconfig.RefSpec
+	var local plumbing.ReferenceName
+	switch {
+	case options.Ref != "":
+		name, err := resolveRef(advertised, options.Ref)
+		if err != nil {
+			return err
+		}
+		spec, local = gitconfig.RefSpec(fmt.Sprintf("+%s:%s", name, fetchedRef)), fetchedRef
+	case options.Commit != "":
+		spec, local = gitconfig.RefSpec(fmt.Sprintf("%s:%s", options.Commit, fetchedCommit)), fetchedCommit
+	default:
+		spec, local = gitconfig.RefSpec(fmt.Sprintf("+%s:%s", plumbing.HEAD, fetchedRef)), fetchedRef
+	}
+
+	err = fetch(repository, spec, options.Depth, options.Auth)
+	if errors.Is(err, git.ErrExactSHA1NotSupported) {
+		// The remote does not allow to fetch a commit by its hash, so the branches are fetched instead
+		spec, local = gitconfig.RefSpec("+refs/heads/*:refs/remotes/origin/*"), ""
+		err = fetch(repository, spec, options.Depth, options.Auth)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Get the commit to check out
+	hash := plumbing.NewHash(options.Commit)
+	if options.Commit == "" {
+		ref, err := repository.Reference(local, true)
+		if err != nil {
+			return fmt.Errorf("%w: %s", ErrRefNotFound, options.Ref)
+		}
+		commit, err := peelCommit(repository, ref.Hash())
+		if err != nil {
+			return err
+		}
+		hash = commit
+	}
+	if err = deepen(repository, spec, hash, options.Depth, options.Auth); err != nil {
+		return err
+	}
+
+	if len(options.SparseDirs) > 0 {
+		err = checkoutDirs(repository, localDir, hash, options.SparseDirs)
+	} else {
+		err = checkout(repository, hash)
+	}
+	if err != nil {
+		return err
+	}
+
+	if options.MergeTarget != "" {
+		if err = merge(repository, localDir, advertised, spec, options); err != nil {
+			return err
+		}
+	}
+
+	if options.Submodules {
+		if err = updateSubmodules(repository, options); err != nil {
+			return err
+		}
+	}
+
+	if options.LFS {
+		if err = pullLFS(localDir, options); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkout checks out the commit in detached mode, writing the whole tree
+func checkout(repository *git.Repository, hash plumbing.Hash) error {
+	workTree, err := repository.Worktree()
+	if err != nil {
+		return err
+	}
+	return workTree.Checkout(&git.CheckoutOptions{
+		Hash:  hash,
+		Force: true,
 	})
+}
+
+// checkoutDirs points HEAD to the commit and writes only the files of the given directories of its tree
+// The sparse checkout of go-git writes the whole tree anyway, so the files are written from the tree instead. The
+// directories missing in the commit are skipped, and the index is not written
+func checkoutDirs(repository *git.Repository, localDir string, hash plumbing.Hash, dirs []string) error {
+	commit, err := repository.CommitObject(hash)
+	if err != nil {
+		return err
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		return err
+	}
+
+	for _, dir := range dirs {
+		subTree, err := tree.Tree(strings.Trim(filepath.ToSlash(dir), "/"))
+		if errors.Is(err, object.ErrDirectoryNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		err = subTree.Files().ForEach(func(file *object.File) error {
+			return writeFile(filepath.Join(localDir, dir, filepath.FromSlash(file.Name)), file)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return repository.Storer.SetReference(plumbing.NewHashReference(plumbing.HEAD, hash))
+}
+
+// writeFile writes the file of the tree to the path, creating its directory
+// The symbolic links are written as links and the executable files keep their mode
+func writeFile(path string, file *object.File) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	contents, err := file.Contents()
 	if err != nil {
 		return err
 	}
+	if file.Mode == filemode.Symlink {
+		return os.Symlink(contents, path)
+	}
 
-	// Get the worktree
+	mode, err := file.Mode.ToOSFileMode()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(contents), mode.Perm())
+}
+
+// updateSubmodules initializes and checks out the submodules of the checkout recursively
+// The relative URLs of the submodules are resolved from the URL of the repository
+func updateSubmodules(repository *git.Repository, options Options) error {
 	workTree, err := repository.Worktree()
 	if err != nil {
 		return err
 	}
+	submodules, err := workTree.Submodules()
+	if err != nil {
+		return fmt.Errorf("error reading the submodules: %w", err)
+	}
 
-	// Checkout the commit
-	err = workTree.Checkout(&git.CheckoutOptions{
-		Hash: plumbing.NewHash(commitHash),
+	err = submodules.Update(&git.SubmoduleUpdateOptions{
+		Init:              true,
+		RecurseSubmodules: git.DefaultSubmoduleRecursionDepth,
+		Auth:              options.Auth,
+		Depth:             options.Depth,
 	})
 	if err != nil {
+		return fmt.Errorf("error updating the submodules: %w", wrapError(err))
+	}
+	return nil
+}
+
+// fetch fetches the refspec from the remote with the given depth
+func fetch(repository *git.Repository, spec gitconfig.RefSpec, depth int, auth transport.AuthMethod) error {
+	err := repository.Fetch(&git.FetchOptions{
+		RemoteName: remoteName,
+		RefSpecs:   []gitconfig.RefSpec{spec},
+		Depth:      depth,
+		Auth:       auth,
+		Tags:       git.NoTags,
+		Force:      true,
+	})
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return wrapError(err)
+	}
+	if depth > 0 {
+		return pruneShallow(repository)
+	}
+	return nil
+}
+
+// pruneShallow removes the commits whose parents have been fetched from the shallow commits of the repository
+// go-git adds the new shallow commits when the clone is deepened, but never removes the old ones, so the git command
+// would see a truncated history (e.g., when merging)
+func pruneShallow(repository *git.Repository) error {
+	shallows, err := repository.Storer.Shallow()
+	if err != nil || len(shallows) == 0 {
+		return err
+	}
+
+	// The shallow commits are kept while they are missing or any of their parents has not been fetched
+	var kept []plumbing.Hash
+	for _, hash := range shallows {
+		commit, err := repository.CommitObject(hash)
+		if err != nil {
+			kept = append(kept, hash)
+			continue
+		}
+		for _, parent := range commit.ParentHashes {
+			if repository.Storer.HasEncodedObject(parent) != nil {
+				kept = append(kept, hash)
+				break
+			}
+		}
+	}
+	if len(kept) == len(shallows) {
+		return nil
+	}
+	return repository.Storer.SetShallow(kept)
+}
+
+// deepen fetches the refspec again with a greater depth until the commit is in the repository
+// The depth is multiplied on each attempt, and the whole history is fetched at last
+func deepen(repository *git.Repository, spec gitconfig.RefSpec, hash plumbing.Hash, depth int, auth transport.AuthMethod) error {
+	for attempt := 0; ; attempt++ {
+		if _, err := repository.CommitObject(hash); err == nil {
+			return nil
+		}
+		if depth == 0 || depth == fullDepth {
+			return fmt.Errorf("%w: commit %s", ErrRefNotFound, hash)
+		}
+
+		depth *= deepenFactor
+		if attempt >= deepenAttempts {
+			depth = fullDepth
+		}
+		if err := fetch(repository, spec, depth, auth); err != nil {
+			return err
+		}
+	}
+}
+
+// merge fetches the target branch with the whole history of both sides and merges it into the checked out commit
+// The history of the checked out commit is fetched again with the refspec it was fetched with
+// go-git only supports fast-forward merges, so the merge commit is created by the git command
+func merge(repository *git.Repository, localDir string, advertised []*plumbing.Reference, commitSpec gitconfig.RefSpec, options Options) error {
+	name, err := resolveRef(advertised, options.MergeTarget)
+	if err != nil {
+		return err
+	}
 
+	// The merge base can be anywhere in the history, so the shallow clone is not enough
+	head, err := repository.Head()
+	if err != nil {
+		return err
+	}
+	spec := gitconfig.RefSpec(fmt.Sprintf("+%s:%s", name, fetchedTarget))
+	if err = fetch(repository, spec, fullDepth, options.Auth); err != nil {
 		return err
 	}
+	if options.Depth > 0 {
+		if err = fetch(repository, commitSpec, fullDepth, options.Auth); err != nil {
+			return err
+		}
+	}
+
+	cmd := exec.Command("git", "-c", "user.name=pipe-manager", "-c", "user.email=pipe-manager@localhost",
+		"merge", "--no-ff", "--no-edit", "-m", fmt.Sprintf("Merge %s into %s", name.Short(), head.Hash()), fetchedTarget)
+	cmd.Dir = localDir
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		abort := exec.Command("git", "merge", "--abort")
+		abort.Dir = localDir
+		_ = abort.Run()
+		return fmt.Errorf("%w with %s: %s", ErrMergeConflict, options.MergeTarget, strings.TrimSpace(string(output)))
+	}
 
 	return nil
 }
+
+// resolveRef returns the full name of the advertised ref for a branch, a tag or a full ref
+func resolveRef(advertised []*plumbing.Reference, ref string) (plumbing.ReferenceName, error) {
+	candidates := []plumbing.ReferenceName{plumbing.ReferenceName(ref)}
+	if !strings.HasPrefix(ref, "refs/") {
+		candidates = []plumbing.ReferenceName{plumbing.NewBranchReferenceName(ref), plumbing.NewTagReferenceName(ref)}
+	}
+
+	for _, candidate := range candidates {
+		for _, reference := range advertised {
+			if reference.Name() == candidate {
+				return candidate, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("%w: %s", ErrRefNotFound, ref)
+}
+
+// peelCommit returns the commit of the hash, following the annotated tags
+func peelCommit(repository *git.Repository, hash plumbing.Hash) (plumbing.Hash, error) {
+	tag, err := repository.TagObject(hash)
+	if err != nil {
+		return hash, nil
+	}
+	commit, err := tag.Commit()
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	return commit.Hash, nil
+}
+
+// wrapError wraps the errors of the remote caused by the credentials with ErrAuth
+func wrapError(err error) error {
+	message := err.Error()
+	if errors.Is(err, transport.ErrAuthenticationRequired) || errors.Is(err, transport.ErrAuthorizationFailed) ||
+		strings.Contains(message, "unable to authenticate") || strings.Contains(message, "knownhosts") {
+		return fmt.Errorf("%w: %v", ErrAuth, err)
+	}
+	return err
+}
@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+)
+
+func TestResolveRef(t *testing.T) {
+	hash := plumbing.NewHash("0123456789abcdef0123456789abcdef01234567")
+	advertised := []*plumbing.Reference{
+		plumbing.NewHashReference("refs/heads/main", hash),
+		plumbing.NewHashReference("refs/heads/release", hash),
+		plumbing.NewHashReference("refs/tags/v1.0.0", hash),
+		plumbing.NewHashReference("refs/tags/release", hash),
+		plumbing.NewHashReference("refs/pull/42/head", hash),
+	}
+
+	tests := []struct {
+		name    string
+		ref     string
+		want    plumbing.ReferenceName
+		wantErr error
+	}{
+		{"branch", "main", "refs/heads/main", nil},
+		{"tag", "v1.0.0", "refs/tags/v1.0.0", nil},
+		{"branch before tag", "release", "refs/heads/release", nil},
+		{"full branch ref", "refs/heads/main", "refs/heads/main", nil},
+		{"full tag ref", "refs/tags/release", "refs/tags/release", nil},
+		{"pull request ref", "refs/pull/42/head", "refs/pull/42/head", nil},
+		{"unknown branch", "develop", "", ErrRefNotFound},
+		{"full ref not shortened", "refs/main", "", ErrRefNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveRef(advertised, tt.ref)
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Fatalf("resolveRef(%s) error = %v, want %v", tt.ref, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("resolveRef(%s) = %s, want %s", tt.ref, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWrapError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"authentication required", transport.ErrAuthenticationRequired, true},
+		{"authorization failed", fmt.Errorf("fetch: %w", transport.ErrAuthorizationFailed), true},
+		{"ssh key", errors.New("ssh: handshake failed: ssh: unable to authenticate"), true},
+		{"known hosts", errors.New("ssh: handshake failed: knownhosts: key is unknown"), true},
+		{"other", transport.ErrRepositoryNotFound, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := wrapError(tt.err)
+			if got := errors.Is(err, ErrAuth); got != tt.want {
+				t.Errorf("wrapError(%v) is ErrAuth = %v, want %v", tt.err, got, tt.want)
+			}
+			if !errors.Is(err, tt.err) && !tt.want {
+				t.Errorf("wrapError(%v) = %v, want the same error", tt.err, err)
+			}
+		})
+	}
+}
@@ -1,44 +1,46 @@
 package databuilder
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
+	"reflect"
 	"strconv"
 
-	"github.com/google/cel-go/cel"
-	"github.com/google/cel-go/checker/decls"
-	"github.com/google/cel-go/ext"
+	"github.com/google/cel-go/common/types/ref"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/sergiotejon/pipeManagerLauncher/internal/app/webhook-listener/metrics"
+	"github.com/sergiotejon/pipeManagerLauncher/internal/pkg/celprogram"
+	"github.com/sergiotejon/pipeManagerLauncher/pkg/config"
 )
 
-// evaluateCELExpression creates a CEL environment and evaluates the given expression
-// It returns an error if the expression cannot be compiled, the program cannot be created, the value of the expression
-// is nil, the value is not a string, or the value is not a boolean
+// CompileExpression compiles the given CEL expression and keeps its program, so it is only evaluated when a request
+// arrives. It is the expression compiler of the webhook configuration
+func CompileExpression(expression string) error {
+	_, err := celprogram.Compile(expression)
+	return err
+}
+
+// ResetExpressions drops the programs of the compiled expressions. It is the expression reset of the webhook
+// configuration, called when the configuration is reloaded
+func ResetExpressions() {
+	celprogram.Reset()
+}
+
+// evaluateCELExpression evaluates the given expression using its compiled program
+// The program is compiled when the configuration is loaded, so only the evaluation is done for each request
+// It returns an error if the expression cannot be compiled, the value of the expression is nil, or the value is not a
+// string or a boolean
 // It returns the value of the expression if it is successful
 // The celExpression is the CEL expression to be evaluated
 // The jsonData is the JSON data to be used in the evaluation. It is a map of string keys and interface values from the webhook payload
 func evaluateCELExpression(celExpresion string, jsonData map[string]interface{}) (string, error) {
-	// Create the CEL environment
-	env, err := cel.NewEnv(
-		// Extensible functions and types
-		ext.Strings(), ext.Encoders(), ext.Math(), ext.Sets(), ext.Lists(),
-		// Declaration of variable 'data'
-		cel.Declarations(
-			decls.NewVar("data", decls.NewMapType(decls.String, decls.Dyn)),
-		),
-	)
-	if err != nil {
-		return "", err
-	}
-
-	// Compile the CEL expression
-	ast, issues := env.Compile(celExpresion)
-	if issues != nil && issues.Err() != nil {
-		return "", issues.Err()
-	}
-
-	// Create the CEL program
-	program, err := env.Program(ast)
+	// Get the compiled CEL program
+	program, err := celprogram.Compile(celExpresion)
 	if err != nil {
+		metrics.CELErrorsTotal.WithLabelValues("expression").Inc()
 		return "", err
 	}
 
@@ -47,12 +49,14 @@ func evaluateCELExpression(celExpresion string, jsonData map[string]interface{})
 		"data": jsonData,
 	})
 	if err != nil {
+		metrics.CELErrorsTotal.WithLabelValues("expression").Inc()
 		return "", err
 	}
 
 	value := out.Value()
 	// Check if the value is nil
 	if value == nil {
+		metrics.CELErrorsTotal.WithLabelValues("expression").Inc()
 		return "", errors.New(fmt.Sprintf("expression '%s' did not return a value", celExpresion))
 	}
 	// Check if the value is a boolean and convert it to a string if it is
@@ -61,8 +65,140 @@ func evaluateCELExpression(celExpresion string, jsonData map[string]interface{})
 	}
 	// Check if the value is a string
 	if _, ok := value.(string); !ok {
+		metrics.CELErrorsTotal.WithLabelValues("expression").Inc()
 		return "", errors.New(fmt.Sprintf("expression '%s' did not return a string value", celExpresion))
 	}
 
 	return value.(string), nil
 }
+
+// evaluateCELCondition evaluates the given condition using its compiled program
+// It returns an error if the expression cannot be compiled or the value of the expression is not a boolean
+// It returns the value of the condition if it is successful
+// The jsonData is the JSON data to be used in the evaluation. It is a map of string keys and interface values from the webhook payload
+func evaluateCELCondition(celExpresion string, jsonData map[string]interface{}) (bool, error) {
+	// Get the compiled CEL program
+	program, err := celprogram.Compile(celExpresion)
+	if err != nil {
+		metrics.CELErrorsTotal.WithLabelValues("condition").Inc()
+		return false, err
+	}
+
+	out, _, err := program.Eval(map[string]interface{}{
+		"data": jsonData,
+	})
+	if err != nil {
+		metrics.CELErrorsTotal.WithLabelValues("condition").Inc()
+		return false, err
+	}
+
+	value, ok := out.Value().(bool)
+	if !ok {
+		metrics.CELErrorsTotal.WithLabelValues("condition").Inc()
+		return false, errors.New(fmt.Sprintf("condition '%s' did not return a boolean value", celExpresion))
+	}
+
+	return value, nil
+}
+
+// evaluateCELVariable evaluates the expression of the given variable using its compiled program
+// Unlike evaluateCELExpression, the value can be a string, boolean, number, list or map. Numbers are formatted as
+// their decimal representation and lists and maps as JSON with sorted keys, so the same value is always serialized
+// the same way
+// It returns an error if the expression cannot be compiled, the value is nil, or the value does not match the type
+// declared for the variable, if any
+func evaluateCELVariable(variable config.Variable, jsonData map[string]interface{}) (string, error) {
+	// Get the compiled CEL program
+	program, err := celprogram.Compile(variable.Expression)
+	if err != nil {
+		metrics.CELErrorsTotal.WithLabelValues("variable").Inc()
+		return "", err
+	}
+
+	out, _, err := program.Eval(map[string]interface{}{
+		"data": jsonData,
+	})
+	if err != nil {
+		metrics.CELErrorsTotal.WithLabelValues("variable").Inc()
+		return "", err
+	}
+
+	value, err := formatCELValue(out, variable.Type)
+	if err != nil {
+		metrics.CELErrorsTotal.WithLabelValues("variable").Inc()
+		return "", errors.New(fmt.Sprintf("expression '%s' %v", variable.Expression, err))
+	}
+
+	return value, nil
+}
+
+// formatCELValue serializes the value returned by a CEL expression checking it matches the declared type
+// An empty declared type accepts any type of value
+// Doubles without decimals are accepted as int, as all the numbers of the JSON payloads are doubles
+func formatCELValue(out ref.Val, declaredType string) (string, error) {
+	typeName := out.Type().TypeName()
+	accepts := func(types ...string) bool {
+		if declaredType == "" {
+			return true
+		}
+		for _, t := range types {
+			if t == declaredType {
+				return true
+			}
+		}
+		return false
+	}
+
+	switch value := out.Value().(type) {
+	case nil:
+		return "", errors.New("did not return a value")
+	case string:
+		if accepts(config.VariableTypeString) {
+			return value, nil
+		}
+	case bool:
+		if accepts(config.VariableTypeBool) {
+			return strconv.FormatBool(value), nil
+		}
+	case int64:
+		if accepts(config.VariableTypeInt, config.VariableTypeDouble) {
+			return strconv.FormatInt(value, 10), nil
+		}
+	case uint64:
+		if accepts(config.VariableTypeInt, config.VariableTypeDouble) {
+			return strconv.FormatUint(value, 10), nil
+		}
+	case float64:
+		if declaredType == config.VariableTypeInt && value == math.Trunc(value) {
+			return strconv.FormatInt(int64(value), 10), nil
+		}
+		if accepts(config.VariableTypeDouble) {
+			return strconv.FormatFloat(value, 'f', -1, 64), nil
+		}
+	default:
+		if (typeName == "list" && accepts(config.VariableTypeList)) || (typeName == "map" && accepts(config.VariableTypeMap)) {
+			return formatCELStructuredValue(out)
+		}
+		if typeName != "list" && typeName != "map" {
+			return "", errors.New(fmt.Sprintf("returned an unsupported %s value", typeName))
+		}
+	}
+
+	return "", errors.New(fmt.Sprintf("returned a %s value, expected %s", typeName, declaredType))
+}
+
+// formatCELStructuredValue serializes a list or map returned by a CEL expression as JSON
+func formatCELStructuredValue(out ref.Val) (string, error) {
+	native, err := out.ConvertToNative(reflect.TypeOf(&structpb.Value{}))
+	if err != nil {
+		return "", err
+	}
+
+	// encoding/json sorts the keys of the maps, so the output is deterministic
+	data, err := json.Marshal(native.(*structpb.Value).AsInterface())
+	if err != nil {
+		return "", err
+	}
+
+	return string(data), nil
+}
@@ -0,0 +1,115 @@
+package databuilder
+
+import (
+	"testing"
+
+	"github.com/sergiotejon/pipeManagerLauncher/pkg/config"
+)
+
+// testData is the data of the CEL expressions, as decoded from a JSON payload
+var testData = map[string]interface{}{
+	"body": map[string]interface{}{
+		"ref":     "refs/heads/main",
+		"number":  float64(42),
+		"ratio":   0.5,
+		"draft":   false,
+		"labels":  []interface{}{"bug", "ci"},
+		"sender":  map[string]interface{}{"login": "octocat", "id": float64(1)},
+		"missing": nil,
+	},
+}
+
+func TestEvaluateCELVariable(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		varType    string
+		want       string
+		wantErr    bool
+	}{
+		{"string", "data.body.ref", "", "refs/heads/main", false},
+		{"string declared", "data.body.ref", config.VariableTypeString, "refs/heads/main", false},
+		{"bool", "data.body.draft", "", "false", false},
+		{"bool declared", "data.body.draft", config.VariableTypeBool, "false", false},
+		{"json number as int", "data.body.number", config.VariableTypeInt, "42", false},
+		{"json number without type", "data.body.number", "", "42", false},
+		{"double", "data.body.ratio", config.VariableTypeDouble, "0.5", false},
+		{"double as int", "data.body.ratio", config.VariableTypeInt, "", true},
+		{"int literal", "1 + 2", config.VariableTypeInt, "3", false},
+		{"int literal as double", "1 + 2", config.VariableTypeDouble, "3", false},
+		{"uint literal", "3u", config.VariableTypeInt, "3", false},
+		{"list", "data.body.labels", config.VariableTypeList, `["bug","ci"]`, false},
+		{"list of the map", "data.body.labels.map(l, l.upperAscii())", "", `["BUG","CI"]`, false},
+		{"map with sorted keys", "data.body.sender", config.VariableTypeMap, `{"id":1,"login":"octocat"}`, false},
+		{"list declared as map", "data.body.labels", config.VariableTypeMap, "", true},
+		{"string declared as int", "data.body.ref", config.VariableTypeInt, "", true},
+		{"bool declared as string", "data.body.draft", config.VariableTypeString, "", true},
+		{"null value", "data.body.missing", "", "", true},
+		{"missing key", "data.body.unknown", "", "", true},
+		{"invalid expression", "data.body.", "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := evaluateCELVariable(config.Variable{Expression: tt.expression, Type: tt.varType}, testData)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("evaluateCELVariable() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("evaluateCELVariable() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvaluateCELExpression(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		want       string
+		wantErr    bool
+	}{
+		{"string", "data.body.ref", "refs/heads/main", false},
+		{"bool", "data.body.draft", "false", false},
+		{"number", "data.body.number", "", true},
+		{"list", "data.body.labels", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := evaluateCELExpression(tt.expression, testData)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("evaluateCELExpression() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("evaluateCELExpression() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvaluateCELCondition(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		want       bool
+		wantErr    bool
+	}{
+		{"true", "data.body.ref.startsWith('refs/heads/')", true, false},
+		{"false", "data.body.draft", false, false},
+		{"in list", "'ci' in data.body.labels", true, false},
+		{"not a bool", "data.body.ref", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := evaluateCELCondition(tt.expression, testData)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("evaluateCELCondition() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("evaluateCELCondition() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -11,26 +11,50 @@ import (
 )
 
 // PipelineData represents a pipeline to be executed
-// It contains the name, path, event, repository, commit, and variables (a map of variable names and their values)
+// It contains the name, path, event, repository, commit, variables (a map of variable names and their values), the
+// concurrency policy and key, and the event route configuration it was built from
 type PipelineData struct {
-	Name          string
-	Path          string
-	Event         string
-	Repository    string
-	GitSecretName string
-	Commit        string
-	DiffCommit    string
-	Variables     map[string]string
+	Name              string            `json:"name"`
+	Path              string            `json:"path"`
+	Event             string            `json:"event"`
+	Repository        string            `json:"repository"`
+	GitSecretName     string            `json:"gitSecretName"`
+	Commit            string            `json:"commit"`
+	DiffCommit        string            `json:"diffCommit"`
+	Ref               string            `json:"ref,omitempty"`
+	MergeTarget       string            `json:"mergeTarget,omitempty"`
+	Variables         map[string]string `json:"variables"`
+	ConcurrencyPolicy string            `json:"concurrencyPolicy,omitempty"`
+	ConcurrencyKey    string            `json:"concurrencyKey,omitempty"`
+	Source            *config.Event     `json:"-"`
 }
 
-// Run executes the parser with the given payload and routes configuration returning a Pipeline
+// FilteredError is returned when the delivery does not meet the 'when' condition of the route or the event
+// It contains the route, the event type (empty if the route condition failed) and the condition that was not met
+type FilteredError struct {
+	Route     string
+	Event     string
+	Condition string
+}
+
+// Error returns the description of the condition that filtered the delivery
+func (e *FilteredError) Error() string {
+	if e.Event == "" {
+		return fmt.Sprintf("condition '%s' of route '%s' not met", e.Condition, e.Route)
+	}
+	return fmt.Sprintf("condition '%s' of event '%s' in route '%s' not met", e.Condition, e.Event, e.Route)
+}
+
+// Run executes the parser with the given payload and routes configuration returning the Pipelines to launch
 // It returns an error if the payload cannot be unmarshalled, the route cannot be found, the event route cannot be found,
 // or the CEL expression cannot be evaluated
-// It returns the PipelineData object if the parser is successful
+// It returns a FilteredError if the 'when' condition of the route or of every event route of the type is false
+// It returns a PipelineData object for each event route of the type whose condition is met, so one delivery can
+// launch several pipelines (e.g., one per component of a monorepo or per target cluster)
 // The payload is the JSON data to be parsed from the webhook
 // The path is the path of the route to be executed from the request
 // The routes are the list of routes to be executed with their associated events and variables from the configuration
-func Run(payload json.RawMessage, path string, routes []config.Route) (*PipelineData, error) {
+func Run(payload json.RawMessage, path string, routes []config.Route) ([]*PipelineData, error) {
 	var err error
 
 	// Unmarshal JSON data into a map
@@ -46,6 +70,18 @@ func Run(payload json.RawMessage, path string, routes []config.Route) (*Pipeline
 		return nil, err
 	}
 
+	// Check the condition of the route
+	if route.When != "" {
+		var matched bool
+		matched, err = evaluateCELCondition(route.When, jsonData)
+		if err != nil {
+			return nil, err
+		}
+		if !matched {
+			return nil, &FilteredError{Route: route.Name, Condition: route.When}
+		}
+	}
+
 	// Retrieve the event value from the route
 	var eventType string
 	eventType, err = evaluateCELExpression(route.EventType, jsonData)
@@ -62,13 +98,36 @@ func Run(payload json.RawMessage, path string, routes []config.Route) (*Pipeline
 		}
 	}
 
-	// Retrieve the event route information from the events configuration
-	var event *config.Event
-	event, err = getEventRouteByEventType(eventType, route.Events)
+	// Retrieve the event routes information from the events configuration
+	var events []config.Event
+	events, err = getEventRoutesByEventType(eventType, route.Events, jsonData)
 	if err != nil {
+		var filtered *FilteredError
+		if errors.As(err, &filtered) {
+			filtered.Route = route.Name
+		}
 		return nil, err
 	}
 
+	// Build the pipeline data of each event route
+	pipelines := make([]*PipelineData, 0, len(events))
+	for i := range events {
+		var pipelineData *PipelineData
+		pipelineData, err = buildPipelineData(route, &events[i], eventType, gitSecretName, jsonData)
+		if err != nil {
+			return nil, err
+		}
+		pipelines = append(pipelines, pipelineData)
+	}
+
+	return pipelines, nil
+}
+
+// buildPipelineData evaluates the expressions of the event route and returns the resulting PipelineData
+// It returns an error if any CEL expression cannot be evaluated
+func buildPipelineData(route *config.Route, event *config.Event, eventType, gitSecretName string, jsonData map[string]interface{}) (*PipelineData, error) {
+	var err error
+
 	// Evaluate the repository from the event route
 	var repository string
 	repository, err = evaluateCELExpression(event.Repository, jsonData)
@@ -92,29 +151,64 @@ func Run(payload json.RawMessage, path string, routes []config.Route) (*Pipeline
 		}
 	}
 
+	// Evaluate the ref to fetch and the branch to merge with, if defined
+	ref := ""
+	if event.Ref != "" {
+		ref, err = evaluateCELExpression(event.Ref, jsonData)
+		if err != nil {
+			return nil, err
+		}
+	}
+	mergeTarget := ""
+	if event.MergeTarget != "" {
+		mergeTarget, err = evaluateCELExpression(event.MergeTarget, jsonData)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Evaluate the concurrency key. The concurrency of the event route takes precedence over the one of the route
+	concurrency := route.Concurrency
+	if event.Concurrency != nil {
+		concurrency = event.Concurrency
+	}
+	concurrencyPolicy, concurrencyKey := "", ""
+	if concurrency != nil && concurrency.Policy != config.ConcurrencyAllow {
+		concurrencyPolicy = concurrency.Policy
+		concurrencyKey, err = evaluateCELExpression(concurrency.Key, jsonData)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	logging.Logger.Info("Data Builder", "route", route.Name, "eventType", eventType, "repository", repository, "commit", commit)
 
 	// Create a PipelineData object
 	pipelineData := PipelineData{
-		Name:          route.Name,
-		Path:          route.Path,
-		Event:         eventType,
-		Repository:    repository,
-		GitSecretName: gitSecretName,
-		Commit:        commit,
-		DiffCommit:    diffCommit,
-		Variables:     make(map[string]string),
+		Name:              route.Name,
+		Path:              route.Path,
+		Event:             eventType,
+		Repository:        repository,
+		GitSecretName:     gitSecretName,
+		Commit:            commit,
+		DiffCommit:        diffCommit,
+		Ref:               ref,
+		MergeTarget:       mergeTarget,
+		Variables:         make(map[string]string),
+		ConcurrencyPolicy: concurrencyPolicy,
+		ConcurrencyKey:    concurrencyKey,
+		Source:            event,
 	}
 
 	// Evaluate the variables and store them in the PipelineData object
-	for key, value := range event.Variables {
-		pipelineData.Variables[key], err = evaluateCELExpression(value, jsonData)
+	for key, variable := range event.Variables {
+		pipelineData.Variables[key], err = evaluateCELVariable(variable, jsonData)
 		if err != nil {
 			return nil, err
 		}
 
 		logging.Logger.Info("Data Builder", "variableName", key,
-			"celExpression", value,
+			"celExpression", variable.Expression,
 			"valueRetrieved", pipelineData.Variables[key],
 			"event", eventType,
 			"route", route.Name,
@@ -124,6 +218,22 @@ func Run(payload json.RawMessage, path string, routes []config.Route) (*Pipeline
 	return &pipelineData, nil
 }
 
+// GetDeliveryID evaluates the delivery ID expression of the route with the given payload
+// It returns an empty string if the route does not define a delivery ID expression
+// The payload is the JSON data of the request from the webhook
+func GetDeliveryID(payload json.RawMessage, route *config.Route) (string, error) {
+	if route.DeliveryID == "" {
+		return "", nil
+	}
+
+	var jsonData map[string]interface{}
+	if err := json.Unmarshal(payload, &jsonData); err != nil {
+		return "", err
+	}
+
+	return evaluateCELExpression(route.DeliveryID, jsonData)
+}
+
 // Retrieve the route from the routes configuration
 func getConfiguredRouteByPath(path string, routes []config.Route) (*config.Route, error) {
 	for _, route := range routes {
@@ -134,16 +244,38 @@ func getConfiguredRouteByPath(path string, routes []config.Route) (*config.Route
 	return nil, errors.New(fmt.Sprintf("route path '%s' not found", path))
 }
 
-// getEventRouteByEventType retrieves the event route from the events configuration
-// It returns an error if the event route is not found
-// It returns the event route if it is found
+// getEventRoutesByEventType retrieves the event routes from the events configuration
+// It returns an error if no event route is found, or a FilteredError if the condition of every event route of the
+// type is false
+// It returns all the event routes of the type whose condition is true or not defined, in the configuration order
 // The eventType is the type of the event to be retrieved
 // The events are the list of events to be searched. These are the events associated with the route and its path from the configuration
-func getEventRouteByEventType(eventType string, events []config.Event) (*config.Event, error) {
+// The jsonData is the JSON data used to evaluate the conditions of the events
+func getEventRoutesByEventType(eventType string, events []config.Event, jsonData map[string]interface{}) ([]config.Event, error) {
+	var matchedEvents []config.Event
+	var filtered *FilteredError
 	for _, event := range events {
-		if event.Type == eventType {
-			return &event, nil
+		if event.Type != eventType {
+			continue
+		}
+		if event.When != "" {
+			matched, err := evaluateCELCondition(event.When, jsonData)
+			if err != nil {
+				return nil, err
+			}
+			if !matched {
+				filtered = &FilteredError{Event: eventType, Condition: event.When}
+				continue
+			}
 		}
+		matchedEvents = append(matchedEvents, event)
+	}
+
+	if len(matchedEvents) > 0 {
+		return matchedEvents, nil
+	}
+	if filtered != nil {
+		return nil, filtered
 	}
 	return nil, errors.New(fmt.Sprintf("event route '%s' not found", eventType))
 }
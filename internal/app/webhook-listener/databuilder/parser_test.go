@@ -0,0 +1,132 @@
+package databuilder
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/sergiotejon/pipeManagerLauncher/internal/pkg/logging"
+	"github.com/sergiotejon/pipeManagerLauncher/pkg/config"
+)
+
+func TestMain(m *testing.M) {
+	if err := logging.SetupLogger("error", "text", "stderr"); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+// testRoutes are the routes of the tests. The push events of the github route launch a pipeline in the repository
+// and, for the main and release branches, another one in the deploy repository
+var testRoutes = []config.Route{
+	{
+		Name:      "github",
+		Path:      "/github",
+		EventType: "data.headers['X-Github-Event'][0]",
+		Events: []config.Event{
+			{Type: "push", Repository: "data.body.repository.clone_url", Commit: "data.body.after"},
+			{Type: "push", When: "data.body.ref == 'refs/heads/main'", Repository: "'https://github.com/org/deploy.git'", Commit: "data.body.after"},
+			{Type: "push", When: "data.body.ref == 'refs/heads/release'", Repository: "'https://github.com/org/deploy.git'", Commit: "data.body.after"},
+			{Type: "pull_request", Repository: "data.body.repository.clone_url"},
+		},
+	},
+	{
+		Name:      "releases",
+		Path:      "/releases",
+		When:      "data.body.ref.startsWith('refs/heads/')",
+		EventType: "data.headers['X-Github-Event'][0]",
+		Events: []config.Event{
+			{Type: "push", When: "data.body.ref == 'refs/heads/release'", Repository: "data.body.repository.clone_url"},
+		},
+	},
+}
+
+// testPayload returns the payload of a delivery of the given event type and ref
+func testPayload(eventType, ref string) json.RawMessage {
+	return json.RawMessage(`{"headers":{"X-Github-Event":["` + eventType + `"]},"body":{"ref":"` + ref +
+		`","after":"abc123","repository":{"clone_url":"https://github.com/org/repo.git"}}}`)
+}
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name         string
+		path         string
+		payload      json.RawMessage
+		wantRepos    string
+		wantFiltered string
+		wantErr      bool
+	}{
+		{
+			name:      "one pipeline per matching event",
+			path:      "/github",
+			payload:   testPayload("push", "refs/heads/main"),
+			wantRepos: "https://github.com/org/repo.git,https://github.com/org/deploy.git",
+		},
+		{
+			name:      "events whose condition is not met skipped",
+			path:      "/github",
+			payload:   testPayload("push", "refs/heads/feature"),
+			wantRepos: "https://github.com/org/repo.git",
+		},
+		{
+			name:      "other event type",
+			path:      "/github",
+			payload:   testPayload("pull_request", "refs/heads/feature"),
+			wantRepos: "https://github.com/org/repo.git",
+		},
+		{
+			name:         "condition of every event not met",
+			path:         "/releases",
+			payload:      testPayload("push", "refs/heads/main"),
+			wantFiltered: "condition 'data.body.ref == 'refs/heads/release'' of event 'push' in route 'releases' not met",
+		},
+		{
+			name:         "condition of the route not met",
+			path:         "/releases",
+			payload:      testPayload("push", "refs/tags/v1"),
+			wantFiltered: "condition 'data.body.ref.startsWith('refs/heads/')' of route 'releases' not met",
+		},
+		{
+			name:    "event type not configured",
+			path:    "/github",
+			payload: testPayload("issues", "refs/heads/main"),
+			wantErr: true,
+		},
+		{
+			name:    "route not configured",
+			path:    "/gitlab",
+			payload: testPayload("push", "refs/heads/main"),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pipelines, err := Run(tt.payload, tt.path, testRoutes)
+
+			var filtered *FilteredError
+			if errors.As(err, &filtered) {
+				if filtered.Error() != tt.wantFiltered {
+					t.Errorf("Run() filtered = %s, want %s", filtered.Error(), tt.wantFiltered)
+				}
+				return
+			}
+			if (err != nil) != (tt.wantErr || tt.wantFiltered != "") {
+				t.Fatalf("Run() error = %v, want error %v", err, tt.wantErr || tt.wantFiltered != "")
+			}
+
+			repos := make([]string, 0, len(pipelines))
+			for _, pipelineData := range pipelines {
+				if pipelineData.Name != "github" || pipelineData.Path != tt.path {
+					t.Errorf("pipeline = %+v, want a pipeline of the route %s", pipelineData, tt.path)
+				}
+				repos = append(repos, pipelineData.Repository)
+			}
+			if got := strings.Join(repos, ","); got != tt.wantRepos {
+				t.Errorf("Run() repositories = %s, want %s", got, tt.wantRepos)
+			}
+		})
+	}
+}
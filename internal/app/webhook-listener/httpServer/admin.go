@@ -0,0 +1,34 @@
+package httpServer
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/sergiotejon/pipeManagerLauncher/internal/pkg/logging"
+	"github.com/sergiotejon/pipeManagerLauncher/internal/pkg/secrets"
+	"github.com/sergiotejon/pipeManagerLauncher/pkg/config"
+)
+
+// adminHandler wraps the handler of an administration endpoint, so it is only called if the request has the admin
+// token as bearer token in the Authorization header
+func adminHandler(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, err := secrets.ReadCached(*config.Webhook.Data.Admin.Token)
+		if err != nil {
+			logging.Logger.Error("Error reading admin token", "error", fmt.Sprintf("%v", err))
+			http.Error(w, "Error reading admin token", http.StatusInternalServerError)
+			return
+		}
+
+		received, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !found || subtle.ConstantTimeCompare([]byte(received), token) != 1 {
+			logging.Logger.Warn("Rejected request with invalid admin token", "path", r.URL.Path)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		next(w, r)
+	}
+}
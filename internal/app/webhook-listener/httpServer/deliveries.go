@@ -0,0 +1,115 @@
+package httpServer
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	defaultDeliveryTTL = time.Hour // defaultDeliveryTTL is the time a delivery ID is remembered when it is not set in the configuration
+	deliveryRetryAfter = "30"      // deliveryRetryAfter is the seconds a retry of a delivery in progress must wait
+)
+
+// Status of a delivery already accepted by the server
+const (
+	deliveryNew        = iota // deliveryNew is a delivery not seen before, or whose processing failed
+	deliveryInProgress        // deliveryInProgress is a delivery accepted and not processed yet
+	deliveryDone              // deliveryDone is a delivery processed successfully
+)
+
+// delivery is a delivery already accepted by the server
+type delivery struct {
+	requestID string
+	done      bool
+	expiresAt time.Time
+}
+
+// deliveryRegistry is a TTL-bounded set of the delivery IDs accepted by the server
+// It allows to answer the retried deliveries with the request ID of the original one instead of launching a new job
+type deliveryRegistry struct {
+	mu        sync.Mutex
+	seen      map[string]delivery // seen contains the accepted deliveries indexed by route and delivery ID
+	byRequest map[string]string   // byRequest contains the keys of the seen deliveries indexed by request ID
+	lastSweep time.Time
+}
+
+// deliveries is the registry of the deliveries accepted by the server
+var deliveries = &deliveryRegistry{
+	seen:      make(map[string]delivery),
+	byRequest: make(map[string]string),
+}
+
+// register records the delivery of the route with the given request ID
+// If the delivery was already seen and has not expired, it returns the original request ID and whether it is still
+// in progress or done. Otherwise it returns the given request ID and deliveryNew
+func (d *deliveryRegistry) register(route, deliveryID, requestID string, ttl time.Duration) (string, int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now()
+	d.sweep(now)
+
+	key := route + "/" + deliveryID
+	if existing, ok := d.seen[key]; ok && now.Before(existing.expiresAt) {
+		if existing.done {
+			return existing.requestID, deliveryDone
+		}
+		return existing.requestID, deliveryInProgress
+	}
+
+	d.seen[key] = delivery{
+		requestID: requestID,
+		expiresAt: now.Add(ttl),
+	}
+	d.byRequest[requestID] = key
+
+	return requestID, deliveryNew
+}
+
+// complete marks the delivery of the given request ID as processed successfully, so its retries are answered with
+// success instead of asking the provider to retry later
+func (d *deliveryRegistry) complete(requestID string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if key, ok := d.byRequest[requestID]; ok {
+		existing := d.seen[key]
+		existing.done = true
+		d.seen[key] = existing
+	}
+}
+
+// forget removes the delivery of the given request ID, so a retry of the delivery is processed again
+// It is used when the processing of the job fails
+func (d *deliveryRegistry) forget(requestID string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if key, ok := d.byRequest[requestID]; ok {
+		delete(d.seen, key)
+		delete(d.byRequest, requestID)
+	}
+}
+
+// sweep removes the expired deliveries. It runs at most once per minute
+func (d *deliveryRegistry) sweep(now time.Time) {
+	if now.Sub(d.lastSweep) < time.Minute {
+		return
+	}
+	d.lastSweep = now
+
+	for key, existing := range d.seen {
+		if now.After(existing.expiresAt) {
+			delete(d.seen, key)
+			delete(d.byRequest, existing.requestID)
+		}
+	}
+}
+
+// getDeliveryTTL returns the configured time to remember the delivery IDs
+func getDeliveryTTL(seconds int) time.Duration {
+	if seconds <= 0 {
+		return defaultDeliveryTTL
+	}
+	return time.Duration(seconds) * time.Second
+}
@@ -0,0 +1,56 @@
+package httpServer
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDeliveryRegistry(t *testing.T) {
+	registry := &deliveryRegistry{
+		seen:      make(map[string]delivery),
+		byRequest: make(map[string]string),
+	}
+
+	steps := []struct {
+		name       string
+		action     func() (string, int)
+		wantID     string
+		wantStatus int
+	}{
+		{"first delivery", func() (string, int) { return registry.register("github", "d1", "r1", time.Hour) }, "r1", deliveryNew},
+		{"retry in progress", func() (string, int) { return registry.register("github", "d1", "r2", time.Hour) }, "r1", deliveryInProgress},
+		{"same ID other route", func() (string, int) { return registry.register("gitlab", "d1", "r3", time.Hour) }, "r3", deliveryNew},
+		{"retry after success", func() (string, int) {
+			registry.complete("r1")
+			return registry.register("github", "d1", "r4", time.Hour)
+		}, "r1", deliveryDone},
+		{"retry after failure", func() (string, int) {
+			registry.forget("r3")
+			return registry.register("gitlab", "d1", "r5", time.Hour)
+		}, "r5", deliveryNew},
+		{"retry after expiration", func() (string, int) {
+			registry.register("bitbucket", "d2", "r6", -time.Second)
+			return registry.register("bitbucket", "d2", "r7", time.Hour)
+		}, "r7", deliveryNew},
+	}
+
+	for _, step := range steps {
+		gotID, gotStatus := step.action()
+		if gotID != step.wantID || gotStatus != step.wantStatus {
+			t.Errorf("%s: got (%s, %d), want (%s, %d)", step.name, gotID, gotStatus, step.wantID, step.wantStatus)
+		}
+	}
+}
+
+func TestGetDeliveryTTL(t *testing.T) {
+	tests := map[int]time.Duration{
+		0:   defaultDeliveryTTL,
+		-1:  defaultDeliveryTTL,
+		120: 2 * time.Minute,
+	}
+	for seconds, want := range tests {
+		if got := getDeliveryTTL(seconds); got != want {
+			t.Errorf("getDeliveryTTL(%d) = %v, want %v", seconds, got, want)
+		}
+	}
+}
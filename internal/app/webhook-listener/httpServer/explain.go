@@ -0,0 +1,150 @@
+package httpServer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	batchv1 "k8s.io/api/batch/v1"
+
+	"github.com/sergiotejon/pipeManagerLauncher/internal/app/webhook-listener/databuilder"
+	"github.com/sergiotejon/pipeManagerLauncher/internal/app/webhook-listener/pipeline"
+	"github.com/sergiotejon/pipeManagerLauncher/internal/pkg/logging"
+	"github.com/sergiotejon/pipeManagerLauncher/pkg/config"
+)
+
+// explainPrefix is the prefix of the explain endpoint. The rest of the path is the path of the route to explain
+const explainPrefix = "/_explain"
+
+// Explanation is the response of the explain endpoint
+// It contains the route and the pipelines that would be launched, or the reason why the delivery would be filtered
+// or rejected
+type Explanation struct {
+	Route     string              `json:"route"`
+	Path      string              `json:"path"`
+	Filtered  string              `json:"filtered,omitempty"`
+	Error     string              `json:"error,omitempty"`
+	Pipelines []ExplainedPipeline `json:"pipelines,omitempty"`
+}
+
+// ExplainedPipeline is a pipeline that would be launched
+// It contains the evaluated data of the pipeline and the Kubernetes Job that would be created
+type ExplainedPipeline struct {
+	Event         string                       `json:"event"`
+	GitSecretName string                       `json:"gitSecretName"`
+	Repository    string                       `json:"repository"`
+	Commit        string                       `json:"commit"`
+	DiffCommit    string                       `json:"diffCommit"`
+	Ref           string                       `json:"ref,omitempty"`
+	MergeTarget   string                       `json:"mergeTarget,omitempty"`
+	Variables     map[string]ExplainedVariable `json:"variables"`
+	Job           *batchv1.Job                 `json:"job"`
+}
+
+// ExplainedVariable is a variable of a pipeline with its CEL expression and the value retrieved
+type ExplainedVariable struct {
+	Expression string `json:"expression"`
+	Type       string `json:"type,omitempty"`
+	Value      string `json:"value"`
+}
+
+// explainHandler runs the data builder with the posted payload as if it was received by the route, and answers with
+// the explanation of the pipelines and jobs that would be launched, without creating anything in the cluster
+// The path of the route is the path of the request without the explain prefix (e.g., /_explain/github -> /github)
+func explainHandler(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, explainPrefix)
+
+	bodyBytes, err := io.ReadAll(r.Body)
+	if err != nil {
+		logging.Logger.Error("Error reading request body", "error", fmt.Sprintf("%v", err))
+		http.Error(w, "Error reading request body", http.StatusInternalServerError)
+		return
+	}
+	var jsonCheck interface{}
+	if err = json.Unmarshal(bodyBytes, &jsonCheck); err != nil {
+		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+		return
+	}
+
+	// The admin token is not part of the simulated delivery
+	r.Header.Del("Authorization")
+	job := newJob(r, path, bodyBytes)
+	job.RequestID = uuid.New().String()
+
+	config.RLock()
+	explanation, statusCode := explainJob(job)
+	config.RUnlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err = encoder.Encode(explanation); err != nil {
+		logging.Logger.Error("Error writing response", "error", fmt.Sprintf("%v", err))
+	}
+}
+
+// explainJob runs the data builder for the job and builds the Kubernetes Jobs of the resulting pipelines
+// It returns the explanation and the status code of the response
+func explainJob(job Job) (Explanation, int) {
+	explanation := Explanation{Path: job.Path}
+
+	route := getRouteByPath(job.Path)
+	if route == nil {
+		explanation.Error = fmt.Sprintf("route path '%s' not found", job.Path)
+		return explanation, http.StatusNotFound
+	}
+	explanation.Route = route.Name
+
+	jsonData, err := json.MarshalIndent(job, "", "  ")
+	if err != nil {
+		explanation.Error = err.Error()
+		return explanation, http.StatusInternalServerError
+	}
+
+	pipelines, err := databuilder.Run(jsonData, job.Path, config.Webhook.Data.Routes)
+	var filtered *databuilder.FilteredError
+	if errors.As(err, &filtered) {
+		explanation.Filtered = err.Error()
+		return explanation, http.StatusOK
+	}
+	if err != nil {
+		explanation.Error = err.Error()
+		return explanation, http.StatusUnprocessableEntity
+	}
+
+	for i, pipelineData := range pipelines {
+		variables := make(map[string]ExplainedVariable)
+		for name, value := range pipelineData.Variables {
+			variables[name] = ExplainedVariable{
+				Expression: pipelineData.Source.Variables[name].Expression,
+				Type:       pipelineData.Source.Variables[name].Type,
+				Value:      value,
+			}
+		}
+
+		launcherJob, err := pipeline.BuildJob(job.RequestID, i, pipelineData)
+		if err != nil {
+			explanation.Error = err.Error()
+			return explanation, http.StatusInternalServerError
+		}
+
+		explanation.Pipelines = append(explanation.Pipelines, ExplainedPipeline{
+			Event:         pipelineData.Event,
+			GitSecretName: pipelineData.GitSecretName,
+			Repository:    pipelineData.Repository,
+			Commit:        pipelineData.Commit,
+			DiffCommit:    pipelineData.DiffCommit,
+			Ref:           pipelineData.Ref,
+			MergeTarget:   pipelineData.MergeTarget,
+			Variables:     variables,
+			Job:           launcherJob,
+		})
+	}
+
+	return explanation, http.StatusOK
+}
@@ -0,0 +1,149 @@
+package httpServer
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	batchv1 "k8s.io/api/batch/v1"
+
+	"github.com/sergiotejon/pipeManagerLauncher/internal/app/webhook-listener/databuilder"
+	"github.com/sergiotejon/pipeManagerLauncher/pkg/config"
+)
+
+// testRoutes are the routes of the tests of the webhook listener
+// The pushes to refs/heads/skip are filtered out
+var testRoutes = []config.Route{{
+	Name:      "github",
+	Path:      "/github",
+	EventType: "data.headers['X-Github-Event'][0]",
+	When:      "data.body.ref != 'refs/heads/skip'",
+	Events: []config.Event{{
+		Type:       "push",
+		Repository: "data.body.repository.clone_url",
+		Commit:     "data.body.after",
+		Variables:  map[string]config.Variable{"REF": {Expression: "data.body.ref"}},
+	}},
+}}
+
+// setTestConfig sets the routes of the tests and a launcher configuration until the test ends
+// The admin token is read from a file with the given token
+func setTestConfig(t *testing.T, adminToken string) {
+	t.Helper()
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte(adminToken), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	previousWebhook, previousLauncher := config.Webhook, config.Launcher
+	t.Cleanup(func() {
+		config.Webhook, config.Launcher = previousWebhook, previousLauncher
+	})
+	config.Webhook.Data.Routes = testRoutes
+	config.Webhook.Data.Admin.Token = &config.SecretSource{File: tokenFile}
+	config.Launcher.Data.Namespace = "pipe-manager"
+	config.Launcher.Data.ImageName = "launcher"
+	config.Launcher.Data.JobNamePrefix = "pipeline-launcher"
+}
+
+// failLaunchJob makes the test fail if a job is launched until the test ends
+func failLaunchJob(t *testing.T) {
+	t.Helper()
+	previous := launchJob
+	launchJob = func(job *batchv1.Job, _ *databuilder.PipelineData) (string, error) {
+		t.Errorf("job %s launched", job.Name)
+		return job.Name, nil
+	}
+	t.Cleanup(func() { launchJob = previous })
+}
+
+func TestExplainHandler(t *testing.T) {
+	setTestConfig(t, "admin-token")
+	failLaunchJob(t)
+	handler := adminHandler(explainHandler)
+
+	const push = `{"ref": "refs/heads/main", "after": "abc123", "repository": {"clone_url": "https://github.com/org/repo.git"}}`
+
+	tests := []struct {
+		name          string
+		path          string
+		token         string
+		body          string
+		wantStatus    int
+		wantError     string
+		wantFiltered  string
+		wantPipelines int
+	}{
+		{"no token", "/_explain/github", "", push, http.StatusUnauthorized, "", "", 0},
+		{"wrong token", "/_explain/github", "other-token", push, http.StatusUnauthorized, "", "", 0},
+		{"unknown route", "/_explain/gitlab", "admin-token", push, http.StatusNotFound, "route path '/gitlab' not found", "", 0},
+		{"invalid JSON", "/_explain/github", "admin-token", "{", http.StatusBadRequest, "", "", 0},
+		{
+			"filtered", "/_explain/github", "admin-token", `{"ref": "refs/heads/skip"}`, http.StatusOK, "",
+			"condition 'data.body.ref != 'refs/heads/skip'' of route 'github' not met", 0,
+		},
+		{"data builder error", "/_explain/github", "admin-token", `{"ref": "refs/heads/main"}`, http.StatusUnprocessableEntity, "no such key: repository", "", 0},
+		{"explained", "/_explain/github", "admin-token", push, http.StatusOK, "", "", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+			r.Header.Set("X-GitHub-Event", "push")
+			if tt.token != "" {
+				r.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			w := httptest.NewRecorder()
+			handler(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if w.Header().Get("Content-Type") != "application/json" {
+				return
+			}
+
+			var explanation Explanation
+			if err := json.Unmarshal(w.Body.Bytes(), &explanation); err != nil {
+				t.Fatalf("invalid response %s: %v", w.Body.String(), err)
+			}
+			if !strings.Contains(explanation.Error, tt.wantError) || (tt.wantError == "" && explanation.Error != "") {
+				t.Errorf("error = %s, want %s", explanation.Error, tt.wantError)
+			}
+			if explanation.Filtered != tt.wantFiltered {
+				t.Errorf("filtered = %s, want %s", explanation.Filtered, tt.wantFiltered)
+			}
+			if len(explanation.Pipelines) != tt.wantPipelines {
+				t.Fatalf("pipelines = %d, want %d", len(explanation.Pipelines), tt.wantPipelines)
+			}
+			if tt.wantPipelines == 0 {
+				return
+			}
+
+			explained := explanation.Pipelines[0]
+			if explanation.Route != "github" || explained.Event != "push" || explained.Repository != "https://github.com/org/repo.git" ||
+				explained.Commit != "abc123" || explained.Variables["REF"].Value != "refs/heads/main" ||
+				explained.Variables["REF"].Expression != "data.body.ref" {
+				t.Errorf("explanation = %+v", explanation)
+			}
+			if explained.Job == nil || explained.Job.Namespace != "pipe-manager" || !strings.HasPrefix(explained.Job.Name, "pipeline-launcher-") ||
+				len(explained.Job.Spec.Template.Spec.Containers) == 0 {
+				t.Fatalf("job = %+v, want the launcher job", explained.Job)
+			}
+			env := make(map[string]string)
+			for _, e := range explained.Job.Spec.Template.Spec.Containers[0].Env {
+				env[e.Name] = e.Value
+			}
+			if env["PIPELINE_REPOSITORY"] != "https://github.com/org/repo.git" || env["PIPELINE_VARIABLE_REF"] != "refs/heads/main" {
+				t.Errorf("job environment = %v", env)
+			}
+			if strings.Contains(w.Body.String(), "admin-token") {
+				t.Errorf("the admin token is part of the explained delivery")
+			}
+		})
+	}
+}
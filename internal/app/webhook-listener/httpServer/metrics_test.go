@@ -0,0 +1,103 @@
+package httpServer
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	batchv1 "k8s.io/api/batch/v1"
+
+	"github.com/sergiotejon/pipeManagerLauncher/internal/app/webhook-listener/databuilder"
+	"github.com/sergiotejon/pipeManagerLauncher/internal/app/webhook-listener/metrics"
+	"github.com/sergiotejon/pipeManagerLauncher/internal/app/webhook-listener/queue"
+	"github.com/sergiotejon/pipeManagerLauncher/pkg/config"
+)
+
+// queueLength returns the exposition of the queue length gauge with the given value
+func queueLength(value int) string {
+	return fmt.Sprintf(`# HELP pipe_manager_webhook_queue_length Jobs waiting in the queue or being processed.
+# TYPE pipe_manager_webhook_queue_length gauge
+pipe_manager_webhook_queue_length %d
+`, value)
+}
+
+func TestProcessedDeliveryMetrics(t *testing.T) {
+	setTestConfig(t, "admin-token")
+	previousLaunchJob, previousQueue := launchJob, jobQueue
+	t.Cleanup(func() { launchJob, jobQueue = previousLaunchJob, previousQueue })
+	launchJob = func(job *batchv1.Job, _ *databuilder.PipelineData) (string, error) {
+		return job.Name, nil
+	}
+
+	var err error
+	jobQueue, err = queue.New(config.QueueConfig{Type: queue.TypeMemory}, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	metrics.RegisterQueueLength(jobQueue.Len)
+
+	deliveries := []struct {
+		name    string
+		ref     string
+		outcome string
+	}{
+		{"launched", "refs/heads/main", metrics.OutcomeLaunched},
+		{"filtered", "refs/heads/skip", metrics.OutcomeFiltered},
+	}
+
+	for i, delivery := range deliveries {
+		counter := metrics.PipelinesTotal.WithLabelValues("github", "push", delivery.outcome)
+		if delivery.outcome == metrics.OutcomeFiltered {
+			// The filtered deliveries are counted before their event is known
+			counter = metrics.PipelinesTotal.WithLabelValues("github", "", delivery.outcome)
+		}
+		before := testutil.ToFloat64(counter)
+
+		body := fmt.Sprintf(`{"ref": %q, "after": "abc123", "repository": {"clone_url": "https://github.com/org/repo.git"}}`, delivery.ref)
+		data, err := json.Marshal(Job{
+			Method:  "POST",
+			Path:    "/github",
+			Headers: map[string][]string{"X-Github-Event": {"push"}},
+			Body:    json.RawMessage(body),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		item := queue.Item{ID: fmt.Sprintf("metrics-%d", i), Data: data}
+		resultChan := make(chan JobResult, 1)
+		resultChans.Store(item.ID, resultChan)
+		if err = jobQueue.Push(item); err != nil {
+			t.Fatal(err)
+		}
+		if err = testutil.GatherAndCompare(prometheus.DefaultGatherer, strings.NewReader(queueLength(1)), "pipe_manager_webhook_queue_length"); err != nil {
+			t.Errorf("%s: queued: %v", delivery.name, err)
+		}
+
+		done := make(chan struct{})
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go worker(&wg, i, done)
+		select {
+		case <-resultChan:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%s: delivery not processed", delivery.name)
+		}
+		close(done)
+		wg.Wait()
+
+		if got := testutil.ToFloat64(counter) - before; got != 1 {
+			t.Errorf("%s: pipelines_total{route=github,outcome=%s} increased by %v, want 1", delivery.name, delivery.outcome, got)
+		}
+		if err = testutil.GatherAndCompare(prometheus.DefaultGatherer, strings.NewReader(queueLength(0)), "pipe_manager_webhook_queue_length"); err != nil {
+			t.Errorf("%s: processed: %v", delivery.name, err)
+		}
+		if got := testutil.ToFloat64(metrics.BusyWorkers); got != 0 {
+			t.Errorf("%s: busy workers = %v, want 0", delivery.name, got)
+		}
+	}
+}
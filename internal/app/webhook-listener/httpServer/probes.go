@@ -0,0 +1,150 @@
+package httpServer
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/sergiotejon/pipeManagerLauncher/internal/pkg/k8s"
+	"github.com/sergiotejon/pipeManagerLauncher/internal/pkg/launcherjob"
+	"github.com/sergiotejon/pipeManagerLauncher/internal/pkg/logging"
+	"github.com/sergiotejon/pipeManagerLauncher/pkg/config"
+)
+
+// readinessTimeout is the maximum time spent by the readiness checks in the Kubernetes API
+const readinessTimeout = 5 * time.Second
+
+// getKubernetesClient returns the client of the Kubernetes API used by the readiness checks
+// It is a variable, so the tests can replace it with a fake client
+var getKubernetesClient = func() (kubernetes.Interface, error) { return k8s.GetKubernetesClient() }
+
+// readinessCheck is a check of the readiness probe
+// It returns an error if the server is not ready to process the requests. The checks hold the configuration lock only
+// while reading it, so a reload is not blocked by the Kubernetes API calls
+type readinessCheck struct {
+	name  string
+	check func(ctx context.Context) error
+}
+
+// readinessChecks are the checks run by the readiness probe, in order
+var readinessChecks = []readinessCheck{
+	{name: "kubernetes", check: checkKubernetesAPI},
+	{name: "jobPermission", check: checkJobPermission},
+	{name: "configmap", check: checkLauncherConfigMap},
+	{name: "queue", check: checkQueue},
+}
+
+// livezHandler answers OK while the process is running
+func livezHandler(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte("OK"))
+}
+
+// readyzHandler runs the readiness checks and answers 200 if all of them pass, or 503 otherwise
+// The body contains a line for each check with its result, e.g., "[+]kubernetes ok" or "[-]queue failed: ..."
+func readyzHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+	defer cancel()
+
+	ready := true
+	var body strings.Builder
+	for _, c := range readinessChecks {
+		if err := c.check(ctx); err != nil {
+			ready = false
+			logging.Logger.Warn("Readiness check failed", "check", c.name, "error", fmt.Sprintf("%v", err))
+			body.WriteString(fmt.Sprintf("[-]%s failed: %v\n", c.name, err))
+			continue
+		}
+		body.WriteString(fmt.Sprintf("[+]%s ok\n", c.name))
+	}
+
+	if ready {
+		w.WriteHeader(http.StatusOK)
+	} else {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	_, _ = w.Write([]byte(body.String()))
+}
+
+// checkKubernetesAPI checks the API server is reachable
+func checkKubernetesAPI(ctx context.Context) error {
+	client, err := getKubernetesClient()
+	if err != nil {
+		return err
+	}
+
+	_, err = client.Discovery().RESTClient().Get().AbsPath("/version").Do(ctx).Raw()
+	return err
+}
+
+// checkJobPermission checks the service account is allowed to create jobs in the namespace of the launcher
+func checkJobPermission(ctx context.Context) error {
+	client, err := getKubernetesClient()
+	if err != nil {
+		return err
+	}
+
+	config.RLock()
+	namespace := launcherjob.GetNamespace()
+	config.RUnlock()
+
+	review := &authorizationv1.SelfSubjectAccessReview{
+		Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Namespace: namespace,
+				Verb:      "create",
+				Group:     "batch",
+				Resource:  "jobs",
+			},
+		},
+	}
+	result, err := client.AuthorizationV1().SelfSubjectAccessReviews().Create(ctx, review, metav1.CreateOptions{})
+	if err != nil {
+		return err
+	}
+	if !result.Status.Allowed {
+		return fmt.Errorf("not allowed to create jobs in namespace '%s': %s", namespace, result.Status.Reason)
+	}
+
+	return nil
+}
+
+// checkLauncherConfigMap checks the configmap mounted by the launcher jobs exists in their namespace
+func checkLauncherConfigMap(ctx context.Context) error {
+	config.RLock()
+	configmapName := config.Launcher.Data.ConfigmapName
+	namespace := launcherjob.GetNamespace()
+	config.RUnlock()
+	if configmapName == "" {
+		return nil
+	}
+
+	client, err := getKubernetesClient()
+	if err != nil {
+		return err
+	}
+
+	_, err = client.CoreV1().ConfigMaps(namespace).Get(ctx, configmapName, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("configmap '%s' in namespace '%s': %w", configmapName, namespace, err)
+	}
+
+	return nil
+}
+
+// checkQueue checks the job queue is not full, so new requests can be accepted without blocking
+func checkQueue(context.Context) error {
+	if jobQueue == nil {
+		return fmt.Errorf("job queue not created")
+	}
+	if capacity := jobQueue.Cap(); capacity > 0 && jobQueue.Len() >= capacity {
+		return fmt.Errorf("job queue full (%d jobs)", capacity)
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package httpServer
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/sergiotejon/pipeManagerLauncher/internal/app/webhook-listener/queue"
+	"github.com/sergiotejon/pipeManagerLauncher/pkg/config"
+)
+
+// fakeQueue is a queue with the given number of items and capacity
+type fakeQueue struct {
+	length   int
+	capacity int
+}
+
+func (q *fakeQueue) Push(queue.Item) error                  { return nil }
+func (q *fakeQueue) Pop(<-chan struct{}) (queue.Item, bool) { return queue.Item{}, false }
+func (q *fakeQueue) Ack(string) error                       { return nil }
+func (q *fakeQueue) Len() int                               { return q.length }
+func (q *fakeQueue) Cap() int                               { return q.capacity }
+func (q *fakeQueue) Close() error                           { return nil }
+
+func TestReadyzHandler(t *testing.T) {
+	const namespace = "pipe-manager"
+	configmap := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "launcher-config", Namespace: namespace}}
+
+	tests := []struct {
+		name       string
+		allowed    bool
+		objects    []runtime.Object
+		queue      queue.Queue
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "ready",
+			allowed:    true,
+			objects:    []runtime.Object{configmap},
+			queue:      &fakeQueue{length: 1, capacity: 4},
+			wantStatus: http.StatusOK,
+			wantBody:   "[+]kubernetes ok\n[+]jobPermission ok\n[+]configmap ok\n[+]queue ok\n",
+		},
+		{
+			name:       "jobs not allowed",
+			allowed:    false,
+			objects:    []runtime.Object{configmap},
+			queue:      &fakeQueue{length: 1, capacity: 4},
+			wantStatus: http.StatusServiceUnavailable,
+			wantBody:   "[-]jobPermission failed: not allowed to create jobs in namespace 'pipe-manager': denied by RBAC\n",
+		},
+		{
+			name:       "configmap missing",
+			allowed:    true,
+			queue:      &fakeQueue{length: 1, capacity: 4},
+			wantStatus: http.StatusServiceUnavailable,
+			wantBody:   "[-]configmap failed: configmap 'launcher-config' in namespace 'pipe-manager'",
+		},
+		{
+			name:       "queue full",
+			allowed:    true,
+			objects:    []runtime.Object{configmap},
+			queue:      &fakeQueue{length: 4, capacity: 4},
+			wantStatus: http.StatusServiceUnavailable,
+			wantBody:   "[-]queue failed: job queue full (4 jobs)\n",
+		},
+		{
+			name:       "unbounded queue",
+			allowed:    true,
+			objects:    []runtime.Object{configmap},
+			queue:      &fakeQueue{length: 100},
+			wantStatus: http.StatusOK,
+			wantBody:   "[+]queue ok\n",
+		},
+	}
+
+	previousClient, previousQueue, previousLauncher := getKubernetesClient, jobQueue, config.Launcher
+	previousCheck := readinessChecks[0].check
+	t.Cleanup(func() {
+		getKubernetesClient, jobQueue, config.Launcher = previousClient, previousQueue, previousLauncher
+		readinessChecks[0].check = previousCheck
+	})
+	config.Launcher.Data.Namespace = namespace
+	config.Launcher.Data.ConfigmapName = configmap.Name
+	// The fake clientset has no REST client to request the version of the API server
+	readinessChecks[0].check = func(context.Context) error { return nil }
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := fake.NewSimpleClientset(tt.objects...)
+			client.PrependReactor("create", "selfsubjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				review := action.(k8stesting.CreateAction).GetObject().(*authorizationv1.SelfSubjectAccessReview)
+				attributes := review.Spec.ResourceAttributes
+				allowed := tt.allowed && attributes.Namespace == namespace && attributes.Verb == "create" &&
+					attributes.Group == "batch" && attributes.Resource == "jobs"
+				review.Status = authorizationv1.SubjectAccessReviewStatus{Allowed: allowed}
+				if !allowed {
+					review.Status.Reason = "denied by RBAC"
+				}
+				return true, review, nil
+			})
+			getKubernetesClient = func() (kubernetes.Interface, error) { return client, nil }
+			jobQueue = tt.queue
+
+			w := httptest.NewRecorder()
+			readyzHandler(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if !strings.Contains(w.Body.String(), tt.wantBody) {
+				t.Errorf("body =\n%s\nwant\n%s", w.Body.String(), tt.wantBody)
+			}
+		})
+	}
+}
@@ -2,17 +2,36 @@ package httpServer
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+
+	batchv1 "k8s.io/api/batch/v1"
 
 	"github.com/sergiotejon/pipeManagerLauncher/internal/app/webhook-listener/databuilder"
+	"github.com/sergiotejon/pipeManagerLauncher/internal/app/webhook-listener/metrics"
 	"github.com/sergiotejon/pipeManagerLauncher/internal/app/webhook-listener/pipeline"
+	"github.com/sergiotejon/pipeManagerLauncher/internal/app/webhook-listener/tracker"
 	"github.com/sergiotejon/pipeManagerLauncher/internal/pkg/logging"
 	"github.com/sergiotejon/pipeManagerLauncher/pkg/config"
 )
 
+// LaunchedJob is a Kubernetes Job launched for a request
+// It contains the name of the job and the event and repository of its pipeline data
+type LaunchedJob struct {
+	Name       string
+	Event      string
+	Repository string
+}
+
+// launchJob creates the Kubernetes Job of a pipeline in the cluster
+// It is a variable, so the tests can process the requests without a cluster
+var launchJob = pipeline.LaunchJob
+
 // processJob is the function that processes the incoming HTTP request
-// It creates a PipelineData object and launches a job
-// It returns an error if the job fails to launch
-func processJob(job Job) error {
+// It creates the PipelineData objects and launches a job for each one. The caller must not hold the configuration lock
+// It returns the launched jobs, and an error if any job fails to launch. The remaining jobs are launched anyway, and
+// the jobs already launched by a previous processing of the same request are returned as launched
+func processJob(job Job) ([]LaunchedJob, error) {
 	var err error
 
 	logging.Logger.Info("Request received", "method", job.Method, "path", job.Path)
@@ -21,21 +40,51 @@ func processJob(job Job) error {
 	var jsonData []byte
 	jsonData, err = json.MarshalIndent(job, "", "  ")
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	var pipelineData *databuilder.PipelineData
-	pipelineData, err = databuilder.Run(jsonData, job.Path, config.Webhook.Data.Routes)
+	// The configuration is only read while the jobs are built, so a reload waiting for the lock is not blocked by
+	// the Kubernetes API calls that launch them
+	config.RLock()
+	pipelines, err := databuilder.Run(jsonData, job.Path, config.Webhook.Data.Routes)
+	routeName := getRouteName(job.Path)
+	launcherJobs := make([]*batchv1.Job, len(pipelines))
+	buildErrs := make([]error, len(pipelines))
+	for i, pipelineData := range pipelines {
+		launcherJobs[i], buildErrs[i] = pipeline.BuildJob(job.RequestID, i, pipelineData)
+	}
+	config.RUnlock()
+
+	var filtered *databuilder.FilteredError
+	if err != nil && !errors.As(err, &filtered) {
+		metrics.PipelinesTotal.WithLabelValues(routeName, "", metrics.OutcomeError).Inc()
+	}
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	logging.Logger.Debug("Pipeline", "data", pipelineData)
+	var launchedJobs []LaunchedJob
+	var errs []error
+	for i, pipelineData := range pipelines {
+		logging.Logger.Debug("Pipeline", "data", pipelineData)
 
-	err = pipeline.LaunchJob(job.RequestID, pipelineData)
-	if err != nil {
-		return err
+		jobName, err := "", buildErrs[i]
+		if err == nil {
+			jobName, err = launchJob(launcherJobs[i], pipelineData)
+		}
+		if err != nil {
+			metrics.PipelinesTotal.WithLabelValues(pipelineData.Name, pipelineData.Event, metrics.OutcomeFailed).Inc()
+			errs = append(errs, fmt.Errorf("event '%s' of repository '%s': %w", pipelineData.Event, pipelineData.Repository, err))
+			continue
+		}
+		metrics.PipelinesTotal.WithLabelValues(pipelineData.Name, pipelineData.Event, metrics.OutcomeLaunched).Inc()
+		tracker.AddJob(job.RequestID, jobName, pipelineData.Name, pipelineData.Event)
+		launchedJobs = append(launchedJobs, LaunchedJob{
+			Name:       jobName,
+			Event:      pipelineData.Event,
+			Repository: pipelineData.Repository,
+		})
 	}
 
-	return nil
+	return launchedJobs, errors.Join(errs...)
 }
@@ -0,0 +1,121 @@
+package httpServer
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/sergiotejon/pipeManagerLauncher/internal/pkg/logging"
+	"github.com/sergiotejon/pipeManagerLauncher/internal/pkg/secrets"
+	"github.com/sergiotejon/pipeManagerLauncher/pkg/config"
+)
+
+// reloadDelay is the time to wait after a change of the configuration file before reloading it, so the several events
+// of a single update (e.g., the symlinks swapped by Kubernetes when a ConfigMap changes) trigger only one reload
+const reloadDelay = time.Second
+
+// watchConfig calls the reload function (reloadConfig) when the configuration file changes or a SIGHUP signal is
+// received. The directory of the file is watched instead of the file, because the files of a mounted ConfigMap are
+// replaced instead of modified. It stops when the done channel is closed
+func watchConfig(configFile string, done <-chan struct{}, reload func(configFile string)) {
+	sighup := make(chan os.Signal, 1)
+	signal.Notify(sighup, syscall.SIGHUP)
+	defer signal.Stop(sighup)
+
+	var events <-chan fsnotify.Event
+	var errs <-chan error
+	watcher, err := fsnotify.NewWatcher()
+	if err == nil {
+		err = watcher.Add(filepath.Dir(configFile))
+	}
+	if err != nil {
+		logging.Logger.Warn("Error watching the configuration file, only SIGHUP reloads it", "configFile", configFile, "error", fmt.Sprintf("%v", err))
+	} else {
+		defer func() {
+			_ = watcher.Close()
+		}()
+		events = watcher.Events
+		errs = watcher.Errors
+	}
+
+	var timer <-chan time.Time
+	for {
+		select {
+		case <-done:
+			return
+		case <-sighup:
+			logging.Logger.Info("Received SIGHUP, reloading configuration", "configFile", configFile)
+			reload(configFile)
+		case event := <-events:
+			if !isConfigEvent(configFile, event) {
+				continue
+			}
+			timer = time.After(reloadDelay)
+		case <-timer:
+			timer = nil
+			logging.Logger.Info("Configuration file changed, reloading configuration", "configFile", configFile)
+			reload(configFile)
+		case err := <-errs:
+			logging.Logger.Warn("Error watching the configuration file", "configFile", configFile, "error", fmt.Sprintf("%v", err))
+		}
+	}
+}
+
+// isConfigEvent returns true if the event changes the configuration file
+// The "..data" symlink is swapped by Kubernetes when a mounted ConfigMap changes
+func isConfigEvent(configFile string, event fsnotify.Event) bool {
+	if event.Op == fsnotify.Chmod {
+		return false
+	}
+	name := filepath.Base(event.Name)
+	return name == filepath.Base(configFile) || name == "..data"
+}
+
+// reloadConfig reloads the configuration and logs the routes added, removed and changed
+// The current configuration is kept if the new one is not valid
+func reloadConfig(configFile string) {
+	diff, err := config.ReloadConfig(configFile)
+	if err != nil {
+		logging.Logger.Error("Invalid configuration, keeping the current one", "configFile", configFile, "error", fmt.Sprintf("%v", err))
+		return
+	}
+
+	// The secrets of the new routes are read now, instead of on the first request
+	secrets.Invalidate()
+	loadSecrets()
+
+	logging.Logger.Info("Configuration reloaded",
+		"addedRoutes", diff.Added,
+		"removedRoutes", diff.Removed,
+		"changedRoutes", diff.Changed)
+	if len(diff.Ignored) > 0 {
+		logging.Logger.Warn("Settings changed that require a restart to be applied", "settings", diff.Ignored)
+	}
+}
+
+// loadSecrets reads the secrets of the route signatures and the admin token into the cache, so the first requests do
+// not wait for them and the missing secrets are reported when the configuration is loaded
+func loadSecrets() {
+	sources := map[string]config.SecretSource{}
+	config.RLock()
+	for _, route := range config.Webhook.Data.Routes {
+		if route.Signature != nil {
+			sources["route "+route.Name] = route.Signature.Secret
+		}
+	}
+	if config.Webhook.Data.Admin.Token != nil {
+		sources["admin token"] = *config.Webhook.Data.Admin.Token
+	}
+	config.RUnlock()
+
+	for name, source := range sources {
+		if _, err := secrets.ReadCached(source); err != nil {
+			logging.Logger.Warn("Error reading secret", "secret", name, "error", fmt.Sprintf("%v", err))
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package httpServer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/sergiotejon/pipeManagerLauncher/internal/pkg/logging"
+)
+
+func TestMain(m *testing.M) {
+	if err := logging.SetupLogger("error", "text", "stderr"); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+func TestIsConfigEvent(t *testing.T) {
+	const configFile = "/etc/pipe-manager/config.yaml"
+
+	tests := []struct {
+		name  string
+		event fsnotify.Event
+		want  bool
+	}{
+		{"file written", fsnotify.Event{Name: "/etc/pipe-manager/config.yaml", Op: fsnotify.Write}, true},
+		{"file created", fsnotify.Event{Name: "/etc/pipe-manager/config.yaml", Op: fsnotify.Create}, true},
+		{"file renamed", fsnotify.Event{Name: "/etc/pipe-manager/config.yaml", Op: fsnotify.Rename}, true},
+		{"configmap updated", fsnotify.Event{Name: "/etc/pipe-manager/..data", Op: fsnotify.Create}, true},
+		{"permissions changed", fsnotify.Event{Name: "/etc/pipe-manager/config.yaml", Op: fsnotify.Chmod}, false},
+		{"configmap data directory", fsnotify.Event{Name: "/etc/pipe-manager/..2024_01_01_00_00_00.000000000", Op: fsnotify.Create}, false},
+		{"other file", fsnotify.Event{Name: "/etc/pipe-manager/other.yaml", Op: fsnotify.Write}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isConfigEvent(configFile, tt.event); got != tt.want {
+				t.Errorf("isConfigEvent(%s) = %v, want %v", tt.event, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWatchConfig(t *testing.T) {
+	dir := t.TempDir()
+	configFile := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(configFile, []byte("webhook: {}\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	reloads := make(chan string, 10)
+	done := make(chan struct{})
+	defer close(done)
+	go watchConfig(configFile, done, func(configFile string) { reloads <- configFile })
+	// Give the watcher time to watch the directory
+	time.Sleep(100 * time.Millisecond)
+
+	// expectReloads waits for the reloads after the delay and checks there are no more
+	expectReloads := func(name string, want int) {
+		t.Helper()
+		timeout := time.After(reloadDelay + time.Second)
+		for got := 0; ; {
+			select {
+			case file := <-reloads:
+				got++
+				if file != configFile {
+					t.Errorf("%s: reloaded %s, want %s", name, file, configFile)
+				}
+				if got > want {
+					t.Errorf("%s: %d reloads, want %d", name, got, want)
+					return
+				}
+			case <-timeout:
+				if got != want {
+					t.Errorf("%s: %d reloads, want %d", name, got, want)
+				}
+				return
+			}
+		}
+	}
+
+	// Several writes of a single update reload the configuration once
+	for i := 0; i < 3; i++ {
+		if err := os.WriteFile(configFile, []byte("webhook: {}\n"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	expectReloads("several writes", 1)
+
+	// The other files of the directory do not reload it
+	if err := os.WriteFile(filepath.Join(dir, "other.yaml"), []byte("{}\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	expectReloads("other file", 0)
+
+	// Kubernetes updates a mounted ConfigMap swapping the ..data symlink
+	dataDir := filepath.Join(dir, "..2024_01_01_00_00_00.000000000")
+	if err := os.Mkdir(dataDir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(dataDir, filepath.Join(dir, "..data_tmp")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(filepath.Join(dir, "..data_tmp"), filepath.Join(dir, "..data")); err != nil {
+		t.Fatal(err)
+	}
+	expectReloads("configmap update", 1)
+}
@@ -0,0 +1,27 @@
+package httpServer
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/sergiotejon/pipeManagerLauncher/internal/app/webhook-listener/tracker"
+	"github.com/sergiotejon/pipeManagerLauncher/internal/pkg/logging"
+)
+
+// requestStatusHandler answers the status of the request with the ID of the path, and of the jobs launched for it
+// It allows the upstream tools to poll the outcome of a delivery with the request ID returned by the webhook
+func requestStatusHandler(w http.ResponseWriter, r *http.Request) {
+	status, ok := tracker.Get(r.PathValue("id"))
+	if !ok {
+		http.Error(w, "Request not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(status); err != nil {
+		logging.Logger.Error("Error writing response", "error", fmt.Sprintf("%v", err))
+	}
+}
@@ -0,0 +1,61 @@
+package httpServer
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sergiotejon/pipeManagerLauncher/internal/app/webhook-listener/tracker"
+	"github.com/sergiotejon/pipeManagerLauncher/internal/pkg/launcherjob"
+)
+
+func TestRequestStatusHandler(t *testing.T) {
+	tracker.Queued("status-queued", "github")
+	tracker.AddJob("status-launched", "pipeline-launcher-status-launched", "github", "push")
+	tracker.SetOutcome("status-filtered", launcherjob.PhaseFiltered, "no pipeline matches the event")
+
+	tests := []struct {
+		name        string
+		requestID   string
+		wantStatus  int
+		wantPhase   string
+		wantMessage string
+		wantJobs    int
+	}{
+		{"queued", "status-queued", http.StatusOK, launcherjob.PhaseQueued, "", 0},
+		{"launched", "status-launched", http.StatusOK, launcherjob.PhasePending, "", 1},
+		{"filtered", "status-filtered", http.StatusOK, launcherjob.PhaseFiltered, "no pipeline matches the event", 0},
+		{"unknown", "status-unknown", http.StatusNotFound, "", "", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/requests/"+tt.requestID, nil)
+			r.SetPathValue("id", tt.requestID)
+			w := httptest.NewRecorder()
+			requestStatusHandler(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			if contentType := w.Header().Get("Content-Type"); contentType != "application/json" {
+				t.Errorf("Content-Type = %s, want application/json", contentType)
+			}
+			var status tracker.RequestStatus
+			if err := json.Unmarshal(w.Body.Bytes(), &status); err != nil {
+				t.Fatalf("invalid response %s: %v", w.Body.String(), err)
+			}
+			if status.RequestID != tt.requestID || status.Phase != tt.wantPhase || status.Message != tt.wantMessage {
+				t.Errorf("got (%s, %s, %s), want (%s, %s, %s)", status.RequestID, status.Phase, status.Message,
+					tt.requestID, tt.wantPhase, tt.wantMessage)
+			}
+			if len(status.Jobs) != tt.wantJobs {
+				t.Errorf("jobs = %d, want %d", len(status.Jobs), tt.wantJobs)
+			}
+		})
+	}
+}
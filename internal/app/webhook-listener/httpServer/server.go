@@ -9,18 +9,24 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
 
-	"github.com/google/uuid"
-
+	"github.com/sergiotejon/pipeManagerLauncher/internal/app/webhook-listener/databuilder"
+	"github.com/sergiotejon/pipeManagerLauncher/internal/app/webhook-listener/janitor"
+	"github.com/sergiotejon/pipeManagerLauncher/internal/app/webhook-listener/metrics"
+	"github.com/sergiotejon/pipeManagerLauncher/internal/app/webhook-listener/pipeline"
+	"github.com/sergiotejon/pipeManagerLauncher/internal/app/webhook-listener/queue"
+	"github.com/sergiotejon/pipeManagerLauncher/internal/app/webhook-listener/tracker"
+	"github.com/sergiotejon/pipeManagerLauncher/internal/pkg/launcherjob"
 	"github.com/sergiotejon/pipeManagerLauncher/internal/pkg/logging"
 	"github.com/sergiotejon/pipeManagerLauncher/pkg/config"
 )
 
 // Job represents an HTTP request to be processed
-// It contains the request URL, method, path, arguments, headers, body, and a result channel
+// It contains the request URL, method, path, arguments, headers, body, and the request ID
 type Job struct {
 	RequestURL string              `json:"requestURL"`
 	Method     string              `json:"method"`
@@ -28,7 +34,6 @@ type Job struct {
 	Args       map[string][]string `json:"args"`
 	Headers    map[string][]string `json:"headers"`
 	Body       json.RawMessage     `json:"body"`
-	ResultChan chan JobResult      `json:"-"`
 	RequestID  string              `json:"-"`
 }
 
@@ -39,26 +44,59 @@ type JobResult struct {
 	Message    string
 }
 
-// jobQueue is a channel for incoming jobs
-var jobQueue chan Job
+// jobQueue is the queue for incoming jobs
+var jobQueue queue.Queue
+
+// resultChans contains the channels of the requests waiting for the result of their job, indexed by request ID.
+// Only used when the server is not in async mode
+var resultChans sync.Map
 
 // HttpServer starts the HTTP server to listen for incoming webhook requests
 // It processes the requests and sends them to the worker pool
-// It also captures termination signals to stop the server and workers
+// It also captures termination signals to stop the server and workers, and reloads the configuration when the
+// configuration file changes or a SIGHUP signal is received
 // It returns an error if the server fails to start
-func HttpServer(listenAddr string) error {
+func HttpServer(listenAddr, configFile string) error {
 	// Setup
 	maxWorkers := config.Webhook.Data.Workers
-	jobQueue = make(chan Job, maxWorkers)
+	var err error
+	jobQueue, err = queue.New(config.Webhook.Data.Queue, maxWorkers)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := jobQueue.Close(); err != nil {
+			logging.Logger.Error("Error closing the job queue", "error", fmt.Sprintf("%v", err))
+		}
+	}()
+	metrics.RegisterQueueLength(jobQueue.Len)
+	metrics.QueueCapacity.Set(float64(jobQueue.Cap()))
+	metrics.Workers.Set(float64(maxWorkers))
 
 	// Register routes
 	routes()
 
+	// Read the secrets of the routes before accepting requests
+	loadSecrets()
+
 	// Setup termination signals
 	done := make(chan struct{})
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGTERM, syscall.SIGINT)
 
+	// Reload the configuration when it changes
+	go watchConfig(configFile, done, reloadConfig)
+
+	// Track the lifecycle of the launched jobs. The namespace is not reloaded, changing it requires a restart
+	// The jobs queued by a concurrency policy are resumed when the previous job with the same key finishes
+	tracker.OnJobFinished(pipeline.ResumeQueued)
+	if err := tracker.Start(launcherjob.GetNamespace(), done); err != nil {
+		logging.Logger.Warn("Error starting the job tracker, the status of the jobs will not be updated", "error", fmt.Sprintf("%v", err))
+	}
+
+	// Delete the finished launcher jobs according to their retention
+	go janitor.Run(done)
+
 	// Start the worker pool
 	var wg sync.WaitGroup
 	for i := 0; i < maxWorkers; i++ {
@@ -99,20 +137,27 @@ func HttpServer(listenAddr string) error {
 // TODO:
 //   - Add route `ping` for github webhook (with event ping)
 func routes() {
-	// Health check endpoint
-	http.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusOK)
-		_, _ = w.Write([]byte("OK"))
-	})
-
-	// Each allowed route from config file
-	for _, route := range config.Webhook.Data.Routes {
-		http.HandleFunc(fmt.Sprintf("POST %s", route.Path), webhookHandler)
+	// Health check endpoints. /healthz is kept for compatibility with the liveness probe
+	http.HandleFunc("/healthz", livezHandler)
+	http.HandleFunc("/livez", livezHandler)
+	http.HandleFunc("/readyz", readyzHandler)
+
+	// Prometheus metrics endpoint
+	http.Handle("/metrics", metrics.Handler())
+
+	// Administration endpoints, only available if an admin token is configured
+	// The status of the requests shows the routes, events and failures of the deliveries, so it is one of them
+	if config.Webhook.Data.Admin.Token != nil {
+		http.HandleFunc("POST /_explain/", adminHandler(explainHandler))
+		http.HandleFunc("GET /requests/{id}", adminHandler(requestStatusHandler))
 	}
+
+	// Configured routes. They are looked up for each request, so they can be reloaded
+	http.HandleFunc("/", webhookHandler)
 }
 
 // worker is a function that processes HTTP requests
-// It reads jobs from the jobQueue channel and processes them
+// It reads jobs from the jobQueue and processes them, sending the result to the request waiting for it, if any
 // It stops when the done channel is closed
 func worker(wg *sync.WaitGroup, id int, done <-chan struct{}) {
 	defer wg.Done()
@@ -121,28 +166,88 @@ func worker(wg *sync.WaitGroup, id int, done <-chan struct{}) {
 	logging.Logger.Info("Worker started")
 
 	for {
-		select {
-		case <-done:
+		item, ok := jobQueue.Pop(done)
+		if !ok {
 			logging.Logger.Info("Worker stopping")
 			return
-		case job := <-jobQueue:
-			requestID := uuid.New().String()
-			logging.AddAttribute("requestID", requestID)
-			job.RequestID = requestID
-			err := processJob(job)
-			if err != nil {
-				logging.Logger.Error("Error processing job", "error", fmt.Sprintf("%v", err))
-				job.ResultChan <- JobResult{
-					StatusCode: http.StatusInternalServerError,
-					Message:    fmt.Sprintf("Error processing job: %v\n", err),
-				}
-			} else {
-				job.ResultChan <- JobResult{
-					StatusCode: http.StatusOK,
-					Message:    "Job processed successfully\n",
-				}
-			}
-			logging.RemoveAttribute("requestID")
 		}
+
+		logging.AddAttribute("requestID", item.ID)
+		metrics.BusyWorkers.Inc()
+		result := runJob(item)
+		metrics.BusyWorkers.Dec()
+		if err := jobQueue.Ack(item.ID); err != nil {
+			logging.Logger.Error("Error removing job from the queue", "error", fmt.Sprintf("%v", err))
+		}
+		if resultChan, ok := resultChans.LoadAndDelete(item.ID); ok {
+			resultChan.(chan JobResult) <- result
+		}
+		logging.RemoveAttribute("requestID")
+	}
+}
+
+// runJob decodes the queued job and processes it
+// It returns the result to be sent to the client, including the jobs launched
+func runJob(item queue.Item) JobResult {
+	var job Job
+	var launchedJobs []LaunchedJob
+	err := json.Unmarshal(item.Data, &job)
+	if err == nil {
+		job.RequestID = item.ID
+		start := time.Now()
+		launchedJobs, err = processJob(job)
+		config.RLock()
+		routeName := getRouteName(job.Path)
+		config.RUnlock()
+		metrics.ProcessJobDuration.WithLabelValues(routeName).Observe(time.Since(start).Seconds())
+	}
+
+	var filtered *databuilder.FilteredError
+	if errors.As(err, &filtered) {
+		logging.Logger.Info("Delivery filtered", "route", filtered.Route, "event", filtered.Event, "condition", filtered.Condition)
+		metrics.PipelinesTotal.WithLabelValues(filtered.Route, filtered.Event, metrics.OutcomeFiltered).Inc()
+		tracker.SetOutcome(item.ID, launcherjob.PhaseFiltered, err.Error())
+		deliveries.complete(item.ID)
+		return JobResult{
+			StatusCode: http.StatusOK,
+			Message:    fmt.Sprintf("Delivery filtered: %v\n", err),
+		}
+	}
+	if err != nil && len(launchedJobs) > 0 {
+		// A retry of the delivery would launch again the jobs already launched, so it is answered as processed
+		logging.Logger.Error("Error launching some jobs of the delivery", "launched", len(launchedJobs),
+			"error", fmt.Sprintf("%v", err))
+		tracker.SetMessage(item.ID, err.Error())
+		deliveries.complete(item.ID)
+		return JobResult{
+			StatusCode: http.StatusOK,
+			Message:    fmt.Sprintf("Job processed with errors: %v\n", err) + describeLaunchedJobs(launchedJobs),
+		}
+	}
+	if err != nil {
+		logging.Logger.Error("Error processing job", "error", fmt.Sprintf("%v", err))
+		// Nothing was launched, let the provider retry the delivery
+		deliveries.forget(item.ID)
+		tracker.SetOutcome(item.ID, launcherjob.PhaseError, err.Error())
+		return JobResult{
+			StatusCode: http.StatusInternalServerError,
+			Message:    fmt.Sprintf("Error processing job: %v\n", err) + describeLaunchedJobs(launchedJobs),
+		}
+	}
+
+	deliveries.complete(item.ID)
+	return JobResult{
+		StatusCode: http.StatusOK,
+		Message:    "Job processed successfully\n" + describeLaunchedJobs(launchedJobs),
+	}
+}
+
+// describeLaunchedJobs returns a line for each launched job to be included in the response
+func describeLaunchedJobs(launchedJobs []LaunchedJob) string {
+	var description strings.Builder
+	for _, launchedJob := range launchedJobs {
+		description.WriteString(fmt.Sprintf("Launched job %s (event: %s, repository: %s)\n",
+			launchedJob.Name, launchedJob.Event, launchedJob.Repository))
 	}
+	return description.String()
 }
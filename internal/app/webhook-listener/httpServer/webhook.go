@@ -2,15 +2,28 @@ package httpServer
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 
+	"github.com/google/uuid"
+
+	"github.com/sergiotejon/pipeManagerLauncher/internal/app/webhook-listener/databuilder"
+	"github.com/sergiotejon/pipeManagerLauncher/internal/app/webhook-listener/metrics"
+	"github.com/sergiotejon/pipeManagerLauncher/internal/app/webhook-listener/queue"
+	"github.com/sergiotejon/pipeManagerLauncher/internal/app/webhook-listener/signature"
+	"github.com/sergiotejon/pipeManagerLauncher/internal/app/webhook-listener/tracker"
+	"github.com/sergiotejon/pipeManagerLauncher/internal/pkg/launcherjob"
 	"github.com/sergiotejon/pipeManagerLauncher/internal/pkg/logging"
+	"github.com/sergiotejon/pipeManagerLauncher/pkg/config"
 )
 
 // webhookHandler is the function that handles incoming webhook requests
 // It reads the request body and headers, creates a job, and sends it to the worker pool
+// In async mode it answers 202 with the request ID as soon as the job is queued, otherwise it waits for the result
+// The route is looked up in the current configuration for each request, so the routes can be reloaded without
+// restarting the server. It answers 404 if the path is not a configured route
 func webhookHandler(w http.ResponseWriter, r *http.Request) {
 	// Defer closing the request body to prevent resource leaks
 	defer func(Body io.ReadCloser) {
@@ -20,54 +33,172 @@ func webhookHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}(r.Body)
 
+	// Get the route and its settings from the current configuration
+	config.RLock()
+	route := getRouteByPath(r.URL.Path)
+	ttl := getDeliveryTTL(config.Webhook.Data.Deduplication.TTL)
+	config.RUnlock()
+	if route == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	routeName := route.Name
+
 	// Read the body as a json.RawMessage
 	bodyBytes, err := io.ReadAll(r.Body)
 	if err != nil {
 		logging.Logger.Error("Error reading request body", "error", fmt.Sprintf("%v", err))
+		metrics.RequestsTotal.WithLabelValues(routeName, metrics.OutcomeError).Inc()
 		http.Error(w, "Error reading request body", http.StatusInternalServerError)
 		return
 	}
+
+	// Verify the signature of the request before accepting it, if the route requires it
+	if route.Signature != nil {
+		err = signature.Verify(route.Signature, r.Header, bodyBytes)
+		if errors.Is(err, signature.ErrMissingSignature) || errors.Is(err, signature.ErrInvalidSignature) {
+			logging.Logger.Warn("Rejected request with invalid signature", "path", r.URL.Path, "route", route.Name, "error", fmt.Sprintf("%v", err))
+			metrics.RequestsTotal.WithLabelValues(routeName, metrics.OutcomeUnauthorized).Inc()
+			http.Error(w, "Invalid signature", http.StatusUnauthorized)
+			return
+		}
+		if err != nil {
+			logging.Logger.Error("Error verifying request signature", "path", r.URL.Path, "route", route.Name, "error", fmt.Sprintf("%v", err))
+			metrics.RequestsTotal.WithLabelValues(routeName, metrics.OutcomeError).Inc()
+			http.Error(w, "Error verifying request signature", http.StatusInternalServerError)
+			return
+		}
+	}
+
 	var jsonCheck interface{}
 	err = json.Unmarshal(bodyBytes, &jsonCheck)
 	if err != nil {
 		logging.Logger.Info("Error validating body as JSON", "error", fmt.Sprintf("%v", err))
+		metrics.RequestsTotal.WithLabelValues(routeName, metrics.OutcomeInvalid).Inc()
 		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
 		return
 	}
 
+	// Create a job
+	requestID := uuid.New().String()
+	job := newJob(r, r.URL.Path, bodyBytes)
+	jobData, err := json.Marshal(job)
+	if err != nil {
+		logging.Logger.Error("Error encoding job", "error", fmt.Sprintf("%v", err))
+		metrics.RequestsTotal.WithLabelValues(routeName, metrics.OutcomeError).Inc()
+		http.Error(w, "Error encoding job", http.StatusInternalServerError)
+		return
+	}
+
+	// Answer the retries of an already accepted delivery with the original request ID
+	deliveryID, err := databuilder.GetDeliveryID(jobData, route)
+	if err != nil {
+		logging.Logger.Warn("Error evaluating delivery ID, de-duplication skipped", "route", route.Name, "error", fmt.Sprintf("%v", err))
+	} else if deliveryID != "" {
+		originalID, status := deliveries.register(route.Name, deliveryID, requestID, ttl)
+		if status != deliveryNew {
+			logging.Logger.Info("Duplicated delivery", "route", route.Name, "deliveryID", deliveryID, "requestID", originalID)
+			metrics.RequestsTotal.WithLabelValues(routeName, metrics.OutcomeDuplicate).Inc()
+			w.Header().Set("X-Request-ID", originalID)
+			if status == deliveryInProgress {
+				// The original delivery can still fail, so the provider must retry later
+				w.Header().Set("Retry-After", deliveryRetryAfter)
+				writeResult(w, JobResult{
+					StatusCode: http.StatusConflict,
+					Message:    fmt.Sprintf("Delivery in progress with request ID %s, retry later\n", originalID),
+				})
+				return
+			}
+			writeResult(w, JobResult{
+				StatusCode: http.StatusOK,
+				Message:    fmt.Sprintf("Delivery already processed with request ID %s\n", originalID),
+			})
+			return
+		}
+	}
+
+	// Register the channel to receive the result before the job can be processed
+	var resultChan chan JobResult
+	if !config.Webhook.Data.Async {
+		resultChan = make(chan JobResult, 1)
+		resultChans.Store(requestID, resultChan)
+	}
+
+	// Send the job to the worker queue. It is tracked before, so the outcome of a worker is not overwritten
+	tracker.Queued(requestID, routeName)
+	err = jobQueue.Push(queue.Item{ID: requestID, Data: jobData})
+	if err != nil {
+		resultChans.Delete(requestID)
+		deliveries.forget(requestID)
+		tracker.SetOutcome(requestID, launcherjob.PhaseError, "Error queuing job")
+		logging.Logger.Error("Error queuing job", "requestID", requestID, "error", fmt.Sprintf("%v", err))
+		metrics.RequestsTotal.WithLabelValues(routeName, metrics.OutcomeError).Inc()
+		http.Error(w, "Error queuing job", http.StatusInternalServerError)
+		return
+	}
+	metrics.RequestsTotal.WithLabelValues(routeName, metrics.OutcomeAccepted).Inc()
+	w.Header().Set("X-Request-ID", requestID)
+
+	// Send a response
+	if config.Webhook.Data.Async {
+		writeResult(w, JobResult{
+			StatusCode: http.StatusAccepted,
+			Message:    fmt.Sprintf("Job accepted with request ID %s\n", requestID),
+		})
+		return
+	}
+	writeResult(w, <-resultChan)
+}
+
+// newJob creates the job of the request for the given route path
+// The headers are copied as a map, so they can be used in the CEL expressions
+func newJob(r *http.Request, path string, body []byte) Job {
 	// Read headers as a map
 	headers := make(map[string][]string)
 	for name, values := range r.Header {
 		headers[name] = values
 	}
 
-	// TODO: Add additional validation here if needed
-	// Optional: Verify the HMAC of the webhook for added security.
-	// token := "my_webhook_secret"
-	// sig := r.Header.Get("X-Hub-Signature-256")
-	// if !verifySignature(body, sig, token) {
-	//     http.Error(w, "Invalid signature", http.StatusUnauthorized)
-	//     return
-	// }
-
-	// Create a job
-	resultChan := make(chan JobResult)
-	job := Job{
+	return Job{
 		RequestURL: r.URL.String(),
 		Method:     r.Method,
-		Path:       r.URL.Path,
+		Path:       path,
 		Args:       r.URL.Query(),
 		Headers:    headers,
-		Body:       json.RawMessage(bodyBytes),
-		ResultChan: resultChan,
+		Body:       json.RawMessage(body),
 	}
-	jobQueue <- job // Send the job to the worker queue
+}
 
-	// Send a response
-	result := <-resultChan
+// writeResult writes the result of a job as the response of the request
+func writeResult(w http.ResponseWriter, result JobResult) {
 	w.WriteHeader(result.StatusCode)
-	_, err = w.Write([]byte(result.Message))
+	_, err := w.Write([]byte(result.Message))
 	if err != nil {
 		logging.Logger.Error("Error writing response", "error", fmt.Sprintf("%v", err))
 	}
 }
+
+// getRouteByPath returns the configured route for the given path or nil if it is not found
+// The caller must hold the read lock of the configuration
+func getRouteByPath(path string) *config.Route {
+	for i := range config.Webhook.Data.Routes {
+		if config.Webhook.Data.Routes[i].Path == path {
+			return &config.Webhook.Data.Routes[i]
+		}
+	}
+	return nil
+}
+
+// getRouteName returns the name of the configured route for the given path, or the path if it is not found
+// It is used to label the metrics of the requests. The caller must hold the read lock of the configuration
+func getRouteName(path string) string {
+	if route := getRouteByPath(path); route != nil {
+		return route.Name
+	}
+	return path
+}
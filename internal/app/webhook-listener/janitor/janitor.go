@@ -0,0 +1,63 @@
+// Package janitor deletes the finished launcher jobs according to the retention of the configuration: the jobs whose
+// TTL expired and the ones exceeding the number of jobs to keep for each route.
+package janitor
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/sergiotejon/pipeManagerLauncher/internal/pkg/k8s"
+	"github.com/sergiotejon/pipeManagerLauncher/internal/pkg/launcherjob"
+	"github.com/sergiotejon/pipeManagerLauncher/internal/pkg/logging"
+	"github.com/sergiotejon/pipeManagerLauncher/pkg/config"
+)
+
+// interval is the time between two runs of the janitor
+const interval = time.Minute
+
+// Run deletes the expired launcher jobs every minute until the done channel is closed
+// The retention is read from the current configuration on each run, so it can be reloaded. Nothing is done if no
+// retention is configured
+func Run(done <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			config.RLock()
+			defaultRetention := config.Launcher.Data.Retention
+			retention := make(map[string]config.Retention)
+			enabled := defaultRetention != config.Retention{}
+			for _, route := range config.Webhook.Data.Routes {
+				retention[route.Name] = launcherjob.GetRetention(route.Name)
+				enabled = enabled || retention[route.Name] != config.Retention{}
+			}
+			namespace := ""
+			if enabled {
+				namespace = launcherjob.GetNamespace()
+			}
+			config.RUnlock()
+			if !enabled {
+				continue
+			}
+
+			client, err := k8s.GetKubernetesClient()
+			if err != nil {
+				logging.Logger.Error("Error cleaning launcher jobs", "error", fmt.Sprintf("%v", err))
+				continue
+			}
+			_, err = launcherjob.Clean(client, namespace, func(route string) config.Retention {
+				if r, ok := retention[route]; ok {
+					return r
+				}
+				return defaultRetention
+			}, false)
+			if err != nil {
+				logging.Logger.Error("Error cleaning launcher jobs", "error", fmt.Sprintf("%v", err))
+			}
+		}
+	}
+}
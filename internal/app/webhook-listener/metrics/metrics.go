@@ -0,0 +1,122 @@
+// Package metrics contains the Prometheus metrics of the webhook listener.
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "pipe_manager_webhook" // namespace is the prefix of every metric
+
+// Outcomes of the requests received by the webhook routes
+const (
+	OutcomeAccepted     = "accepted"     // OutcomeAccepted is a request queued to be processed
+	OutcomeDuplicate    = "duplicate"    // OutcomeDuplicate is a retry of a delivery already accepted
+	OutcomeUnauthorized = "unauthorized" // OutcomeUnauthorized is a request with a missing or invalid signature
+	OutcomeInvalid      = "invalid"      // OutcomeInvalid is a request with an invalid body
+	OutcomeError        = "error"        // OutcomeError is a request that could not be queued or processed
+	OutcomeFiltered     = "filtered"     // OutcomeFiltered is a delivery that does not meet the 'when' conditions
+	OutcomeLaunched     = "launched"     // OutcomeLaunched is a pipeline whose job was created
+	OutcomeFailed       = "failed"       // OutcomeFailed is a pipeline whose job could not be created
+)
+
+// Actions of the concurrency policies over the launcher jobs
+const (
+	ConcurrencyQueued    = "queued"    // ConcurrencyQueued is a job created suspended until the running ones finish
+	ConcurrencyResumed   = "resumed"   // ConcurrencyResumed is a queued job started after the running ones finished
+	ConcurrencyCancelled = "cancelled" // ConcurrencyCancelled is a running job deleted by a newer one with the same key
+)
+
+var (
+	// RequestsTotal counts the requests received by each route, by outcome
+	RequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "requests_total",
+		Help:      "Requests received by the webhook routes.",
+	}, []string{"route", "outcome"})
+
+	// PipelinesTotal counts the processed deliveries by route, event and outcome
+	PipelinesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "pipelines_total",
+		Help:      "Pipelines built from the deliveries, by route, event and outcome.",
+	}, []string{"route", "event", "outcome"})
+
+	// CELErrorsTotal counts the CEL expressions that failed to evaluate, by kind of expression
+	CELErrorsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cel_errors_total",
+		Help:      "CEL expressions that failed to evaluate, by kind (expression, condition, variable).",
+	}, []string{"kind"})
+
+	// ProcessJobDuration observes the time spent processing a job, from the data builder to the creation of the jobs
+	ProcessJobDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "process_job_duration_seconds",
+		Help:      "Time spent processing a queued job, by route.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route"})
+
+	// JobCreationDuration observes the latency of the Kubernetes API creating the launcher jobs
+	JobCreationDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "job_creation_duration_seconds",
+		Help:      "Latency of the Kubernetes API creating a launcher job.",
+		Buckets:   prometheus.DefBuckets,
+	})
+
+	// KubernetesAPIErrorsTotal counts the errors returned by the Kubernetes API, by operation and reason
+	KubernetesAPIErrorsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "kubernetes_api_errors_total",
+		Help:      "Errors returned by the Kubernetes API, by operation and reason.",
+	}, []string{"operation", "reason"})
+
+	// ConcurrencyTotal counts the actions of the concurrency policies over the launcher jobs, by route and action
+	ConcurrencyTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "concurrency_total",
+		Help:      "Launcher jobs queued, resumed or cancelled by the concurrency policies, by route and action.",
+	}, []string{"route", "action"})
+
+	// BusyWorkers is the number of workers processing a job
+	BusyWorkers = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "busy_workers",
+		Help:      "Workers processing a job.",
+	})
+
+	// Workers is the number of workers started
+	Workers = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "workers",
+		Help:      "Workers started to process the queued jobs.",
+	})
+
+	// QueueCapacity is the number of jobs the queue can hold
+	QueueCapacity = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "queue_capacity",
+		Help:      "Jobs the queue can hold.",
+	})
+)
+
+// RegisterQueueLength registers the gauge with the number of jobs waiting in the queue or being processed
+// The length function is called each time the metrics are collected
+func RegisterQueueLength(length func() int) {
+	prometheus.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "queue_length",
+		Help:      "Jobs waiting in the queue or being processed.",
+	}, func() float64 {
+		return float64(length())
+	}))
+}
+
+// Handler returns the HTTP handler that exposes the metrics
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
@@ -0,0 +1,203 @@
+package pipeline
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sync"
+
+	batchv1 "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	batchv1listers "k8s.io/client-go/listers/batch/v1"
+
+	"github.com/sergiotejon/pipeManagerLauncher/internal/app/webhook-listener/databuilder"
+	"github.com/sergiotejon/pipeManagerLauncher/internal/app/webhook-listener/metrics"
+	"github.com/sergiotejon/pipeManagerLauncher/internal/pkg/k8s"
+	"github.com/sergiotejon/pipeManagerLauncher/internal/pkg/launcherjob"
+	"github.com/sergiotejon/pipeManagerLauncher/internal/pkg/logging"
+	"github.com/sergiotejon/pipeManagerLauncher/pkg/config"
+)
+
+// concurrencyMu serializes the lookup of the jobs with the same concurrency key and the creation or resumption of a
+// job, so two requests with the same key cannot both find no running job
+var concurrencyMu sync.Mutex
+
+// getKubernetesClient and getDynamicClient return the clients of the Kubernetes API
+// They are variables, so the tests can replace them with fake clients
+var (
+	getKubernetesClient = func() (kubernetes.Interface, error) { return k8s.GetKubernetesClient() }
+	getDynamicClient    = k8s.GetDynamicClient
+)
+
+// getConcurrencyLabel returns the value of the concurrency label of the jobs of the route with the given key
+// The key is hashed, so it can be any string and fits in a label value
+func getConcurrencyLabel(route, key string) string {
+	hash := sha256.Sum256([]byte(route + "\x00" + key))
+	return hex.EncodeToString(hash[:20])
+}
+
+// applyConcurrency applies the concurrency policy of the pipeline data to the job before it is created
+// With the "queue" policy the job is created suspended if there are unfinished jobs of other requests with the same key,
+// and it is resumed by ResumeQueued when they finish. With the "cancel-in-progress" policy the unfinished jobs with the
+// same key of other requests are deleted along with their pipelines
+// The caller must hold concurrencyMu
+func applyConcurrency(client kubernetes.Interface, job *batchv1.Job, pipelineData *databuilder.PipelineData) error {
+	list, err := listUnfinishedJobs(client, job.Namespace, job.Labels[launcherjob.LabelConcurrency])
+	if err != nil {
+		metrics.KubernetesAPIErrorsTotal.WithLabelValues("listJobs", getErrorReason(err)).Inc()
+		return err
+	}
+	// The jobs of the same request (e.g., a request launching several pipelines) do not wait for nor cancel each other
+	var jobs []batchv1.Job
+	for _, j := range list {
+		if j.Labels[launcherjob.LabelRequestID] != job.Labels[launcherjob.LabelRequestID] {
+			jobs = append(jobs, j)
+		}
+	}
+
+	switch pipelineData.ConcurrencyPolicy {
+	case config.ConcurrencyQueue:
+		if len(jobs) > 0 {
+			suspend := true
+			job.Spec.Suspend = &suspend
+			metrics.ConcurrencyTotal.WithLabelValues(pipelineData.Name, metrics.ConcurrencyQueued).Inc()
+			logging.Logger.Info("Job queued until the running jobs with the same concurrency key finish",
+				"job", job.Name, "key", pipelineData.ConcurrencyKey, "running", len(jobs))
+		}
+	case config.ConcurrencyCancelInProgress:
+		for i := range jobs {
+			if err = cancelJob(client, &jobs[i]); err != nil {
+				return err
+			}
+			metrics.ConcurrencyTotal.WithLabelValues(pipelineData.Name, metrics.ConcurrencyCancelled).Inc()
+			logging.Logger.Info("Job cancelled by a newer job with the same concurrency key",
+				"job", jobs[i].Name, "newJob", job.Name, "key", pipelineData.ConcurrencyKey)
+		}
+	}
+
+	return nil
+}
+
+// ResumeQueued starts the oldest queued job with the same concurrency key as the given job, if none of them is
+// running. It is called when a launcher job finishes or is deleted
+// The jobs with the same key are read from the lister of the informer, so the Kubernetes API is only called to resume
+// the job
+func ResumeQueued(job *batchv1.Job, lister batchv1listers.JobLister) {
+	label := job.Labels[launcherjob.LabelConcurrency]
+	if label == "" {
+		return
+	}
+
+	concurrencyMu.Lock()
+	defer concurrencyMu.Unlock()
+
+	list, err := lister.Jobs(job.Namespace).List(labels.SelectorFromSet(labels.Set{launcherjob.LabelConcurrency: label}))
+	if err != nil {
+		logging.Logger.Error("Error resuming queued jobs", "error", fmt.Sprintf("%v", err))
+		return
+	}
+
+	var next *batchv1.Job
+	for _, j := range list {
+		if j.DeletionTimestamp != nil || launcherjob.IsFinished(j) {
+			continue
+		}
+		if !isSuspended(j) {
+			// A job with the same key is still running
+			return
+		}
+		if next == nil || j.CreationTimestamp.Before(&next.CreationTimestamp) {
+			next = j
+		}
+	}
+	if next == nil {
+		return
+	}
+
+	client, err := getKubernetesClient()
+	if err != nil {
+		metrics.KubernetesAPIErrorsTotal.WithLabelValues("getClient", "ClientError").Inc()
+		logging.Logger.Error("Error resuming queued jobs", "error", fmt.Sprintf("%v", err))
+		return
+	}
+
+	patch := []byte(`{"spec":{"suspend":false}}`)
+	_, err = client.BatchV1().Jobs(next.Namespace).Patch(context.TODO(), next.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+	if err != nil {
+		metrics.KubernetesAPIErrorsTotal.WithLabelValues("resumeJob", getErrorReason(err)).Inc()
+		logging.Logger.Error("Error resuming queued job", "job", next.Name, "error", fmt.Sprintf("%v", err))
+		return
+	}
+	metrics.ConcurrencyTotal.WithLabelValues(next.Labels[launcherjob.LabelRoute], metrics.ConcurrencyResumed).Inc()
+	logging.Logger.Info("Queued job resumed", "job", next.Name, "previousJob", job.Name)
+}
+
+// listUnfinishedJobs returns the jobs of the namespace with the given concurrency label that have not finished and
+// are not being deleted
+func listUnfinishedJobs(client kubernetes.Interface, namespace, label string) ([]batchv1.Job, error) {
+	list, err := client.BatchV1().Jobs(namespace).List(context.TODO(), metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s=%s", launcherjob.LabelConcurrency, label),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var jobs []batchv1.Job
+	for _, job := range list.Items {
+		if job.DeletionTimestamp == nil && !launcherjob.IsFinished(&job) {
+			jobs = append(jobs, job)
+		}
+	}
+	return jobs, nil
+}
+
+// cancelJob deletes the job, its pods and the pipelines deployed by it
+// The pipelines are found by the request ID and job name labels set by the launcher, so the pipelines of the other jobs
+// of the same request are kept
+func cancelJob(client kubernetes.Interface, job *batchv1.Job) error {
+	propagation := metav1.DeletePropagationBackground
+	err := client.BatchV1().Jobs(job.Namespace).Delete(context.TODO(), job.Name, metav1.DeleteOptions{
+		PropagationPolicy: &propagation,
+	})
+	if err != nil {
+		metrics.KubernetesAPIErrorsTotal.WithLabelValues("deleteJob", getErrorReason(err)).Inc()
+		return fmt.Errorf("error cancelling job %s: %w", job.Name, err)
+	}
+
+	requestID := job.Labels[launcherjob.LabelRequestID]
+	if requestID == "" {
+		return nil
+	}
+	dynamicClient, err := getDynamicClient()
+	if err != nil {
+		metrics.KubernetesAPIErrorsTotal.WithLabelValues("getClient", "ClientError").Inc()
+		return err
+	}
+	pipelines, err := dynamicClient.Resource(launcherjob.PipelineResource).List(context.TODO(), metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s=%s,%s=%s", launcherjob.LabelRequestID, requestID, launcherjob.LabelJobName, job.Name),
+	})
+	if err != nil {
+		metrics.KubernetesAPIErrorsTotal.WithLabelValues("listPipelines", getErrorReason(err)).Inc()
+		return fmt.Errorf("error listing the pipelines of job %s: %w", job.Name, err)
+	}
+	// The pipelines are listed in all the namespaces, so each one is deleted in its own namespace
+	for _, pipeline := range pipelines.Items {
+		err = dynamicClient.Resource(launcherjob.PipelineResource).Namespace(pipeline.GetNamespace()).Delete(context.TODO(), pipeline.GetName(), metav1.DeleteOptions{})
+		if err != nil {
+			metrics.KubernetesAPIErrorsTotal.WithLabelValues("deletePipeline", getErrorReason(err)).Inc()
+			return fmt.Errorf("error deleting pipeline %s of job %s: %w", pipeline.GetName(), job.Name, err)
+		}
+		logging.Logger.Info("Pipeline cancelled", "pipeline", pipeline.GetName(), "namespace", pipeline.GetNamespace(), "job", job.Name)
+	}
+
+	return nil
+}
+
+// isSuspended returns true if the job is suspended, waiting for the jobs with the same concurrency key
+func isSuspended(job *batchv1.Job) bool {
+	return job.Spec.Suspend != nil && *job.Spec.Suspend
+}
@@ -0,0 +1,272 @@
+package pipeline
+
+import (
+	"context"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	batchv1listers "k8s.io/client-go/listers/batch/v1"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/sergiotejon/pipeManagerLauncher/internal/app/webhook-listener/databuilder"
+	"github.com/sergiotejon/pipeManagerLauncher/internal/pkg/launcherjob"
+	"github.com/sergiotejon/pipeManagerLauncher/internal/pkg/logging"
+	"github.com/sergiotejon/pipeManagerLauncher/pkg/config"
+)
+
+const (
+	testNamespace = "pipe-manager"
+	testKey       = "main"
+)
+
+// testTime is the creation time of the oldest jobs of the tests
+var testTime = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func TestMain(m *testing.M) {
+	if err := logging.SetupLogger("error", "text", "stderr"); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+// newConcurrencyJob returns a launcher job of the request with the concurrency key of the tests, created the given
+// minutes after testTime. The job is running unless it is suspended or has the given condition
+func newConcurrencyJob(name, requestID string, minutes int, suspended bool, condition batchv1.JobConditionType) *batchv1.Job {
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			Namespace:         testNamespace,
+			CreationTimestamp: metav1.NewTime(testTime.Add(time.Duration(minutes) * time.Minute)),
+			Labels: map[string]string{
+				launcherjob.LabelRequestID:   requestID,
+				launcherjob.LabelRoute:       "github",
+				launcherjob.LabelConcurrency: getConcurrencyLabel("github", testKey),
+			},
+		},
+		Spec: batchv1.JobSpec{Suspend: &suspended},
+	}
+	if condition != "" {
+		job.Status.Conditions = []batchv1.JobCondition{{Type: condition, Status: corev1.ConditionTrue}}
+	}
+	return job
+}
+
+// newPipeline returns a Pipeline resource deployed by the launcher job of the request
+func newPipeline(name, namespace, requestID, jobName string) *unstructured.Unstructured {
+	item := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": launcherjob.PipelineResource.GroupVersion().String(),
+		"kind":       "Pipeline",
+	}}
+	item.SetName(name)
+	item.SetNamespace(namespace)
+	item.SetLabels(map[string]string{launcherjob.LabelRequestID: requestID, launcherjob.LabelJobName: jobName})
+	return item
+}
+
+// setFakeClients replaces the clients of the Kubernetes API with the given fake clients until the test ends
+func setFakeClients(t *testing.T, client kubernetes.Interface, dynamicClient dynamic.Interface) {
+	t.Helper()
+	previousClient, previousDynamicClient := getKubernetesClient, getDynamicClient
+	getKubernetesClient = func() (kubernetes.Interface, error) { return client, nil }
+	getDynamicClient = func() (dynamic.Interface, error) { return dynamicClient, nil }
+	t.Cleanup(func() {
+		getKubernetesClient, getDynamicClient = previousClient, previousDynamicClient
+	})
+}
+
+// jobNames returns the sorted names of the jobs of the test namespace
+func jobNames(t *testing.T, client kubernetes.Interface) string {
+	t.Helper()
+	list, err := client.BatchV1().Jobs(testNamespace).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make([]string, 0, len(list.Items))
+	for _, job := range list.Items {
+		names = append(names, job.Name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ",")
+}
+
+// pipelineNames returns the sorted namespaces and names of the pipelines
+func pipelineNames(t *testing.T, dynamicClient dynamic.Interface) string {
+	t.Helper()
+	list, err := dynamicClient.Resource(launcherjob.PipelineResource).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make([]string, 0, len(list.Items))
+	for _, item := range list.Items {
+		names = append(names, item.GetNamespace()+"/"+item.GetName())
+	}
+	sort.Strings(names)
+	return strings.Join(names, ",")
+}
+
+func TestApplyConcurrency(t *testing.T) {
+	tests := []struct {
+		name          string
+		policy        string
+		jobs          []runtime.Object
+		wantSuspended bool
+		wantJobs      string
+		wantPipelines string
+	}{
+		{
+			name:          "queue without other jobs",
+			policy:        config.ConcurrencyQueue,
+			wantSuspended: false,
+			wantPipelines: "repo-main/build-old,repo-main/build-same",
+		},
+		{
+			name:          "queue behind an unfinished job",
+			policy:        config.ConcurrencyQueue,
+			jobs:          []runtime.Object{newConcurrencyJob("job-old", "req-old", 0, false, "")},
+			wantSuspended: true,
+			wantJobs:      "job-old",
+			wantPipelines: "repo-main/build-old,repo-main/build-same",
+		},
+		{
+			name:          "queue behind a queued job",
+			policy:        config.ConcurrencyQueue,
+			jobs:          []runtime.Object{newConcurrencyJob("job-old", "req-old", 0, true, "")},
+			wantSuspended: true,
+			wantJobs:      "job-old",
+			wantPipelines: "repo-main/build-old,repo-main/build-same",
+		},
+		{
+			name:          "queue after a finished job",
+			policy:        config.ConcurrencyQueue,
+			jobs:          []runtime.Object{newConcurrencyJob("job-old", "req-old", 0, false, batchv1.JobComplete)},
+			wantSuspended: false,
+			wantJobs:      "job-old",
+			wantPipelines: "repo-main/build-old,repo-main/build-same",
+		},
+		{
+			name:          "queue with a job of the same request",
+			policy:        config.ConcurrencyQueue,
+			jobs:          []runtime.Object{newConcurrencyJob("job-same", "req-new", 0, false, "")},
+			wantSuspended: false,
+			wantJobs:      "job-same",
+			wantPipelines: "repo-main/build-old,repo-main/build-same",
+		},
+		{
+			name:   "cancel in progress",
+			policy: config.ConcurrencyCancelInProgress,
+			jobs: []runtime.Object{
+				newConcurrencyJob("job-old", "req-old", 0, false, ""),
+				newConcurrencyJob("job-same", "req-new", 1, false, ""),
+				newConcurrencyJob("job-done", "req-done", 2, false, batchv1.JobFailed),
+			},
+			wantSuspended: false,
+			wantJobs:      "job-done,job-same",
+			wantPipelines: "repo-main/build-same",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := fake.NewSimpleClientset(tt.jobs...)
+			// The pipelines are deployed in the namespaces of the repositories, not in the namespace of the launcher
+			dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+				map[schema.GroupVersionResource]string{launcherjob.PipelineResource: "PipelineList"},
+				newPipeline("build-old", "repo-main", "req-old", "job-old"),
+				newPipeline("build-same", "repo-main", "req-new", "job-same"),
+			)
+			setFakeClients(t, client, dynamicClient)
+
+			job := newConcurrencyJob("job-new", "req-new", 10, false, "")
+			job.Spec.Suspend = nil
+			pipelineData := &databuilder.PipelineData{Name: "github", ConcurrencyPolicy: tt.policy, ConcurrencyKey: testKey}
+			if err := applyConcurrency(client, job, pipelineData); err != nil {
+				t.Fatalf("applyConcurrency() error = %v", err)
+			}
+
+			if got := isSuspended(job); got != tt.wantSuspended {
+				t.Errorf("suspended = %v, want %v", got, tt.wantSuspended)
+			}
+			if got := jobNames(t, client); got != tt.wantJobs {
+				t.Errorf("jobs = %s, want %s", got, tt.wantJobs)
+			}
+			if got := pipelineNames(t, dynamicClient); got != tt.wantPipelines {
+				t.Errorf("pipelines = %s, want %s", got, tt.wantPipelines)
+			}
+		})
+	}
+}
+
+func TestResumeQueued(t *testing.T) {
+	tests := []struct {
+		name        string
+		jobs        []*batchv1.Job
+		wantResumed string
+	}{
+		{
+			name: "oldest queued job resumed",
+			jobs: []*batchv1.Job{
+				newConcurrencyJob("job-done", "req-done", 0, false, batchv1.JobComplete),
+				newConcurrencyJob("job-newer", "req-newer", 2, true, ""),
+				newConcurrencyJob("job-older", "req-older", 1, true, ""),
+			},
+			wantResumed: "job-older",
+		},
+		{
+			name: "a job is still running",
+			jobs: []*batchv1.Job{
+				newConcurrencyJob("job-done", "req-done", 0, false, batchv1.JobComplete),
+				newConcurrencyJob("job-running", "req-running", 1, false, ""),
+				newConcurrencyJob("job-queued", "req-queued", 2, true, ""),
+			},
+		},
+		{
+			name: "no queued jobs",
+			jobs: []*batchv1.Job{
+				newConcurrencyJob("job-done", "req-done", 0, false, batchv1.JobComplete),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+			objects := make([]runtime.Object, 0, len(tt.jobs))
+			for _, job := range tt.jobs {
+				if err := indexer.Add(job); err != nil {
+					t.Fatal(err)
+				}
+				objects = append(objects, job.DeepCopy())
+			}
+			client := fake.NewSimpleClientset(objects...)
+			setFakeClients(t, client, nil)
+
+			ResumeQueued(tt.jobs[0], batchv1listers.NewJobLister(indexer))
+
+			for _, job := range tt.jobs {
+				got, err := client.BatchV1().Jobs(testNamespace).Get(context.TODO(), job.Name, metav1.GetOptions{})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if resumed := isSuspended(job) && !isSuspended(got); resumed != (job.Name == tt.wantResumed) {
+					t.Errorf("job %s resumed = %v, want %v", job.Name, resumed, job.Name == tt.wantResumed)
+				}
+				if !isSuspended(job) && isSuspended(got) {
+					t.Errorf("job %s suspended", job.Name)
+				}
+			}
+		})
+	}
+}
@@ -2,10 +2,12 @@ package pipeline
 
 import (
 	"fmt"
-	"os"
+	"sort"
 	"strings"
 
 	"github.com/sergiotejon/pipeManagerLauncher/internal/app/webhook-listener/databuilder"
+	"github.com/sergiotejon/pipeManagerLauncher/internal/pkg/gitauth"
+	"github.com/sergiotejon/pipeManagerLauncher/internal/pkg/launcherjob"
 	"github.com/sergiotejon/pipeManagerLauncher/internal/pkg/version"
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
@@ -14,47 +16,62 @@ import (
 
 // JobConfig contains the configuration for a new Kubernetes Job
 type JobConfig struct {
-	JobName         string
-	Image           string
-	RequestID       string
-	PipelineData    *databuilder.PipelineData
-	Namespace       string
-	JobTimeout      int64
-	BackoffLimit    int32
-	ContainerName   string
-	Env             []corev1.EnvVar
-	ConfigmapName   string
-	ImagePullPolicy string
+	JobName          string
+	Image            string
+	RequestID        string
+	PipelineData     *databuilder.PipelineData
+	Namespace        string
+	JobTimeout       int64
+	BackoffLimit     int32
+	ContainerName    string
+	Env              []corev1.EnvVar
+	ConfigmapName    string
+	ImagePullPolicy  string
+	PodTemplates     []*corev1.PodTemplateSpec // PodTemplates are merged in order over the generated pod template
+	TTLAfterFinished *int32                    // TTLAfterFinished is the time the job is kept after it finishes (optional)
 }
 
+// requestIDEnvVar is the environment variable with the request ID of the job. The launcher labels the pipelines
+// with it. It has no PIPELINE_ prefix, so it is not a parameter of the pipelines
+const requestIDEnvVar = "REQUEST_ID"
+
+// jobNameEnvVar is the environment variable with the name of the job. The launcher labels the pipelines with it, so
+// the pipelines of each job of a request can be told apart
+const jobNameEnvVar = "JOB_NAME"
+
+// routeNameEnvVar is the environment variable with the route of the job. The launcher labels the pipelines with it, so
+// the cleaner can read their repositories with the git authentication of the route
+const routeNameEnvVar = "ROUTE_NAME"
+
 // getLabels returns a map of labels to be used in Kubernetes objects
 func getLabels(requestID string, pipelineData *databuilder.PipelineData) map[string]string {
-	return map[string]string{
-		"handleBy":               "pipeManager",
-		"pipe-manager/Version":   version.GetVersion(),
-		"pipe-manager/RequestID": requestID,
-		"pipe-manager/Route":     pipelineData.Name,
-		"pipe-manager/Event":     pipelineData.Event,
+	labels := map[string]string{
+		launcherjob.LabelHandleBy:  launcherjob.HandleByValue,
+		launcherjob.LabelVersion:   version.GetVersion(),
+		launcherjob.LabelRequestID: requestID,
+		launcherjob.LabelRoute:     pipelineData.Name,
+		launcherjob.LabelEvent:     pipelineData.Event,
 	}
-}
-
-// getCurrentNamespace returns the current namespace where the pod is running
-func getCurrentNamespace() (string, error) {
-	namespaceFile := "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
-	namespace, err := os.ReadFile(namespaceFile)
-	if err != nil {
-		return "default", err
+	if pipelineData.ConcurrencyPolicy != "" {
+		labels[launcherjob.LabelConcurrency] = getConcurrencyLabel(pipelineData.Name, pipelineData.ConcurrencyKey)
 	}
-	return string(namespace), nil
+	return labels
 }
 
 // getEnvVarsFromPipelineData converts the pipeline data into a slice of corev1.EnvVar
+// The variables are sorted by name, so the same pipeline data always produces the same job
 func getEnvVarsFromPipelineData(pipelineData *databuilder.PipelineData) []corev1.EnvVar {
+	names := make([]string, 0, len(pipelineData.Variables))
+	for key := range pipelineData.Variables {
+		names = append(names, key)
+	}
+	sort.Strings(names)
+
 	var env []corev1.EnvVar
-	for key, value := range pipelineData.Variables {
+	for _, key := range names {
 		env = append(env, corev1.EnvVar{
 			Name:  fmt.Sprintf("PIPELINE_VARIABLE_%s", strings.ToUpper(key)),
-			Value: value,
+			Value: pipelineData.Variables[key],
 		})
 	}
 
@@ -66,6 +83,19 @@ func getEnvVarsFromPipelineData(pipelineData *databuilder.PipelineData) []corev1
 		Name:  "PIPELINE_DIFF_COMMIT",
 		Value: pipelineData.DiffCommit,
 	})
+	// The ref and the merge target are only set if defined, so they are not added to the parameters of the pipelines
+	if pipelineData.Ref != "" {
+		env = append(env, corev1.EnvVar{
+			Name:  "PIPELINE_REF",
+			Value: pipelineData.Ref,
+		})
+	}
+	if pipelineData.MergeTarget != "" {
+		env = append(env, corev1.EnvVar{
+			Name:  "PIPELINE_MERGE_TARGET",
+			Value: pipelineData.MergeTarget,
+		})
+	}
 	env = append(env, corev1.EnvVar{
 		Name:  "PIPELINE_REPOSITORY",
 		Value: pipelineData.Repository,
@@ -84,7 +114,9 @@ func pointerInt32(i int32) *int32 {
 }
 
 // createJobObject creates a Kubernetes Job object with the given parameters
-func createJobObject(job *JobConfig) *batchv1.Job {
+// The pod templates of the configuration are merged over the generated pod template, keeping the labels of the
+// pipeline. It returns an error if they cannot be merged
+func createJobObject(job *JobConfig) (*batchv1.Job, error) {
 	// If the GitSecretName is empty, use an emptyDir volume. Otherwise, use a secret volume
 	var gitSecretVolume corev1.VolumeSource
 	if job.PipelineData.GitSecretName == "" {
@@ -101,15 +133,20 @@ func createJobObject(job *JobConfig) *batchv1.Job {
 	}
 
 	// Create the Job object
-	return &batchv1.Job{
+	jobObject := &batchv1.Job{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Job",
+			APIVersion: batchv1.SchemeGroupVersion.String(),
+		},
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      job.JobName,
 			Labels:    getLabels(job.RequestID, job.PipelineData),
 			Namespace: job.Namespace,
 		},
 		Spec: batchv1.JobSpec{
-			ActiveDeadlineSeconds: &job.JobTimeout,
-			BackoffLimit:          &job.BackoffLimit,
+			ActiveDeadlineSeconds:   &job.JobTimeout,
+			BackoffLimit:            &job.BackoffLimit,
+			TTLSecondsAfterFinished: job.TTLAfterFinished,
 			Template: corev1.PodTemplateSpec{
 				ObjectMeta: metav1.ObjectMeta{
 					Labels: getLabels(job.RequestID, job.PipelineData),
@@ -128,7 +165,7 @@ func createJobObject(job *JobConfig) *batchv1.Job {
 								},
 								{
 									Name:      "git-credentials",
-									MountPath: "/root/.ssh",
+									MountPath: gitauth.CredentialsDir,
 								},
 								{
 									Name:      "repo-storage",
@@ -164,4 +201,18 @@ func createJobObject(job *JobConfig) *batchv1.Job {
 			},
 		},
 	}
+
+	template, err := mergePodTemplates(jobObject.Spec.Template, job.PodTemplates...)
+	if err != nil {
+		return nil, err
+	}
+	if template.Labels == nil {
+		template.Labels = make(map[string]string)
+	}
+	for key, value := range getLabels(job.RequestID, job.PipelineData) {
+		template.Labels[key] = value
+	}
+	jobObject.Spec.Template = template
+
+	return jobObject, nil
 }
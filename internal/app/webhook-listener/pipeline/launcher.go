@@ -3,68 +3,135 @@ package pipeline
 
 import (
 	"context"
+	"fmt"
+	"time"
 
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"github.com/sergiotejon/pipeManagerLauncher/internal/app/webhook-listener/databuilder"
-	"github.com/sergiotejon/pipeManagerLauncher/internal/pkg/k8s"
+	"github.com/sergiotejon/pipeManagerLauncher/internal/app/webhook-listener/metrics"
+	"github.com/sergiotejon/pipeManagerLauncher/internal/pkg/gitauth"
+	"github.com/sergiotejon/pipeManagerLauncher/internal/pkg/launcherjob"
 	"github.com/sergiotejon/pipeManagerLauncher/internal/pkg/logging"
 	"github.com/sergiotejon/pipeManagerLauncher/pkg/config"
 )
 
-const containerName = "launcher"
-
-// LaunchJob creates a new Kubernetes Job with the given request ID and pipeline data
-// It returns an error if the job cannot be created
-// The request ID is the unique identifier of the http request coming from the webhook
+// LaunchJob creates in the cluster the Kubernetes Job built by BuildJob for the given pipeline data
+// It returns the name of the job or an error if the job cannot be created
+// The configuration is not read, so the caller does not need to hold the configuration lock while the Kubernetes API
+// is called
 // The pipeline data contains the pipeline name, path, event, repository, commit, and variables
-func LaunchJob(requestID string, pipelineData *databuilder.PipelineData) error {
-	namespace := config.Launcher.Data.Namespace
-
+func LaunchJob(job *batchv1.Job, pipelineData *databuilder.PipelineData) (string, error) {
 	// Get the Kubernetes client
-	client, err := k8s.GetKubernetesClient()
+	client, err := getKubernetesClient()
 	if err != nil {
-		return err
+		metrics.KubernetesAPIErrorsTotal.WithLabelValues("getClient", "ClientError").Inc()
+		return "", err
 	}
 
-	// Convert the environment variables map into an array of corev1.EnvVar objects
-	env := getEnvVarsFromPipelineData(pipelineData)
+	// Apply the concurrency policy. The lock is kept until the job is created, so the next job with the same key finds it
+	if pipelineData.ConcurrencyPolicy != "" {
+		concurrencyMu.Lock()
+		defer concurrencyMu.Unlock()
+		if err = applyConcurrency(client, job, pipelineData); err != nil {
+			return "", err
+		}
+	}
 
-	// Get the current namespace if not provided. "default" if not found
-	if namespace == "" {
-		namespace, err = getCurrentNamespace()
-		if err != nil {
-			logging.Logger.Warn("Error getting current namespace", "error", err, "defaultNamespace", namespace)
+	// Build the Job
+	jobClient := client.BatchV1().Jobs(job.Namespace)
+	start := time.Now()
+	result, err := jobClient.Create(context.TODO(), job, metav1.CreateOptions{})
+	metrics.JobCreationDuration.Observe(time.Since(start).Seconds())
+	if apierrors.IsAlreadyExists(err) {
+		// The request is processed again (e.g., the bolt queue replays it after a restart), the job was launched before
+		existing, getErr := jobClient.Get(context.TODO(), job.Name, metav1.GetOptions{})
+		if getErr == nil && existing.Labels[launcherjob.LabelRequestID] == job.Labels[launcherjob.LabelRequestID] {
+			logging.Logger.Info("Pipeline launcher already launched", "job", job.Name, "namespace", job.Namespace)
+			return job.Name, nil
 		}
 	}
+	if err != nil {
+		metrics.KubernetesAPIErrorsTotal.WithLabelValues("createJob", getErrorReason(err)).Inc()
+		return "", err
+	}
+
+	logging.Logger.Info("Pipeline launcher", "job", result.GetObjectMeta().GetName(), "namespace", job.Namespace)
+
+	return result.GetObjectMeta().GetName(), nil
+}
+
+// BuildJob returns the Kubernetes Job for the given request ID and pipeline data, without creating it in the cluster
+// The request ID is the unique identifier of the http request coming from the webhook
+// The index is the position of the pipeline data among the ones built from the same request. It is appended to the
+// job name, except for the first one
+// The caller must hold the configuration lock. It returns an error if the pod templates of the configuration cannot be
+// merged into the job
+func BuildJob(requestID string, index int, pipelineData *databuilder.PipelineData) (*batchv1.Job, error) {
+	namespace := launcherjob.GetNamespace()
+
+	jobName := config.Launcher.Data.JobNamePrefix + "-" + requestID
+	if index > 0 {
+		jobName = fmt.Sprintf("%s-%d", jobName, index)
+	}
+
+	// Convert the environment variables map into an array of corev1.EnvVar objects
+	env := getEnvVarsFromPipelineData(pipelineData)
+	env = append(env, corev1.EnvVar{
+		Name:  requestIDEnvVar,
+		Value: requestID,
+	}, corev1.EnvVar{
+		Name:  jobNameEnvVar,
+		Value: jobName,
+	}, corev1.EnvVar{
+		Name:  routeNameEnvVar,
+		Value: pipelineData.Name,
+	})
+	env = append(env, gitauth.EnvVars(launcherjob.GetGitAuth(pipelineData.Name))...)
 
 	// Job definition
 	// ** TODO: Create a kubernetes controller to manage a new object type called, for example, "Pipeline". That way, we can manage the pipeline lifecycle
 	// ** from the creation to the deletion of the resources. This controller will be responsible for creating the Tekton Pipeline and manage the resources
 	// ** created by the pipeline.
 	jobData := &JobConfig{
-		JobName:         config.Launcher.Data.JobNamePrefix + "-" + requestID,
-		Image:           config.Launcher.Data.GetLauncherImage(),
-		RequestID:       requestID,
-		PipelineData:    pipelineData,
-		Namespace:       namespace,
-		JobTimeout:      config.Launcher.Data.Timeout,
-		BackoffLimit:    config.Launcher.Data.BackoffLimit,
-		ContainerName:   containerName,
-		Env:             env,
-		ConfigmapName:   config.Launcher.Data.ConfigmapName,
-		ImagePullPolicy: config.Launcher.Data.PullPolicy,
+		JobName:          jobName,
+		Image:            config.Launcher.Data.GetLauncherImage(),
+		RequestID:        requestID,
+		PipelineData:     pipelineData,
+		Namespace:        namespace,
+		JobTimeout:       config.Launcher.Data.Timeout,
+		BackoffLimit:     config.Launcher.Data.BackoffLimit,
+		ContainerName:    launcherjob.ContainerName,
+		Env:              env,
+		ConfigmapName:    config.Launcher.Data.ConfigmapName,
+		ImagePullPolicy:  config.Launcher.Data.PullPolicy,
+		PodTemplates:     getPodTemplates(pipelineData.Name),
+		TTLAfterFinished: getTTLAfterFinished(launcherjob.GetRetention(pipelineData.Name)),
 	}
-	job := createJobObject(jobData)
 
-	// Build the Job
-	jobClient := client.BatchV1().Jobs(namespace)
-	result, err := jobClient.Create(context.TODO(), job, metav1.CreateOptions{})
-	if err != nil {
-		return err
-	}
+	return createJobObject(jobData)
+}
 
-	logging.Logger.Info("Pipeline launcher", "job", result.GetObjectMeta().GetName(), "namespace", namespace)
+// getTTLAfterFinished returns the TTL of the Kubernetes Job for the given retention, so the jobs are deleted by the
+// cluster even if the webhook listener is not running. It is the longest TTL, only if both TTLs are set. The exact
+// TTL of each outcome is enforced by the janitor of the webhook listener
+func getTTLAfterFinished(retention config.Retention) *int32 {
+	if retention.SuccessfulTTL <= 0 || retention.FailedTTL <= 0 {
+		return nil
+	}
+	ttl := max(retention.SuccessfulTTL, retention.FailedTTL)
+	return &ttl
+}
 
-	return nil
+// getErrorReason returns the reason of an error returned by the Kubernetes API to label the metrics
+// Errors that are not returned by the API server (e.g., connection errors) have the reason "Unknown"
+func getErrorReason(err error) string {
+	reason := apierrors.ReasonForError(err)
+	if reason == metav1.StatusReasonUnknown {
+		return "Unknown"
+	}
+	return string(reason)
 }
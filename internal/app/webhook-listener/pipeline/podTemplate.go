@@ -0,0 +1,94 @@
+package pipeline
+
+import (
+	"encoding/json"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+
+	"github.com/sergiotejon/pipeManagerLauncher/pkg/config"
+)
+
+// getPodTemplates returns the pod templates to merge over the pod template of the jobs of the given route
+// The launcher pod template is merged first and the one of the route afterward, so the route can override it
+func getPodTemplates(routeName string) []*corev1.PodTemplateSpec {
+	var templates []*corev1.PodTemplateSpec
+	if config.Launcher.Data.PodTemplate != nil {
+		templates = append(templates, config.Launcher.Data.PodTemplate)
+	}
+	for i := range config.Webhook.Data.Routes {
+		route := &config.Webhook.Data.Routes[i]
+		if route.Name == routeName && route.PodTemplate != nil {
+			templates = append(templates, route.PodTemplate)
+		}
+	}
+	return templates
+}
+
+// mergePodTemplates merges the given pod templates over the pod template of the job using a strategic merge patch
+// Containers, volumes, volume mounts and environment variables are merged by name, so a template can modify the
+// launcher container or add new ones without repeating the generated ones
+func mergePodTemplates(template corev1.PodTemplateSpec, patches ...*corev1.PodTemplateSpec) (corev1.PodTemplateSpec, error) {
+	if len(patches) == 0 {
+		return template, nil
+	}
+
+	original, err := json.Marshal(template)
+	if err != nil {
+		return template, err
+	}
+
+	for _, patch := range patches {
+		patchData, err := podTemplatePatch(patch)
+		if err != nil {
+			return template, err
+		}
+		original, err = strategicpatch.StrategicMergePatch(original, patchData, corev1.PodTemplateSpec{})
+		if err != nil {
+			return template, fmt.Errorf("error merging pod template: %w", err)
+		}
+	}
+
+	var merged corev1.PodTemplateSpec
+	if err = json.Unmarshal(original, &merged); err != nil {
+		return template, err
+	}
+	return merged, nil
+}
+
+// podTemplatePatch returns the pod template as a strategic merge patch
+// The null values are removed, otherwise the fields not set in the template (e.g., the list of containers) would
+// delete the generated ones
+func podTemplatePatch(template *corev1.PodTemplateSpec) ([]byte, error) {
+	data, err := json.Marshal(template)
+	if err != nil {
+		return nil, err
+	}
+
+	var patch map[string]interface{}
+	if err = json.Unmarshal(data, &patch); err != nil {
+		return nil, err
+	}
+	removeNulls(patch)
+
+	return json.Marshal(patch)
+}
+
+// removeNulls removes the keys with null values from the map and its nested maps and lists
+func removeNulls(data interface{}) {
+	switch x := data.(type) {
+	case map[string]interface{}:
+		for key, value := range x {
+			if value == nil {
+				delete(x, key)
+				continue
+			}
+			removeNulls(value)
+		}
+	case []interface{}:
+		for _, item := range x {
+			removeNulls(item)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package pipeline
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/sergiotejon/pipeManagerLauncher/pkg/config"
+)
+
+// jobTemplate returns a pod template as generated for the launcher jobs
+func jobTemplate() corev1.PodTemplateSpec {
+	return corev1.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "launcher"}},
+		Spec: corev1.PodSpec{
+			ServiceAccountName: "launcher",
+			RestartPolicy:      corev1.RestartPolicyNever,
+			Containers: []corev1.Container{{
+				Name:  "launcher",
+				Image: "launcher:latest",
+				Env: []corev1.EnvVar{
+					{Name: "REQUEST_ID", Value: "1"},
+					{Name: "JOB_NAME", Value: "pipeline-launcher-1"},
+				},
+				VolumeMounts: []corev1.VolumeMount{{Name: "git-secret", MountPath: "/secrets/git"}},
+			}},
+			Volumes: []corev1.Volume{{Name: "git-secret"}},
+		},
+	}
+}
+
+func TestMergePodTemplates(t *testing.T) {
+	memory := resource.MustParse("512Mi")
+
+	tests := []struct {
+		name    string
+		patches []*corev1.PodTemplateSpec
+		want    func() corev1.PodTemplateSpec
+	}{
+		{
+			name: "no patches",
+			want: jobTemplate,
+		},
+		{
+			name: "launcher container merged by name",
+			patches: []*corev1.PodTemplateSpec{{
+				Spec: corev1.PodSpec{Containers: []corev1.Container{{
+					Name:      "launcher",
+					Env:       []corev1.EnvVar{{Name: "HTTPS_PROXY", Value: "http://proxy:3128"}, {Name: "JOB_NAME", Value: "other"}},
+					Resources: corev1.ResourceRequirements{Limits: corev1.ResourceList{corev1.ResourceMemory: memory}},
+				}}},
+			}},
+			want: func() corev1.PodTemplateSpec {
+				template := jobTemplate()
+				container := &template.Spec.Containers[0]
+				container.Env = []corev1.EnvVar{
+					{Name: "HTTPS_PROXY", Value: "http://proxy:3128"},
+					{Name: "REQUEST_ID", Value: "1"},
+					{Name: "JOB_NAME", Value: "other"},
+				}
+				container.Resources.Limits = corev1.ResourceList{corev1.ResourceMemory: memory}
+				return template
+			},
+		},
+		{
+			name: "sidecar and volume added",
+			patches: []*corev1.PodTemplateSpec{{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "proxy", Image: "proxy:latest"}},
+					Volumes:    []corev1.Volume{{Name: "cache"}},
+				},
+			}},
+			want: func() corev1.PodTemplateSpec {
+				template := jobTemplate()
+				template.Spec.Containers = append([]corev1.Container{{Name: "proxy", Image: "proxy:latest"}}, template.Spec.Containers...)
+				template.Spec.Volumes = append([]corev1.Volume{{Name: "cache"}}, template.Spec.Volumes...)
+				return template
+			},
+		},
+		{
+			name: "route over launcher",
+			patches: []*corev1.PodTemplateSpec{
+				{
+					ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"team": "platform"}},
+					Spec: corev1.PodSpec{
+						ServiceAccountName: "shared",
+						NodeSelector:       map[string]string{"pool": "ci"},
+					},
+				},
+				{
+					Spec: corev1.PodSpec{
+						ServiceAccountName: "route",
+						Tolerations:        []corev1.Toleration{{Key: "ci", Operator: corev1.TolerationOpExists}},
+					},
+				},
+			},
+			want: func() corev1.PodTemplateSpec {
+				template := jobTemplate()
+				template.Labels["team"] = "platform"
+				template.Spec.ServiceAccountName = "route"
+				template.Spec.NodeSelector = map[string]string{"pool": "ci"}
+				template.Spec.Tolerations = []corev1.Toleration{{Key: "ci", Operator: corev1.TolerationOpExists}}
+				return template
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := mergePodTemplates(jobTemplate(), tt.patches...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := tt.want(); !reflect.DeepEqual(got, want) {
+				t.Errorf("mergePodTemplates() =\n%+v\nwant\n%+v", got.Spec, want.Spec)
+			}
+		})
+	}
+}
+
+func TestGetPodTemplates(t *testing.T) {
+	launcherTemplate := &corev1.PodTemplateSpec{Spec: corev1.PodSpec{ServiceAccountName: "launcher"}}
+	routeTemplate := &corev1.PodTemplateSpec{Spec: corev1.PodSpec{ServiceAccountName: "route"}}
+
+	launcher, webhook := config.Launcher, config.Webhook
+	defer func() { config.Launcher, config.Webhook = launcher, webhook }()
+	config.Launcher.Data.PodTemplate = launcherTemplate
+	config.Webhook.Data.Routes = []config.Route{
+		{Name: "github", PodTemplate: routeTemplate},
+		{Name: "gitlab"},
+	}
+
+	tests := []struct {
+		route string
+		want  []*corev1.PodTemplateSpec
+	}{
+		{"github", []*corev1.PodTemplateSpec{launcherTemplate, routeTemplate}},
+		{"gitlab", []*corev1.PodTemplateSpec{launcherTemplate}},
+		{"unknown", []*corev1.PodTemplateSpec{launcherTemplate}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.route, func(t *testing.T) {
+			if got := getPodTemplates(tt.route); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("getPodTemplates(%s) = %v, want %v", tt.route, got, tt.want)
+			}
+		})
+	}
+}
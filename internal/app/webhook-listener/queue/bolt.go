@@ -0,0 +1,228 @@
+package queue
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/sergiotejon/pipeManagerLauncher/internal/pkg/logging"
+)
+
+var (
+	// bucketName is the name of the bucket where the items are stored
+	bucketName = []byte("requests")
+	// deadLetterBucketName is the name of the bucket where the items that cannot be decoded are moved
+	deadLetterBucketName = []byte("dead-letter")
+)
+
+// boltQueue is a durable queue stored in a bbolt database file
+// Items are stored by insertion order and removed only when they are acknowledged, so the items being processed when
+// the listener stops are delivered again after a restart
+// The keys are increasing sequence numbers, so the items before the last popped one are all being processed and Pop
+// seeks past it instead of scanning them
+type boltQueue struct {
+	db       *bolt.DB
+	mu       sync.Mutex
+	inFlight map[string][]byte // inFlight contains the keys of the items returned by Pop and not acknowledged yet
+	last     []byte            // last is the key of the last item returned by Pop or moved to the dead-letter bucket
+	notify   chan struct{}     // notify wakes up a waiting Pop when a new item is pushed
+}
+
+// newBoltQueue opens or creates the bolt database in the given path
+func newBoltQueue(path string) (*boltQueue, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists(bucketName); err != nil {
+			return err
+		}
+		_, err := tx.CreateBucketIfNotExists(deadLetterBucketName)
+		return err
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+
+	q := &boltQueue{
+		db:       db,
+		inFlight: make(map[string][]byte),
+		notify:   make(chan struct{}, 1),
+	}
+	// Wake up the workers for the items pending from a previous run
+	q.signal()
+
+	return q, nil
+}
+
+// Push stores an item at the end of the queue
+func (q *boltQueue) Push(item Item) error {
+	data, err := json.Marshal(item)
+	if err != nil {
+		return err
+	}
+
+	err = q.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(bucketName)
+		seq, err := bucket.NextSequence()
+		if err != nil {
+			return err
+		}
+		key := make([]byte, 8)
+		binary.BigEndian.PutUint64(key, seq)
+		return bucket.Put(key, data)
+	})
+	if err != nil {
+		return err
+	}
+
+	q.signal()
+	return nil
+}
+
+// Pop blocks until an item is available or done is closed
+// The item is kept in the database until it is acknowledged
+func (q *boltQueue) Pop(done <-chan struct{}) (Item, bool) {
+	for {
+		item, found, err := q.next()
+		if err == nil && found {
+			// There may be more items waiting, let another worker check it
+			q.signal()
+			return item, true
+		}
+
+		select {
+		case <-done:
+			return Item{}, false
+		case <-q.notify:
+		case <-time.After(time.Second): // Retry on errors or missed notifications
+		}
+	}
+}
+
+// next returns the first item of the queue after the last popped one
+// The items that cannot be decoded are moved to the dead-letter bucket, so they do not block the queue
+func (q *boltQueue) next() (Item, bool, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var item Item
+	var found bool
+	var deadLetters [][]byte
+	err := q.db.View(func(tx *bolt.Tx) error {
+		cursor := tx.Bucket(bucketName).Cursor()
+		key, value := cursor.First()
+		if q.last != nil {
+			key, value = cursor.Seek(q.last)
+			if bytes.Equal(key, q.last) {
+				key, value = cursor.Next()
+			}
+		}
+		for ; key != nil; key, value = cursor.Next() {
+			if err := json.Unmarshal(value, &item); err != nil || item.ID == "" {
+				deadLetters = append(deadLetters, append([]byte(nil), key...))
+				continue
+			}
+			q.inFlight[item.ID] = append([]byte(nil), key...)
+			q.last = q.inFlight[item.ID]
+			found = true
+			return nil
+		}
+		return nil
+	})
+	if err != nil {
+		return item, false, err
+	}
+
+	if len(deadLetters) > 0 {
+		if err = q.moveToDeadLetter(deadLetters); err != nil {
+			return item, false, err
+		}
+		if !found {
+			q.last = deadLetters[len(deadLetters)-1]
+		}
+	}
+
+	return item, found, nil
+}
+
+// moveToDeadLetter moves the items with the given keys to the dead-letter bucket, where they are kept for inspection
+func (q *boltQueue) moveToDeadLetter(keys [][]byte) error {
+	err := q.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(bucketName)
+		deadLetter := tx.Bucket(deadLetterBucketName)
+		for _, key := range keys {
+			if err := deadLetter.Put(key, bucket.Get(key)); err != nil {
+				return err
+			}
+			if err := bucket.Delete(key); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	logging.Logger.Error("Queued items cannot be decoded, moved to the dead-letter bucket", "items", len(keys),
+		"bucket", string(deadLetterBucketName))
+	return nil
+}
+
+// Ack removes the processed item from the database
+func (q *boltQueue) Ack(id string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	key, ok := q.inFlight[id]
+	if !ok {
+		return errors.New("item is not being processed: " + id)
+	}
+
+	err := q.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketName).Delete(key)
+	})
+	if err != nil {
+		return err
+	}
+
+	delete(q.inFlight, id)
+	return nil
+}
+
+// Len returns the number of items stored in the database, waiting or being processed
+func (q *boltQueue) Len() int {
+	n := 0
+	_ = q.db.View(func(tx *bolt.Tx) error {
+		n = tx.Bucket(bucketName).Stats().KeyN
+		return nil
+	})
+	return n
+}
+
+// Cap returns 0, the database is only bounded by the disk
+func (q *boltQueue) Cap() int {
+	return 0
+}
+
+// Close closes the database
+func (q *boltQueue) Close() error {
+	return q.db.Close()
+}
+
+// signal wakes up one waiting Pop without blocking
+func (q *boltQueue) signal() {
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+}
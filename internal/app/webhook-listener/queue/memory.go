@@ -0,0 +1,55 @@
+package queue
+
+import "sync/atomic"
+
+// memoryQueue is a queue backed by a buffered channel
+// Push blocks when the channel is full, so the requests are throttled to the pace of the workers
+type memoryQueue struct {
+	items    chan Item
+	inFlight atomic.Int64 // inFlight is the number of items returned by Pop and not acknowledged yet
+}
+
+// newMemoryQueue creates a memory queue with the given capacity
+func newMemoryQueue(size int) *memoryQueue {
+	return &memoryQueue{
+		items: make(chan Item, size),
+	}
+}
+
+// Push adds an item to the queue
+func (q *memoryQueue) Push(item Item) error {
+	q.items <- item
+	return nil
+}
+
+// Pop blocks until an item is available or done is closed
+func (q *memoryQueue) Pop(done <-chan struct{}) (Item, bool) {
+	select {
+	case <-done:
+		return Item{}, false
+	case item := <-q.items:
+		q.inFlight.Add(1)
+		return item, true
+	}
+}
+
+// Ack only counts the item as processed, it was already removed from the channel by Pop
+func (q *memoryQueue) Ack(string) error {
+	q.inFlight.Add(-1)
+	return nil
+}
+
+// Len returns the number of items waiting in the channel or being processed
+func (q *memoryQueue) Len() int {
+	return len(q.items) + int(q.inFlight.Load())
+}
+
+// Cap returns the capacity of the channel
+func (q *memoryQueue) Cap() int {
+	return cap(q.items)
+}
+
+// Close does nothing for the memory queue
+func (q *memoryQueue) Close() error {
+	return nil
+}
@@ -0,0 +1,59 @@
+// Package queue contains the implementation of the queues where the accepted webhook requests wait until a worker
+// processes them. The memory queue is lost when the listener stops, while the bolt queue is persisted on disk and its
+// pending items are delivered again after a restart.
+package queue
+
+import (
+	"fmt"
+
+	"github.com/sergiotejon/pipeManagerLauncher/pkg/config"
+)
+
+// Supported queue types
+const (
+	TypeMemory = "memory"
+	TypeBolt   = "bolt"
+)
+
+// Item is a request waiting in the queue
+// It contains the request ID and the serialized request
+type Item struct {
+	ID   string `json:"id"`
+	Data []byte `json:"data"`
+}
+
+// Queue is the interface implemented by the queues of incoming requests
+type Queue interface {
+	// Push adds an item to the queue
+	Push(item Item) error
+	// Pop blocks until an item is available and returns it. It returns false if done is closed before
+	Pop(done <-chan struct{}) (Item, bool)
+	// Ack removes a processed item from the queue. Items not acknowledged are delivered again after a restart
+	Ack(id string) error
+	// Len returns the number of items waiting or being processed
+	Len() int
+	// Cap returns the number of items the queue can hold, or 0 if it is unbounded
+	Cap() int
+	// Close releases the resources of the queue
+	Close() error
+}
+
+// New creates the queue defined by the given configuration
+// The size is used as capacity of the memory queue when it is not set in the configuration
+func New(cfg config.QueueConfig, size int) (Queue, error) {
+	if cfg.Size > 0 {
+		size = cfg.Size
+	}
+
+	switch cfg.Type {
+	case "", TypeMemory:
+		return newMemoryQueue(size), nil
+	case TypeBolt:
+		if cfg.Path == "" {
+			return nil, fmt.Errorf("path is required for queue type '%s'", cfg.Type)
+		}
+		return newBoltQueue(cfg.Path)
+	default:
+		return nil, fmt.Errorf("unsupported queue type '%s'", cfg.Type)
+	}
+}
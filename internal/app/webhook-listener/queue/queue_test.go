@@ -0,0 +1,147 @@
+package queue
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/sergiotejon/pipeManagerLauncher/internal/pkg/logging"
+	"github.com/sergiotejon/pipeManagerLauncher/pkg/config"
+)
+
+func TestMain(m *testing.M) {
+	if err := logging.SetupLogger("error", "text", "stderr"); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+// popID pops an item from the queue and returns its ID, failing if there is none after a while
+func popID(t *testing.T, q Queue) string {
+	t.Helper()
+	done := make(chan struct{})
+	timer := time.AfterFunc(2*time.Second, func() { close(done) })
+	defer timer.Stop()
+
+	item, ok := q.Pop(done)
+	if !ok {
+		t.Fatal("Pop() returned no item")
+	}
+	return item.ID
+}
+
+func TestQueues(t *testing.T) {
+	queues := map[string]config.QueueConfig{
+		"memory": {Type: TypeMemory, Size: 10},
+		"bolt":   {Type: TypeBolt, Path: filepath.Join(t.TempDir(), "queue.db")},
+	}
+
+	for name, cfg := range queues {
+		t.Run(name, func(t *testing.T) {
+			q, err := New(cfg, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer q.Close()
+
+			for _, id := range []string{"a", "b", "c"} {
+				if err := q.Push(Item{ID: id, Data: []byte(id)}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if got := q.Len(); got != 3 {
+				t.Errorf("Len() = %d, want 3", got)
+			}
+
+			// The items being processed are not returned again and still count in the length
+			if got := popID(t, q); got != "a" {
+				t.Errorf("Pop() = %s, want a", got)
+			}
+			if got := popID(t, q); got != "b" {
+				t.Errorf("Pop() = %s, want b", got)
+			}
+			if got := q.Len(); got != 3 {
+				t.Errorf("Len() = %d, want 3", got)
+			}
+
+			if err := q.Ack("a"); err != nil {
+				t.Fatal(err)
+			}
+			if got := q.Len(); got != 2 {
+				t.Errorf("Len() = %d, want 2", got)
+			}
+			if got := popID(t, q); got != "c" {
+				t.Errorf("Pop() = %s, want c", got)
+			}
+		})
+	}
+}
+
+func TestBoltRedeliveryAndDeadLetter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "queue.db")
+	q, err := newBoltQueue(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Push(Item{ID: "a"}); err != nil {
+		t.Fatal(err)
+	}
+	// An item that cannot be decoded must not block the ones after it
+	err = q.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(bucketName)
+		seq, err := bucket.NextSequence()
+		if err != nil {
+			return err
+		}
+		key := make([]byte, 8)
+		binary.BigEndian.PutUint64(key, seq)
+		return bucket.Put(key, []byte("not json"))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Push(Item{ID: "b"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := popID(t, q); got != "a" {
+		t.Errorf("Pop() = %s, want a", got)
+	}
+	if got := popID(t, q); got != "b" {
+		t.Errorf("Pop() = %s, want b", got)
+	}
+	if err := q.Ack("b"); err != nil {
+		t.Fatal(err)
+	}
+	deadLetters := 0
+	_ = q.db.View(func(tx *bolt.Tx) error {
+		deadLetters = tx.Bucket(deadLetterBucketName).Stats().KeyN
+		return nil
+	})
+	if deadLetters != 1 {
+		t.Errorf("dead letters = %d, want 1", deadLetters)
+	}
+	if err := q.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The item not acknowledged is delivered again after a restart
+	q, err = newBoltQueue(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.Close()
+	if got := q.Len(); got != 1 {
+		t.Errorf("Len() = %d, want 1", got)
+	}
+	if got := popID(t, q); got != "a" {
+		t.Errorf("Pop() after restart = %s, want a", got)
+	}
+	if err := q.Ack("unknown"); err == nil {
+		t.Error("Ack() of an unknown item should fail")
+	}
+}
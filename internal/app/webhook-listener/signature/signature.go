@@ -0,0 +1,132 @@
+// Package signature contains the verification of the incoming webhook requests against the secret shared with the
+// webhook provider (GitHub, GitLab, Bitbucket or a generic HMAC signature).
+package signature
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"net/http"
+	"strings"
+
+	"github.com/sergiotejon/pipeManagerLauncher/internal/pkg/secrets"
+	"github.com/sergiotejon/pipeManagerLauncher/pkg/config"
+)
+
+// Supported verification schemes
+const (
+	TypeGitHub    = "github"
+	TypeGitLab    = "gitlab"
+	TypeBitbucket = "bitbucket"
+	TypeHMAC      = "hmac"
+)
+
+var (
+	// ErrMissingSignature is returned when the request does not contain the signature header
+	ErrMissingSignature = errors.New("signature header not found in the request")
+	// ErrInvalidSignature is returned when the signature does not match the payload and the secret
+	ErrInvalidSignature = errors.New("signature does not match")
+)
+
+// Verify checks the signature of the request using the given signature configuration
+// It returns ErrMissingSignature or ErrInvalidSignature if the request must be rejected, or another error if the
+// verification could not be done (e.g., the secret could not be read or the configuration is not valid)
+// The headers and body are the raw headers and body of the incoming request. The secret is cached, see
+// secrets.ReadCached
+func Verify(signature *config.Signature, headers http.Header, body []byte) error {
+	secret, err := secrets.ReadCached(signature.Secret)
+	if err != nil {
+		return err
+	}
+
+	switch signature.Type {
+	case TypeGitHub:
+		return verifyHMAC(headers.Get("X-Hub-Signature-256"), "sha256=", sha256.New, hex.DecodeString, secret, body)
+	case TypeBitbucket:
+		return verifyHMAC(headers.Get("X-Hub-Signature"), "sha256=", sha256.New, hex.DecodeString, secret, body)
+	case TypeGitLab:
+		return verifyToken(headers.Get("X-Gitlab-Token"), secret)
+	case TypeHMAC:
+		if signature.Header == "" {
+			return errors.New("header is required for hmac signatures")
+		}
+		hashFunc, err := getHashFunc(signature.Algorithm)
+		if err != nil {
+			return err
+		}
+		decode, err := getDecodeFunc(signature.Encoding)
+		if err != nil {
+			return err
+		}
+		return verifyHMAC(headers.Get(signature.Header), signature.Prefix, hashFunc, decode, secret, body)
+	default:
+		return fmt.Errorf("unsupported signature type '%s'", signature.Type)
+	}
+}
+
+// verifyHMAC checks that the header value is the HMAC of the body using the given secret and hash function
+func verifyHMAC(value, prefix string, hashFunc func() hash.Hash, decode func(string) ([]byte, error), secret, body []byte) error {
+	if value == "" {
+		return ErrMissingSignature
+	}
+	if !strings.HasPrefix(value, prefix) {
+		return ErrInvalidSignature
+	}
+
+	received, err := decode(strings.TrimPrefix(value, prefix))
+	if err != nil {
+		return ErrInvalidSignature
+	}
+
+	mac := hmac.New(hashFunc, secret)
+	mac.Write(body)
+	if !hmac.Equal(received, mac.Sum(nil)) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
+
+// verifyToken checks that the header value is the secret itself, as GitLab does not sign the payload
+func verifyToken(value string, secret []byte) error {
+	if value == "" {
+		return ErrMissingSignature
+	}
+	if subtle.ConstantTimeCompare([]byte(value), secret) != 1 {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// getHashFunc returns the hash function of the given algorithm. Defaults to sha256
+func getHashFunc(algorithm string) (func() hash.Hash, error) {
+	switch algorithm {
+	case "", "sha256":
+		return sha256.New, nil
+	case "sha1":
+		return sha1.New, nil
+	case "sha512":
+		return sha512.New, nil
+	default:
+		return nil, fmt.Errorf("unsupported signature algorithm '%s'", algorithm)
+	}
+}
+
+// getDecodeFunc returns the decoding function of the given encoding. Defaults to hex
+func getDecodeFunc(encoding string) (func(string) ([]byte, error), error) {
+	switch encoding {
+	case "", "hex":
+		return hex.DecodeString, nil
+	case "base64":
+		return base64.StdEncoding.DecodeString, nil
+	default:
+		return nil, fmt.Errorf("unsupported signature encoding '%s'", encoding)
+	}
+}
@@ -0,0 +1,155 @@
+package signature
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sergiotejon/pipeManagerLauncher/pkg/config"
+)
+
+const (
+	testSecret = "s3cr3t"
+	testBody   = `{"ref":"refs/heads/main"}`
+)
+
+// sign returns the HMAC of the test body with the test secret
+func sign(hashFunc func() hash.Hash) []byte {
+	mac := hmac.New(hashFunc, []byte(testSecret))
+	mac.Write([]byte(testBody))
+	return mac.Sum(nil)
+}
+
+func TestVerify(t *testing.T) {
+	secretFile := filepath.Join(t.TempDir(), "secret")
+	if err := os.WriteFile(secretFile, []byte(testSecret+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	secret := config.SecretSource{File: secretFile}
+
+	tests := []struct {
+		name      string
+		signature config.Signature
+		headers   map[string]string
+		want      error
+		wantError bool
+	}{
+		{
+			name:      "github valid",
+			signature: config.Signature{Type: TypeGitHub},
+			headers:   map[string]string{"X-Hub-Signature-256": "sha256=" + hex.EncodeToString(sign(sha256.New))},
+		},
+		{
+			name:      "github invalid",
+			signature: config.Signature{Type: TypeGitHub},
+			headers:   map[string]string{"X-Hub-Signature-256": "sha256=" + hex.EncodeToString(sign(sha1.New))},
+			want:      ErrInvalidSignature,
+		},
+		{
+			name:      "github missing",
+			signature: config.Signature{Type: TypeGitHub},
+			want:      ErrMissingSignature,
+		},
+		{
+			name:      "github without prefix",
+			signature: config.Signature{Type: TypeGitHub},
+			headers:   map[string]string{"X-Hub-Signature-256": hex.EncodeToString(sign(sha256.New))},
+			want:      ErrInvalidSignature,
+		},
+		{
+			name:      "bitbucket valid",
+			signature: config.Signature{Type: TypeBitbucket},
+			headers:   map[string]string{"X-Hub-Signature": "sha256=" + hex.EncodeToString(sign(sha256.New))},
+		},
+		{
+			name:      "gitlab valid",
+			signature: config.Signature{Type: TypeGitLab},
+			headers:   map[string]string{"X-Gitlab-Token": testSecret},
+		},
+		{
+			name:      "gitlab invalid",
+			signature: config.Signature{Type: TypeGitLab},
+			headers:   map[string]string{"X-Gitlab-Token": "other"},
+			want:      ErrInvalidSignature,
+		},
+		{
+			name:      "gitlab missing",
+			signature: config.Signature{Type: TypeGitLab},
+			want:      ErrMissingSignature,
+		},
+		{
+			name:      "hmac default sha256 hex",
+			signature: config.Signature{Type: TypeHMAC, Header: "X-Signature"},
+			headers:   map[string]string{"X-Signature": hex.EncodeToString(sign(sha256.New))},
+		},
+		{
+			name:      "hmac sha1 base64 with prefix",
+			signature: config.Signature{Type: TypeHMAC, Header: "X-Signature", Prefix: "sha1=", Algorithm: "sha1", Encoding: "base64"},
+			headers:   map[string]string{"X-Signature": "sha1=" + base64.StdEncoding.EncodeToString(sign(sha1.New))},
+		},
+		{
+			name:      "hmac not decodable",
+			signature: config.Signature{Type: TypeHMAC, Header: "X-Signature"},
+			headers:   map[string]string{"X-Signature": "not hex"},
+			want:      ErrInvalidSignature,
+		},
+		{
+			name:      "hmac without header",
+			signature: config.Signature{Type: TypeHMAC},
+			wantError: true,
+		},
+		{
+			name:      "hmac unsupported algorithm",
+			signature: config.Signature{Type: TypeHMAC, Header: "X-Signature", Algorithm: "md5"},
+			headers:   map[string]string{"X-Signature": "00"},
+			wantError: true,
+		},
+		{
+			name:      "unsupported type",
+			signature: config.Signature{Type: "other"},
+			wantError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.signature.Secret = secret
+			headers := http.Header{}
+			for name, value := range tt.headers {
+				headers.Set(name, value)
+			}
+
+			err := Verify(&tt.signature, headers, []byte(testBody))
+			switch {
+			case tt.wantError:
+				if err == nil || errors.Is(err, ErrInvalidSignature) || errors.Is(err, ErrMissingSignature) {
+					t.Errorf("Verify() = %v, want a verification error", err)
+				}
+			case !errors.Is(err, tt.want) || (tt.want == nil && err != nil):
+				t.Errorf("Verify() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyMissingSecret(t *testing.T) {
+	signature := &config.Signature{
+		Type:   TypeGitLab,
+		Secret: config.SecretSource{File: filepath.Join(t.TempDir(), "missing")},
+	}
+	headers := http.Header{}
+	headers.Set("X-Gitlab-Token", testSecret)
+
+	err := Verify(signature, headers, []byte(testBody))
+	if err == nil || errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Verify() = %v, want an error reading the secret", err)
+	}
+}
@@ -0,0 +1,185 @@
+package tracker
+
+import (
+	"fmt"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	batchv1listers "k8s.io/client-go/listers/batch/v1"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/sergiotejon/pipeManagerLauncher/internal/pkg/exitcode"
+	"github.com/sergiotejon/pipeManagerLauncher/internal/pkg/k8s"
+	"github.com/sergiotejon/pipeManagerLauncher/internal/pkg/launcherjob"
+	"github.com/sergiotejon/pipeManagerLauncher/internal/pkg/logging"
+)
+
+// resyncPeriod is the period the informers resend the jobs and pods they know
+const resyncPeriod = 10 * time.Minute
+
+// FinishedHandler is a function called when a launcher job finishes or is deleted
+// The lister reads the launcher jobs from the cache of the informer, so the handler does not need to list them from the
+// Kubernetes API
+type FinishedHandler func(job *batchv1.Job, jobs batchv1listers.JobLister)
+
+var (
+	finishedHandlers []FinishedHandler // finishedHandlers are called when a launcher job finishes or is deleted
+	jobLister        batchv1listers.JobLister
+	jobsSynced       cache.InformerSynced
+	stop             <-chan struct{}
+)
+
+// OnJobFinished registers a function to be called when a launcher job finishes or is deleted
+// The functions are called in their own goroutine once the cache of the informer is synchronized, so they can call the
+// Kubernetes API. They are called when a job goes from unfinished to finished, when an unfinished job is deleted, and
+// for the finished jobs found when the informer starts, so they must be idempotent
+// It must be called before Start
+func OnJobFinished(handler FinishedHandler) {
+	finishedHandlers = append(finishedHandlers, handler)
+}
+
+// Start starts the informers of the launcher jobs and their pods in the given namespace
+// Only the objects labelled by the webhook listener are watched. The informers stop when the done channel is closed
+// It returns an error if the Kubernetes client cannot be created
+func Start(namespace string, done <-chan struct{}) error {
+	client, err := k8s.GetKubernetesClient()
+	if err != nil {
+		return err
+	}
+
+	factory := informers.NewSharedInformerFactoryWithOptions(client, resyncPeriod,
+		informers.WithNamespace(namespace),
+		informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.LabelSelector = fmt.Sprintf("%s=%s", launcherjob.LabelHandleBy, launcherjob.HandleByValue)
+		}),
+	)
+
+	jobs := factory.Batch().V1().Jobs()
+	jobInformer := jobs.Informer()
+	jobLister = jobs.Lister()
+	jobsSynced = jobInformer.HasSynced
+	stop = done
+	_, err = jobInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) { onJob(obj, false, nil) },
+		// The resyncs deliver the same version of the jobs, so the handlers only run when a job finishes
+		UpdateFunc: func(oldObj, obj interface{}) { onJob(obj, false, oldObj) },
+		DeleteFunc: func(obj interface{}) { onJob(obj, true, nil) },
+	})
+	if err != nil {
+		return err
+	}
+
+	podInformer := factory.Core().V1().Pods().Informer()
+	_, err = podInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    onPod,
+		UpdateFunc: func(_, obj interface{}) { onPod(obj) },
+	})
+	if err != nil {
+		return err
+	}
+
+	factory.Start(done)
+	go func() {
+		if !cache.WaitForCacheSync(done, jobInformer.HasSynced, podInformer.HasSynced) {
+			return
+		}
+		logging.Logger.Info("Job tracker synchronized", "namespace", namespace)
+	}()
+
+	return nil
+}
+
+// onJob updates the status of a launcher job from the Kubernetes Job
+// The old object is the previous version of the job on updates, and nil otherwise
+func onJob(obj interface{}, deleted bool, oldObj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	job, ok := obj.(*batchv1.Job)
+	if !ok {
+		return
+	}
+	requestID := job.Labels[launcherjob.LabelRequestID]
+	if requestID == "" {
+		return
+	}
+
+	updateJob(requestID, job.Name, func(status *JobStatus) {
+		status.Namespace = job.Namespace
+		status.Route = job.Labels[launcherjob.LabelRoute]
+		status.Event = job.Labels[launcherjob.LabelEvent]
+		status.Deleted = deleted
+		if job.Status.StartTime != nil {
+			status.StartTime = &job.Status.StartTime.Time
+		}
+		if job.Status.CompletionTime != nil {
+			status.CompletionTime = &job.Status.CompletionTime.Time
+		}
+
+		phase, reason := launcherjob.GetJobPhase(job)
+		// The jobs do not go back from a final phase, the informer could deliver an old version of the job
+		if status.Phase != launcherjob.PhaseSucceeded && status.Phase != launcherjob.PhaseFailed {
+			status.Phase = phase
+		}
+		if reason != "" && status.Reason == "" {
+			status.Reason = reason
+		}
+	})
+
+	if justFinished(job, deleted, oldObj) {
+		for _, handler := range finishedHandlers {
+			go func(handler FinishedHandler) {
+				if cache.WaitForCacheSync(stop, jobsSynced) {
+					handler(job, jobLister)
+				}
+			}(handler)
+		}
+	}
+}
+
+// justFinished returns true if the job has finished or has been deleted since its previous version
+// Without a previous version (e.g., the jobs found when the informer starts), a finished job is taken as just finished
+// A finished job that is deleted is not, as its handlers already ran when it finished
+func justFinished(job *batchv1.Job, deleted bool, oldObj interface{}) bool {
+	finished := launcherjob.IsFinished(job)
+	if deleted {
+		return !finished
+	}
+	if old, ok := oldObj.(*batchv1.Job); ok && launcherjob.IsFinished(old) {
+		return false
+	}
+	return finished
+}
+
+// onPod records the exit code of the launcher container of a pod of a launcher job
+// The exit code explains why the job failed (e.g., the repository could not be cloned)
+func onPod(obj interface{}) {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok {
+		return
+	}
+	requestID := pod.Labels[launcherjob.LabelRequestID]
+	jobName := pod.Labels[batchv1.JobNameLabel]
+	if jobName == "" {
+		jobName = pod.Labels["job-name"]
+	}
+	if requestID == "" || jobName == "" {
+		return
+	}
+
+	for _, containerStatus := range pod.Status.ContainerStatuses {
+		if containerStatus.Name != launcherjob.ContainerName || containerStatus.State.Terminated == nil {
+			continue
+		}
+		exitCode := containerStatus.State.Terminated.ExitCode
+		updateJob(requestID, jobName, func(status *JobStatus) {
+			status.ExitCode = &exitCode
+			if exitCode != exitcode.ErrCodeOK {
+				status.Reason = exitcode.Describe(exitCode)
+			}
+		})
+	}
+}
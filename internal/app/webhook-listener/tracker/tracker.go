@@ -0,0 +1,221 @@
+// Package tracker follows the lifecycle of the launcher jobs created by the webhook listener and keeps the status of
+// each request, so the outcome of a delivery can be queried by its request ID.
+package tracker
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sergiotejon/pipeManagerLauncher/internal/pkg/launcherjob"
+)
+
+// retention is the time the status of a request is kept after its last update, once it has no jobs in the cluster
+const retention = time.Hour
+
+// Transition is a change of phase of a job
+type Transition struct {
+	Phase string    `json:"phase"`
+	Time  time.Time `json:"time"`
+}
+
+// JobStatus is the status of a launcher job
+// The exit code is the one of the launcher container, and the reason describes why the job failed
+type JobStatus struct {
+	Name           string       `json:"name"`
+	Namespace      string       `json:"namespace,omitempty"`
+	Route          string       `json:"route,omitempty"`
+	Event          string       `json:"event,omitempty"`
+	Phase          string       `json:"phase"`
+	ExitCode       *int32       `json:"exitCode,omitempty"`
+	Reason         string       `json:"reason,omitempty"`
+	StartTime      *time.Time   `json:"startTime,omitempty"`
+	CompletionTime *time.Time   `json:"completionTime,omitempty"`
+	Deleted        bool         `json:"deleted,omitempty"`
+	Transitions    []Transition `json:"transitions"`
+}
+
+// RequestStatus is the status of a request received by the webhook listener
+// The phase of a request with jobs is the phase of its jobs: failed if any of them failed, running, pending or queued
+// if any of them has not finished yet, and succeeded if all of them succeeded
+type RequestStatus struct {
+	RequestID string      `json:"requestID"`
+	Route     string      `json:"route,omitempty"`
+	Phase     string      `json:"phase"`
+	Message   string      `json:"message,omitempty"`
+	Jobs      []JobStatus `json:"jobs"`
+	UpdatedAt time.Time   `json:"updatedAt"`
+}
+
+// request is the status of a request kept by the tracker
+type request struct {
+	route     string
+	phase     string // phase is the outcome of processing the request, before its jobs are known
+	message   string
+	jobs      map[string]*JobStatus // jobs contains the status of the launched jobs indexed by name
+	updatedAt time.Time
+}
+
+// registry contains the status of the requests indexed by request ID
+type registry struct {
+	mu        sync.Mutex
+	requests  map[string]*request
+	lastSweep time.Time
+}
+
+// requests is the registry of the requests tracked by the webhook listener
+var requests = &registry{
+	requests: make(map[string]*request),
+}
+
+// Queued records a request accepted by the route and waiting to be processed
+func Queued(requestID, route string) {
+	requests.mu.Lock()
+	defer requests.mu.Unlock()
+
+	requests.sweep(time.Now())
+
+	req := requests.get(requestID)
+	req.route = route
+	req.phase = launcherjob.PhaseQueued
+	req.updatedAt = time.Now()
+}
+
+// SetOutcome records the outcome of processing a request (e.g., filtered or error) with a message explaining it
+func SetOutcome(requestID, phase, message string) {
+	requests.mu.Lock()
+	defer requests.mu.Unlock()
+
+	req := requests.get(requestID)
+	req.phase = phase
+	req.message = message
+	req.updatedAt = time.Now()
+}
+
+// SetMessage records a message about the processing of a request (e.g., the jobs that could not be launched) without
+// changing its phase
+func SetMessage(requestID, message string) {
+	requests.mu.Lock()
+	defer requests.mu.Unlock()
+
+	req := requests.get(requestID)
+	req.message = message
+	req.updatedAt = time.Now()
+}
+
+// AddJob records a job launched for a request. Its status is updated by the informers afterward
+func AddJob(requestID, name, route, event string) {
+	requests.mu.Lock()
+	defer requests.mu.Unlock()
+
+	req := requests.get(requestID)
+	if req.route == "" {
+		req.route = route
+	}
+	if _, ok := req.jobs[name]; !ok {
+		now := time.Now()
+		req.jobs[name] = &JobStatus{
+			Name:        name,
+			Route:       route,
+			Event:       event,
+			Phase:       launcherjob.PhasePending,
+			Transitions: []Transition{{Phase: launcherjob.PhasePending, Time: now}},
+		}
+		req.updatedAt = now
+	}
+}
+
+// Get returns the status of the request with the given ID, or false if it is not known
+func Get(requestID string) (RequestStatus, bool) {
+	requests.mu.Lock()
+	defer requests.mu.Unlock()
+
+	requests.sweep(time.Now())
+
+	req, ok := requests.requests[requestID]
+	if !ok {
+		return RequestStatus{}, false
+	}
+
+	status := RequestStatus{
+		RequestID: requestID,
+		Route:     req.route,
+		Phase:     req.phase,
+		Message:   req.message,
+		Jobs:      make([]JobStatus, 0, len(req.jobs)),
+		UpdatedAt: req.updatedAt,
+	}
+	for _, job := range req.jobs {
+		jobStatus := *job
+		jobStatus.Transitions = append([]Transition(nil), job.Transitions...)
+		status.Jobs = append(status.Jobs, jobStatus)
+	}
+	sort.Slice(status.Jobs, func(i, j int) bool {
+		return status.Jobs[i].Name < status.Jobs[j].Name
+	})
+	if len(status.Jobs) > 0 && status.Phase != launcherjob.PhaseError {
+		phases := make([]string, 0, len(status.Jobs))
+		for _, job := range status.Jobs {
+			phases = append(phases, job.Phase)
+		}
+		status.Phase = launcherjob.AggregatePhase(phases)
+	}
+
+	return status, true
+}
+
+// updateJob applies the given function to the status of the job of the request, creating it if it is not known
+// (e.g., jobs launched before the listener was restarted). A new transition is recorded if the phase changes
+func updateJob(requestID, name string, update func(job *JobStatus)) {
+	requests.mu.Lock()
+	defer requests.mu.Unlock()
+
+	req := requests.get(requestID)
+	job, ok := req.jobs[name]
+	if !ok {
+		job = &JobStatus{Name: name}
+		req.jobs[name] = job
+	}
+
+	previousPhase := job.Phase
+	update(job)
+	if job.Phase != previousPhase {
+		job.Transitions = append(job.Transitions, Transition{Phase: job.Phase, Time: time.Now()})
+	}
+	req.updatedAt = time.Now()
+}
+
+// get returns the request with the given ID, creating it if it is not known. The caller must hold the lock
+func (r *registry) get(requestID string) *request {
+	req, ok := r.requests[requestID]
+	if !ok {
+		req = &request{jobs: make(map[string]*JobStatus)}
+		r.requests[requestID] = req
+	}
+	return req
+}
+
+// sweep removes the requests not updated during the retention time whose jobs are no longer in the cluster
+// It runs at most once per minute. The caller must hold the lock
+func (r *registry) sweep(now time.Time) {
+	if now.Sub(r.lastSweep) < time.Minute {
+		return
+	}
+	r.lastSweep = now
+
+	for requestID, req := range r.requests {
+		if now.Sub(req.updatedAt) < retention {
+			continue
+		}
+		inCluster := false
+		for _, job := range req.jobs {
+			if !job.Deleted {
+				inCluster = true
+				break
+			}
+		}
+		if !inCluster {
+			delete(r.requests, requestID)
+		}
+	}
+}
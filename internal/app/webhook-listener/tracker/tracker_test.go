@@ -0,0 +1,185 @@
+package tracker
+
+import (
+	"strings"
+	"testing"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/sergiotejon/pipeManagerLauncher/internal/pkg/exitcode"
+	"github.com/sergiotejon/pipeManagerLauncher/internal/pkg/launcherjob"
+)
+
+// newJob returns a launcher job with the given condition, if any
+func newJob(condition batchv1.JobConditionType) *batchv1.Job {
+	job := &batchv1.Job{}
+	if condition != "" {
+		job.Status.Conditions = []batchv1.JobCondition{{Type: condition, Status: corev1.ConditionTrue}}
+	}
+	return job
+}
+
+func TestJustFinished(t *testing.T) {
+	tests := []struct {
+		name    string
+		job     *batchv1.Job
+		deleted bool
+		old     interface{}
+		want    bool
+	}{
+		{"running", newJob(""), false, newJob(""), false},
+		{"completed", newJob(batchv1.JobComplete), false, newJob(""), true},
+		{"failed", newJob(batchv1.JobFailed), false, newJob(""), true},
+		{"resync of a finished job", newJob(batchv1.JobComplete), false, newJob(batchv1.JobComplete), false},
+		{"finished when the informer starts", newJob(batchv1.JobFailed), false, nil, true},
+		{"running when the informer starts", newJob(""), false, nil, false},
+		{"running deleted", newJob(""), true, nil, true},
+		{"finished deleted", newJob(batchv1.JobComplete), true, nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := justFinished(tt.job, tt.deleted, tt.old); got != tt.want {
+				t.Errorf("justFinished() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// resetRegistry empties the registry of the requests, so each test starts without requests
+func resetRegistry() {
+	requests = &registry{requests: make(map[string]*request)}
+}
+
+// newLauncherJob returns a launcher job of the request, running if ready and with the given condition, if any
+func newLauncherJob(requestID, name string, ready bool, condition batchv1.JobConditionType) *batchv1.Job {
+	job := newJob(condition)
+	job.Name = name
+	job.Namespace = "pipe-manager"
+	job.Labels = map[string]string{
+		launcherjob.LabelRequestID: requestID,
+		launcherjob.LabelRoute:     "github",
+		launcherjob.LabelEvent:     "push",
+	}
+	if ready {
+		count := int32(1)
+		job.Status.Ready = &count
+	}
+	return job
+}
+
+// transitions returns the phases of the transitions of the job
+func transitions(job JobStatus) string {
+	phases := make([]string, 0, len(job.Transitions))
+	for _, transition := range job.Transitions {
+		phases = append(phases, transition.Phase)
+	}
+	return strings.Join(phases, ",")
+}
+
+func TestRegistry(t *testing.T) {
+	resetRegistry()
+
+	steps := []struct {
+		name            string
+		action          func()
+		requestID       string
+		wantPhase       string
+		wantMessage     string
+		wantTransitions string
+	}{
+		{"queued", func() { Queued("r1", "github") }, "r1", launcherjob.PhaseQueued, "", ""},
+		{"job launched", func() { AddJob("r1", "job-1", "github", "push") }, "r1", launcherjob.PhasePending, "", "pending"},
+		{"job launched again", func() { AddJob("r1", "job-1", "github", "push") }, "r1", launcherjob.PhasePending, "", "pending"},
+		{"job running", func() { onJob(newLauncherJob("r1", "job-1", true, ""), false, nil) }, "r1", launcherjob.PhaseRunning, "", "pending,running"},
+		{"other request filtered", func() { SetOutcome("r2", launcherjob.PhaseFiltered, "filtered") }, "r2", launcherjob.PhaseFiltered, "filtered", ""},
+		{"request unchanged by others", func() {}, "r1", launcherjob.PhaseRunning, "", "pending,running"},
+		{"job completed", func() {
+			onJob(newLauncherJob("r1", "job-1", false, batchv1.JobComplete), false, newLauncherJob("r1", "job-1", true, ""))
+		}, "r1", launcherjob.PhaseSucceeded, "", "pending,running,succeeded"},
+		{"old version of the job", func() { onJob(newLauncherJob("r1", "job-1", true, ""), false, nil) }, "r1", launcherjob.PhaseSucceeded, "", "pending,running,succeeded"},
+		{"message kept with the phase", func() { SetMessage("r1", "job-2 not launched") }, "r1", launcherjob.PhaseSucceeded, "job-2 not launched", "pending,running,succeeded"},
+		{"job of a restarted listener", func() { onJob(newLauncherJob("r3", "job-3", false, batchv1.JobFailed), false, nil) }, "r3", launcherjob.PhaseFailed, "", "failed"},
+		{"request with errors", func() { SetOutcome("r4", launcherjob.PhaseError, "no pipelines") }, "r4", launcherjob.PhaseError, "no pipelines", ""},
+	}
+
+	for _, step := range steps {
+		step.action()
+		status, ok := Get(step.requestID)
+		if !ok {
+			t.Fatalf("%s: request %s not found", step.name, step.requestID)
+		}
+		if status.RequestID != step.requestID || status.Phase != step.wantPhase || status.Message != step.wantMessage {
+			t.Errorf("%s: got (%s, %s, %s), want (%s, %s, %s)", step.name, status.RequestID, status.Phase, status.Message,
+				step.requestID, step.wantPhase, step.wantMessage)
+		}
+		got := ""
+		if len(status.Jobs) > 0 {
+			got = transitions(status.Jobs[0])
+		}
+		if got != step.wantTransitions {
+			t.Errorf("%s: transitions = %s, want %s", step.name, got, step.wantTransitions)
+		}
+	}
+
+	if _, ok := Get("unknown"); ok {
+		t.Errorf("Get(unknown) found")
+	}
+}
+
+func TestOnPod(t *testing.T) {
+	tests := []struct {
+		name       string
+		labels     map[string]string
+		container  string
+		terminated bool
+		exitCode   int32
+		wantCode   *int32
+		wantReason string
+	}{
+		{"succeeded", map[string]string{batchv1.JobNameLabel: "job-1"}, launcherjob.ContainerName, true, exitcode.ErrCodeOK, ptr(exitcode.ErrCodeOK), ""},
+		{"clone error", map[string]string{batchv1.JobNameLabel: "job-1"}, launcherjob.ContainerName, true, exitcode.ErrCodeCloneRepo, ptr(exitcode.ErrCodeCloneRepo), "error cloning the repository"},
+		{"authentication error", map[string]string{batchv1.JobNameLabel: "job-1"}, launcherjob.ContainerName, true, exitcode.ErrCodeGitAuth, ptr(exitcode.ErrCodeGitAuth), "git authentication failed"},
+		{"ref not found", map[string]string{"job-name": "job-1"}, launcherjob.ContainerName, true, exitcode.ErrCodeRefNotFound, ptr(exitcode.ErrCodeRefNotFound), "ref or commit not found in the repository"},
+		{"unknown exit code", map[string]string{batchv1.JobNameLabel: "job-1"}, launcherjob.ContainerName, true, 42, ptr(42), "unknown exit code 42"},
+		{"running", map[string]string{batchv1.JobNameLabel: "job-1"}, launcherjob.ContainerName, false, 0, nil, ""},
+		{"other container", map[string]string{batchv1.JobNameLabel: "job-1"}, "sidecar", true, 1, nil, ""},
+		{"pod of another job", map[string]string{}, launcherjob.ContainerName, true, 1, nil, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetRegistry()
+			AddJob("r1", "job-1", "github", "push")
+
+			state := corev1.ContainerState{}
+			if tt.terminated {
+				state.Terminated = &corev1.ContainerStateTerminated{ExitCode: tt.exitCode}
+			}
+			labels := map[string]string{launcherjob.LabelRequestID: "r1"}
+			for key, value := range tt.labels {
+				labels[key] = value
+			}
+			onPod(&corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "job-1-pod", Labels: labels},
+				Status:     corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{{Name: tt.container, State: state}}},
+			})
+
+			status, _ := Get("r1")
+			job := status.Jobs[0]
+			if (job.ExitCode == nil) != (tt.wantCode == nil) || (job.ExitCode != nil && *job.ExitCode != *tt.wantCode) {
+				t.Errorf("exit code = %v, want %v", job.ExitCode, tt.wantCode)
+			}
+			if job.Reason != tt.wantReason {
+				t.Errorf("reason = %s, want %s", job.Reason, tt.wantReason)
+			}
+		})
+	}
+}
+
+// ptr returns a pointer to the exit code
+func ptr(code int32) *int32 {
+	return &code
+}
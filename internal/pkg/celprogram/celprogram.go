@@ -0,0 +1,72 @@
+// Package celprogram contains the CEL environment used to evaluate the expressions of the webhook configuration and
+// a cache of the compiled programs, so each expression is compiled only once.
+package celprogram
+
+import (
+	"sync"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/checker/decls"
+	"github.com/google/cel-go/ext"
+)
+
+var (
+	env      *cel.Env  // env is the CEL environment shared by all the expressions
+	envErr   error     // envErr is the error creating the CEL environment, if any
+	envOnce  sync.Once // envOnce creates the environment the first time it is needed
+	programs sync.Map  // programs contains the compiled programs indexed by expression
+)
+
+// getEnv returns the CEL environment, creating it the first time
+// The environment declares the variable 'data', a map with the request received from the webhook
+func getEnv() (*cel.Env, error) {
+	envOnce.Do(func() {
+		env, envErr = cel.NewEnv(
+			// Extensible functions and types
+			ext.Strings(), ext.Encoders(), ext.Math(), ext.Sets(), ext.Lists(),
+			// Declaration of variable 'data'
+			cel.Declarations(
+				decls.NewVar("data", decls.NewMapType(decls.String, decls.Dyn)),
+			),
+		)
+	})
+	return env, envErr
+}
+
+// Compile returns the program of the given CEL expression
+// The program is compiled the first time and returned from the cache afterward
+// It returns an error if the expression cannot be compiled or the program cannot be created
+func Compile(expression string) (cel.Program, error) {
+	if program, ok := programs.Load(expression); ok {
+		return program.(cel.Program), nil
+	}
+
+	env, err := getEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	// Compile the CEL expression
+	ast, issues := env.Compile(expression)
+	if issues != nil && issues.Err() != nil {
+		return nil, issues.Err()
+	}
+
+	// Create the CEL program
+	program, err := env.Program(ast)
+	if err != nil {
+		return nil, err
+	}
+
+	programs.Store(expression, program)
+	return program, nil
+}
+
+// Reset drops the compiled programs, so the expressions no longer configured (e.g., after a reload of the
+// configuration) are not kept in the cache
+func Reset() {
+	programs.Range(func(expression, _ interface{}) bool {
+		programs.Delete(expression)
+		return true
+	})
+}
@@ -0,0 +1,45 @@
+package celprogram
+
+import "testing"
+
+// cached returns the number of compiled programs in the cache
+func cached() int {
+	count := 0
+	programs.Range(func(_, _ interface{}) bool {
+		count++
+		return true
+	})
+	return count
+}
+
+func TestCompile(t *testing.T) {
+	Reset()
+
+	tests := []struct {
+		name       string
+		expression string
+		wantErr    bool
+		wantCached int
+	}{
+		{"valid", "data.body.ref", false, 1},
+		{"cached", "data.body.ref", false, 1},
+		{"other expression", "data.headers['X-Github-Event'][0] == 'push'", false, 2},
+		{"invalid", "data.body.", true, 2},
+		{"undeclared variable", "body.ref", true, 2},
+	}
+
+	for _, tt := range tests {
+		program, err := Compile(tt.expression)
+		if (err != nil) != tt.wantErr || (err == nil && program == nil) {
+			t.Errorf("%s: Compile(%s) = %v, %v, want error %v", tt.name, tt.expression, program, err, tt.wantErr)
+		}
+		if got := cached(); got != tt.wantCached {
+			t.Errorf("%s: cached programs = %d, want %d", tt.name, got, tt.wantCached)
+		}
+	}
+
+	Reset()
+	if got := cached(); got != 0 {
+		t.Errorf("cached programs after Reset() = %d, want 0", got)
+	}
+}
@@ -0,0 +1,94 @@
+// Package configcmd contains the config command shared by all the binaries to validate a configuration file and to
+// print the JSON Schema of the configuration, and the flags to override the configuration.
+package configcmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/sergiotejon/pipeManagerLauncher/pkg/config"
+)
+
+// NewCommand returns the config command with its validate and schema subcommands
+func NewCommand() *cobra.Command {
+	configCmd := &cobra.Command{
+		Use:   "config",
+		Short: "Validate the configuration file or print its JSON Schema",
+	}
+
+	validateCmd := &cobra.Command{
+		Use:   "validate <file>",
+		Short: "Validate a configuration file",
+		Long: "Validate a configuration file as the components do when they load it: unknown fields, required fields, " +
+			"allowed values, cross-field rules and CEL expressions of every section present in the file. " +
+			"The environment variables and --set flags are applied over the file.",
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := ApplyFlags(); err != nil {
+				fmt.Fprintf(os.Stderr, "%v\n", err)
+				os.Exit(1)
+			}
+			if err := config.ValidateConfigFile(args[0]); err != nil {
+				fmt.Fprintf(os.Stderr, "%v\n", err)
+				os.Exit(1)
+			}
+			fmt.Printf("Configuration file %s is valid\n", args[0])
+		},
+	}
+
+	validateCmd.Flags().StringArrayVar(&overrides, "set", []string{}, "Override a configuration field in 'path=value' format. Can be repeated")
+
+	schemaCmd := &cobra.Command{
+		Use:   "schema",
+		Short: "Print the JSON Schema of the configuration file",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			schema, err := config.Schema()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error generating the schema: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Println(string(schema))
+		},
+	}
+
+	configCmd.AddCommand(validateCmd)
+	configCmd.AddCommand(schemaCmd)
+
+	return configCmd
+}
+
+var (
+	overrides            []string // overrides are the values of the --set flags
+	printEffectiveConfig bool     // printEffectiveConfig is a flag to print the effective configuration and exit
+)
+
+// AddFlags adds the flags to override the configuration and to print the effective configuration
+func AddFlags(flags *pflag.FlagSet) {
+	flags.StringArrayVar(&overrides, "set", []string{}, "Override a configuration field in 'path=value' format (e.g., launcher.imageName=launcher). Can be repeated")
+	flags.BoolVar(&printEffectiveConfig, "print-effective-config", false, "Print the configuration after applying the environment variables and flags, and exit")
+}
+
+// ApplyFlags sets the overrides of the --set flags. It must be called before loading the configuration
+func ApplyFlags() error {
+	return config.SetOverrides(overrides)
+}
+
+// PrintEffectiveConfig prints the effective configuration and exits if the --print-effective-config flag is set
+// It must be called after loading the configuration
+func PrintEffectiveConfig() {
+	if !printEffectiveConfig {
+		return
+	}
+
+	effectiveConfig, err := config.EffectiveConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error printing the effective configuration: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Print(string(effectiveConfig))
+	os.Exit(0)
+}
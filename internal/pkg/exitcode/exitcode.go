@@ -0,0 +1,42 @@
+// Package exitcode contains the exit codes of the launcher, so the components watching the launcher jobs can tell
+// why a job failed.
+package exitcode
+
+import "fmt"
+
+const (
+	ErrCodeOK                 = 0
+	ErrCodeLoadConfig         = 1
+	ErrCodeCloneRepo          = 2
+	ErrCodeMixFiles           = 3
+	ErrCodeConvertingPipeline = 4
+	ErrCodeBucketDownload     = 6
+	ErrCodeBucketUpload       = 7
+	ErrCodeDeploy             = 8
+	ErrCodeGitAuth            = 9
+	ErrCodeRefNotFound        = 10
+	ErrCodeMergeConflict      = 11
+)
+
+// descriptions contains the description of each exit code
+var descriptions = map[int32]string{
+	ErrCodeOK:                 "ok",
+	ErrCodeLoadConfig:         "error loading the configuration",
+	ErrCodeCloneRepo:          "error cloning the repository",
+	ErrCodeMixFiles:           "error mixing the pipeline files",
+	ErrCodeConvertingPipeline: "error converting the pipeline",
+	ErrCodeBucketDownload:     "error downloading from the bucket",
+	ErrCodeBucketUpload:       "error uploading to the bucket",
+	ErrCodeDeploy:             "error deploying the pipeline",
+	ErrCodeGitAuth:            "git authentication failed",
+	ErrCodeRefNotFound:        "ref or commit not found in the repository",
+	ErrCodeMergeConflict:      "conflict merging the commit with the target branch",
+}
+
+// Describe returns the description of the given exit code of the launcher
+func Describe(code int32) string {
+	if description, ok := descriptions[code]; ok {
+		return description
+	}
+	return fmt.Sprintf("unknown exit code %d", code)
+}
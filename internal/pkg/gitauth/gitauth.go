@@ -0,0 +1,222 @@
+// Package gitauth passes the git authentication of a route from the webhook listener to the launcher through the
+// environment of the launcher job, and builds the go-git authentication of the launcher from it.
+package gitauth
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/sergiotejon/pipeManagerLauncher/pkg/config"
+)
+
+// CredentialsDir is the directory where the git secret of the route is mounted in the launcher container
+const CredentialsDir = "/root/.ssh"
+
+// Environment variables of the launcher job with the git authentication
+const (
+	EnvType           = "GIT_AUTH_TYPE"
+	EnvUsername       = "GIT_AUTH_USERNAME"
+	EnvPasswordFile   = "GIT_AUTH_PASSWORD_FILE"
+	EnvTokenFile      = "GIT_AUTH_TOKEN_FILE"
+	EnvSSHKeyFile     = "GIT_AUTH_SSH_KEY_FILE"
+	EnvKnownHostsFile = "GIT_AUTH_KNOWN_HOSTS_FILE"
+	EnvAppID          = "GIT_AUTH_GITHUB_APP_ID"
+	EnvInstallationID = "GIT_AUTH_GITHUB_INSTALLATION_ID"
+	EnvPrivateKeyFile = "GIT_AUTH_GITHUB_PRIVATE_KEY_FILE"
+	EnvAPIURL         = "GIT_AUTH_GITHUB_API_URL"
+)
+
+const (
+	defaultSSHKeyFile     = "id_rsa"                 // defaultSSHKeyFile is the SSH private key if not configured
+	defaultKnownHostsFile = "known_hosts"            // defaultKnownHostsFile is the known_hosts file if not configured
+	defaultSSHUser        = "git"                    // defaultSSHUser is the SSH user if the URL of the repository has none
+	defaultTokenUsername  = "x-access-token"         // defaultTokenUsername is the username sent along with an access token
+	defaultAPIURL         = "https://api.github.com" // defaultAPIURL is the GitHub API if not configured
+)
+
+// EnvVars returns the environment variables of the launcher job with the given git authentication
+// It returns no variables if the authentication is nil, so the launcher clones without authentication
+func EnvVars(auth *config.GitAuth) []corev1.EnvVar {
+	if auth == nil {
+		return nil
+	}
+
+	values := []struct{ name, value string }{
+		{EnvType, auth.Type},
+		{EnvUsername, auth.Username},
+		{EnvPasswordFile, auth.PasswordFile},
+		{EnvTokenFile, auth.TokenFile},
+		{EnvSSHKeyFile, auth.SSHKeyFile},
+		{EnvKnownHostsFile, auth.KnownHostsFile},
+		{EnvPrivateKeyFile, auth.PrivateKeyFile},
+		{EnvAPIURL, auth.APIURL},
+	}
+	if auth.AppID > 0 {
+		values = append(values, struct{ name, value string }{EnvAppID, strconv.FormatInt(auth.AppID, 10)})
+	}
+	if auth.InstallationID > 0 {
+		values = append(values, struct{ name, value string }{EnvInstallationID, strconv.FormatInt(auth.InstallationID, 10)})
+	}
+
+	var env []corev1.EnvVar
+	for _, v := range values {
+		if v.value != "" {
+			env = append(env, corev1.EnvVar{Name: v.name, Value: v.value})
+		}
+	}
+	return env
+}
+
+// FromEnv returns the git authentication of the environment variables of the launcher job
+// It returns nil if no authentication is set, and an error if a numeric variable is not valid
+func FromEnv() (*config.GitAuth, error) {
+	authType := os.Getenv(EnvType)
+	if authType == "" {
+		return nil, nil
+	}
+
+	auth := &config.GitAuth{
+		Type:           authType,
+		Username:       os.Getenv(EnvUsername),
+		PasswordFile:   os.Getenv(EnvPasswordFile),
+		TokenFile:      os.Getenv(EnvTokenFile),
+		SSHKeyFile:     os.Getenv(EnvSSHKeyFile),
+		KnownHostsFile: os.Getenv(EnvKnownHostsFile),
+		PrivateKeyFile: os.Getenv(EnvPrivateKeyFile),
+		APIURL:         os.Getenv(EnvAPIURL),
+	}
+
+	var err error
+	if value := os.Getenv(EnvAppID); value != "" {
+		auth.AppID, err = strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", EnvAppID, err)
+		}
+	}
+	if value := os.Getenv(EnvInstallationID); value != "" {
+		auth.InstallationID, err = strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", EnvInstallationID, err)
+		}
+	}
+
+	return auth, nil
+}
+
+// AuthMethod returns the go-git authentication to clone the repository with the given git authentication
+// It returns nil if the authentication is nil. The GitHub App authentication requests an installation token to the
+// GitHub API, so it must be called right before cloning
+func AuthMethod(auth *config.GitAuth, repositoryURL string) (transport.AuthMethod, error) {
+	if auth == nil {
+		return nil, nil
+	}
+
+	switch auth.Type {
+	case config.GitAuthSSH:
+		return sshAuth(auth, repositoryURL)
+	case config.GitAuthBasic:
+		password, err := readFile(auth.PasswordFile)
+		if err != nil {
+			return nil, err
+		}
+		return &http.BasicAuth{Username: auth.Username, Password: password}, nil
+	case config.GitAuthToken:
+		token, err := readFile(auth.TokenFile)
+		if err != nil {
+			return nil, err
+		}
+		username := auth.Username
+		if username == "" {
+			username = defaultTokenUsername
+		}
+		return &http.BasicAuth{Username: username, Password: token}, nil
+	case config.GitAuthGitHubApp:
+		token, err := installationToken(auth, repositoryURL)
+		if err != nil {
+			return nil, err
+		}
+		return &http.BasicAuth{Username: defaultTokenUsername, Password: token}, nil
+	default:
+		return nil, fmt.Errorf("unknown git authentication type '%s'", auth.Type)
+	}
+}
+
+// sshAuth returns the SSH authentication with the private key, verifying the host key with the known_hosts file
+func sshAuth(auth *config.GitAuth, repositoryURL string) (transport.AuthMethod, error) {
+	user := defaultSSHUser
+	if endpoint, err := transport.NewEndpoint(repositoryURL); err == nil && endpoint.User != "" {
+		user = endpoint.User
+	}
+
+	keyFile, knownHostsFile := sshFiles(auth)
+	publicKeys, err := ssh.NewPublicKeysFromFile(user, keyFile, "")
+	if err != nil {
+		return nil, fmt.Errorf("error reading the SSH key: %w", err)
+	}
+
+	publicKeys.HostKeyCallback, err = ssh.NewKnownHostsCallback(knownHostsFile)
+	if err != nil {
+		return nil, fmt.Errorf("error reading the known_hosts file: %w", err)
+	}
+
+	return publicKeys, nil
+}
+
+// SSHCommand returns the ssh command for the git commands run by the launcher (e.g., git-lfs), with the same private
+// key and known_hosts file as the go-git authentication
+// It returns an empty command if the authentication is not ssh, so the default ssh command is used
+func SSHCommand(auth *config.GitAuth) string {
+	if auth == nil || auth.Type != config.GitAuthSSH {
+		return ""
+	}
+
+	keyFile, knownHostsFile := sshFiles(auth)
+	return fmt.Sprintf("ssh -i %s -o IdentitiesOnly=yes -o UserKnownHostsFile=%s -o StrictHostKeyChecking=yes",
+		shellQuote(keyFile), shellQuote(knownHostsFile))
+}
+
+// sshFiles returns the paths of the private key and the known_hosts file of the SSH authentication
+func sshFiles(auth *config.GitAuth) (string, string) {
+	keyFile := auth.SSHKeyFile
+	if keyFile == "" {
+		keyFile = defaultSSHKeyFile
+	}
+	knownHostsFile := auth.KnownHostsFile
+	if knownHostsFile == "" {
+		knownHostsFile = defaultKnownHostsFile
+	}
+	return resolvePath(keyFile), resolvePath(knownHostsFile)
+}
+
+// shellQuote quotes the value for the shell that runs the ssh command
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+// readFile returns the content of the credentials file without the trailing line breaks
+func readFile(path string) (string, error) {
+	data, err := os.ReadFile(resolvePath(path))
+	if err != nil {
+		return "", fmt.Errorf("error reading the git credentials: %w", err)
+	}
+	for len(data) > 0 && (data[len(data)-1] == '\n' || data[len(data)-1] == '\r') {
+		data = data[:len(data)-1]
+	}
+	return string(data), nil
+}
+
+// resolvePath returns the path of a credentials file. The relative paths are resolved from CredentialsDir
+func resolvePath(path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(CredentialsDir, path)
+}
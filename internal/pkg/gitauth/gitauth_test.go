@@ -0,0 +1,36 @@
+package gitauth
+
+import (
+	"testing"
+
+	"github.com/sergiotejon/pipeManagerLauncher/pkg/config"
+)
+
+func TestSSHCommand(t *testing.T) {
+	tests := []struct {
+		name string
+		auth *config.GitAuth
+		want string
+	}{
+		{"no authentication", nil, ""},
+		{"token", &config.GitAuth{Type: config.GitAuthToken, TokenFile: "token"}, ""},
+		{
+			name: "ssh defaults",
+			auth: &config.GitAuth{Type: config.GitAuthSSH},
+			want: "ssh -i '/root/.ssh/id_rsa' -o IdentitiesOnly=yes -o UserKnownHostsFile='/root/.ssh/known_hosts' -o StrictHostKeyChecking=yes",
+		},
+		{
+			name: "ssh files",
+			auth: &config.GitAuth{Type: config.GitAuthSSH, SSHKeyFile: "/keys/deploy key", KnownHostsFile: "it's_hosts"},
+			want: `ssh -i '/keys/deploy key' -o IdentitiesOnly=yes -o UserKnownHostsFile='/root/.ssh/it'\''s_hosts' -o StrictHostKeyChecking=yes`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SSHCommand(tt.auth); got != tt.want {
+				t.Errorf("SSHCommand() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
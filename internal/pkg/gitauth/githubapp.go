@@ -0,0 +1,125 @@
+package gitauth
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/sergiotejon/pipeManagerLauncher/pkg/config"
+)
+
+const (
+	jwtLifetime  = 9 * time.Minute  // jwtLifetime is the lifetime of the JWT of the GitHub App, 10 minutes at most
+	jwtClockSkew = time.Minute      // jwtClockSkew is subtracted from the issue time of the JWT against clock drift
+	apiTimeout   = 30 * time.Second // apiTimeout is the timeout of the requests to the GitHub API
+)
+
+// apiClient is the HTTP client of the GitHub API
+var apiClient = &http.Client{Timeout: apiTimeout}
+
+// installationToken returns an installation access token of the GitHub App to clone the repository
+// The token is requested with a JWT signed with the private key of the app. If the installation ID is not set, it is
+// looked up from the owner and name of the repository
+func installationToken(auth *config.GitAuth, repositoryURL string) (string, error) {
+	key, err := readFile(auth.PrivateKeyFile)
+	if err != nil {
+		return "", err
+	}
+	privateKey, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(key))
+	if err != nil {
+		return "", fmt.Errorf("error parsing the private key of the GitHub App: %w", err)
+	}
+
+	now := time.Now()
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.RegisteredClaims{
+		Issuer:    strconv.FormatInt(auth.AppID, 10),
+		IssuedAt:  jwt.NewNumericDate(now.Add(-jwtClockSkew)),
+		ExpiresAt: jwt.NewNumericDate(now.Add(jwtLifetime)),
+	}).SignedString(privateKey)
+	if err != nil {
+		return "", fmt.Errorf("error signing the JWT of the GitHub App: %w", err)
+	}
+
+	apiURL := strings.TrimSuffix(auth.APIURL, "/")
+	if apiURL == "" {
+		apiURL = defaultAPIURL
+	}
+
+	installationID := auth.InstallationID
+	if installationID == 0 {
+		repository, err := getRepositoryName(repositoryURL)
+		if err != nil {
+			return "", err
+		}
+		var installation struct {
+			ID int64 `json:"id"`
+		}
+		err = callAPI(http.MethodGet, fmt.Sprintf("%s/repos/%s/installation", apiURL, repository), signed, &installation)
+		if err != nil {
+			return "", fmt.Errorf("error getting the installation of the GitHub App for %s: %w", repository, err)
+		}
+		installationID = installation.ID
+	}
+
+	var token struct {
+		Token string `json:"token"`
+	}
+	err = callAPI(http.MethodPost, fmt.Sprintf("%s/app/installations/%d/access_tokens", apiURL, installationID), signed, &token)
+	if err != nil {
+		return "", fmt.Errorf("error getting the installation token of the GitHub App: %w", err)
+	}
+	if token.Token == "" {
+		return "", fmt.Errorf("empty installation token of the GitHub App")
+	}
+
+	return token.Token, nil
+}
+
+// callAPI sends a request to the GitHub API authenticated with the JWT and decodes the JSON response into the result
+func callAPI(method, url, jwtToken string, result interface{}) error {
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+jwtToken)
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+
+	resp, err := apiClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s %s: %s: %s", method, url, resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	return json.Unmarshal(body, result)
+}
+
+// getRepositoryName returns the "owner/name" of the repository from its URL (e.g., https://github.com/owner/name.git
+// or git@github.com:owner/name.git)
+func getRepositoryName(repositoryURL string) (string, error) {
+	endpoint, err := transport.NewEndpoint(repositoryURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid repository URL: %w", err)
+	}
+
+	parts := strings.Split(strings.Trim(strings.TrimSuffix(endpoint.Path, ".git"), "/"), "/")
+	if len(parts) < 2 {
+		return "", fmt.Errorf("cannot get the owner and name of the repository from '%s'", repositoryURL)
+	}
+	return parts[len(parts)-2] + "/" + parts[len(parts)-1], nil
+}
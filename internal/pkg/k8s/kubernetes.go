@@ -4,6 +4,7 @@ import (
 	"os"
 	"path/filepath"
 
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
@@ -33,6 +34,17 @@ func GetKubernetesConfig() (*rest.Config, error) {
 	return cfg, nil
 }
 
+// GetCurrentNamespace returns the current namespace where the pod is running
+// It returns "default" along with the error if the namespace cannot be read
+func GetCurrentNamespace() (string, error) {
+	namespaceFile := "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+	namespace, err := os.ReadFile(namespaceFile)
+	if err != nil {
+		return "default", err
+	}
+	return string(namespace), nil
+}
+
 // GetKubernetesClient returns a Kubernetes clientset configured for either in-cluster or local access
 func GetKubernetesClient() (*kubernetes.Clientset, error) {
 	cfg, err := GetKubernetesConfig()
@@ -48,3 +60,14 @@ func GetKubernetesClient() (*kubernetes.Clientset, error) {
 
 	return client, nil
 }
+
+// GetDynamicClient returns a Kubernetes dynamic client configured for either in-cluster or local access
+// It allows to manage the custom resources (e.g., the Pipelines) without their typed clients
+func GetDynamicClient() (dynamic.Interface, error) {
+	cfg, err := GetKubernetesConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	return dynamic.NewForConfig(cfg)
+}
@@ -0,0 +1,45 @@
+package launcherjob
+
+import (
+	"github.com/sergiotejon/pipeManagerLauncher/internal/pkg/k8s"
+	"github.com/sergiotejon/pipeManagerLauncher/internal/pkg/logging"
+	"github.com/sergiotejon/pipeManagerLauncher/pkg/config"
+)
+
+// GetNamespace returns the namespace where the launcher jobs are created
+// It is the namespace of the launcher configuration, or the current namespace if not provided. "default" if not found
+func GetNamespace() string {
+	namespace := config.Launcher.Data.Namespace
+	if namespace == "" {
+		var err error
+		namespace, err = k8s.GetCurrentNamespace()
+		if err != nil {
+			logging.Logger.Warn("Error getting current namespace", "error", err, "defaultNamespace", namespace)
+		}
+	}
+	return namespace
+}
+
+// GetRetention returns the retention of the finished jobs of the route with the given name
+// It is the retention of the route, if defined, or the one of the launcher otherwise
+func GetRetention(routeName string) config.Retention {
+	for i := range config.Webhook.Data.Routes {
+		route := &config.Webhook.Data.Routes[i]
+		if route.Name == routeName && route.Retention != nil {
+			return *route.Retention
+		}
+	}
+	return config.Launcher.Data.Retention
+}
+
+// GetGitAuth returns the git authentication of the jobs of the route with the given name
+// It is the git authentication of the route, if defined, or the one of the launcher otherwise
+func GetGitAuth(routeName string) *config.GitAuth {
+	for i := range config.Webhook.Data.Routes {
+		route := &config.Webhook.Data.Routes[i]
+		if route.Name == routeName && route.GitAuth != nil {
+			return route.GitAuth
+		}
+	}
+	return config.Launcher.Data.GitAuth
+}
@@ -0,0 +1,31 @@
+// Package launcherjob contains what the webhook listener, the cleaner and the dashboard share about the launcher
+// jobs: their labels, phases, namespace, retention and git authentication, and the resource of the Pipelines they
+// deploy.
+package launcherjob
+
+import "k8s.io/apimachinery/pkg/runtime/schema"
+
+// Labels of the Kubernetes objects created by the webhook listener
+const (
+	LabelHandleBy  = "handleBy"
+	LabelVersion   = "pipe-manager/Version"
+	LabelRequestID = "pipe-manager/RequestID"
+	LabelRoute     = "pipe-manager/Route"
+	LabelEvent     = "pipe-manager/Event"
+	// LabelConcurrency is the hash of the route and concurrency key of the job. Only set if it has a concurrency policy
+	LabelConcurrency = "pipe-manager/Concurrency"
+	// LabelJobName is the name of the launcher job that deployed a pipeline. Set by the launcher on the pipelines only.
+	// The launcher also sets the request ID and route labels on the pipelines
+	LabelJobName = "pipe-manager/JobName"
+
+	HandleByValue = "pipeManager" // HandleByValue is the value of the handleBy label
+)
+
+const ContainerName = "launcher" // ContainerName is the name of the launcher container of the jobs
+
+// PipelineResource is the resource of the Pipelines deployed by the launcher jobs
+var PipelineResource = schema.GroupVersionResource{
+	Group:    "pipemanager.sergiotejon.github.io",
+	Version:  "v1alpha1",
+	Resource: "pipelines",
+}
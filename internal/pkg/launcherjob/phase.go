@@ -0,0 +1,71 @@
+package launcherjob
+
+import (
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Phases of the requests and the launcher jobs
+const (
+	PhaseQueued    = "queued"    // PhaseQueued is a request waiting in the queue, or a job waiting for the jobs with the same concurrency key
+	PhaseFiltered  = "filtered"  // PhaseFiltered is a request that does not launch any pipeline
+	PhaseError     = "error"     // PhaseError is a request whose pipelines could not be built or launched
+	PhasePending   = "pending"   // PhasePending is a job whose pod is not running yet
+	PhaseRunning   = "running"   // PhaseRunning is a job whose pod is running
+	PhaseSucceeded = "succeeded" // PhaseSucceeded is a job completed successfully
+	PhaseFailed    = "failed"    // PhaseFailed is a job that failed
+)
+
+// GetJobPhase returns the phase of a Kubernetes Job and the reason if it failed
+func GetJobPhase(job *batchv1.Job) (string, string) {
+	for _, condition := range job.Status.Conditions {
+		if condition.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch condition.Type {
+		case batchv1.JobComplete:
+			return PhaseSucceeded, ""
+		case batchv1.JobFailed:
+			return PhaseFailed, condition.Reason
+		}
+	}
+
+	if job.Spec.Suspend != nil && *job.Spec.Suspend {
+		return PhaseQueued, ""
+	}
+	if job.Status.Ready != nil && *job.Status.Ready > 0 {
+		return PhaseRunning, ""
+	}
+	return PhasePending, ""
+}
+
+// AggregatePhase returns the phase of a request from the phases of its jobs
+func AggregatePhase(jobPhases []string) string {
+	phases := make(map[string]bool)
+	for _, phase := range jobPhases {
+		phases[phase] = true
+	}
+
+	switch {
+	case phases[PhaseFailed]:
+		return PhaseFailed
+	case phases[PhaseRunning]:
+		return PhaseRunning
+	case phases[PhasePending]:
+		return PhasePending
+	case phases[PhaseQueued]:
+		return PhaseQueued
+	default:
+		return PhaseSucceeded
+	}
+}
+
+// IsFinished returns true if the job has completed or failed
+func IsFinished(job *batchv1.Job) bool {
+	for _, condition := range job.Status.Conditions {
+		if (condition.Type == batchv1.JobComplete || condition.Type == batchv1.JobFailed) && condition.Status == corev1.ConditionTrue {
+			return true
+		}
+	}
+	return false
+}
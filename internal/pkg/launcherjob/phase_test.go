@@ -0,0 +1,26 @@
+package launcherjob
+
+import "testing"
+
+func TestAggregatePhase(t *testing.T) {
+	tests := []struct {
+		name   string
+		phases []string
+		want   string
+	}{
+		{"no jobs", nil, PhaseSucceeded},
+		{"all succeeded", []string{PhaseSucceeded, PhaseSucceeded}, PhaseSucceeded},
+		{"any failed", []string{PhaseSucceeded, PhaseRunning, PhaseFailed}, PhaseFailed},
+		{"running before pending", []string{PhasePending, PhaseRunning, PhaseSucceeded}, PhaseRunning},
+		{"pending before queued", []string{PhaseQueued, PhasePending}, PhasePending},
+		{"queued", []string{PhaseSucceeded, PhaseQueued}, PhaseQueued},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := AggregatePhase(tt.phases); got != tt.want {
+				t.Errorf("AggregatePhase(%v) = %s, want %s", tt.phases, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package launcherjob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/sergiotejon/pipeManagerLauncher/internal/pkg/logging"
+	"github.com/sergiotejon/pipeManagerLauncher/pkg/config"
+)
+
+// Clean deletes the expired launcher jobs of the namespace, along with their pods, and returns them
+// The retention of each route is returned by the given function. In dry run mode the jobs are returned but not deleted
+// A job that cannot be deleted does not stop the deletion of the others, and the jobs already deleted (e.g., by their
+// TTL) are skipped
+func Clean(client kubernetes.Interface, namespace string, retention func(route string) config.Retention, dryRun bool) ([]batchv1.Job, error) {
+	list, err := client.BatchV1().Jobs(namespace).List(context.TODO(), metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s=%s", LabelHandleBy, HandleByValue),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	expired := Expired(list.Items, time.Now(), retention)
+	if dryRun {
+		return expired, nil
+	}
+
+	propagation := metav1.DeletePropagationBackground
+	var deleted []batchv1.Job
+	var errs []error
+	for _, job := range expired {
+		err = client.BatchV1().Jobs(job.Namespace).Delete(context.TODO(), job.Name, metav1.DeleteOptions{
+			PropagationPolicy: &propagation,
+		})
+		if apierrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("error deleting job %s: %w", job.Name, err))
+			continue
+		}
+		logging.Logger.Info("Launcher job deleted", "job", job.Name, "namespace", job.Namespace,
+			"route", job.Labels[LabelRoute])
+		deleted = append(deleted, job)
+	}
+
+	return deleted, errors.Join(errs...)
+}
+
+// Expired returns the finished jobs that must be deleted at the given time according to the retention of their route
+// A job is expired if the TTL of its outcome has passed since it finished, or if there are as many newer finished
+// jobs of its route as the number of jobs to keep. The unfinished jobs are never expired
+func Expired(jobs []batchv1.Job, now time.Time, retention func(route string) config.Retention) []batchv1.Job {
+	// Finished jobs by route, the newest first
+	byRoute := make(map[string][]batchv1.Job)
+	for _, job := range jobs {
+		if job.DeletionTimestamp != nil {
+			continue
+		}
+		if _, finished := finishedAt(&job); finished {
+			route := job.Labels[LabelRoute]
+			byRoute[route] = append(byRoute[route], job)
+		}
+	}
+
+	routes := make([]string, 0, len(byRoute))
+	for route := range byRoute {
+		routes = append(routes, route)
+	}
+	sort.Strings(routes)
+
+	var expired []batchv1.Job
+	for _, route := range routes {
+		routeJobs := byRoute[route]
+		sort.SliceStable(routeJobs, func(i, j int) bool {
+			ti, _ := finishedAt(&routeJobs[i])
+			tj, _ := finishedAt(&routeJobs[j])
+			return ti.After(tj)
+		})
+
+		policy := retention(route)
+		for i, job := range routeJobs {
+			if policy.KeepLast > 0 && i >= policy.KeepLast {
+				expired = append(expired, job)
+				continue
+			}
+
+			ttl := policy.SuccessfulTTL
+			if isFailed(&job) {
+				ttl = policy.FailedTTL
+			}
+			end, _ := finishedAt(&job)
+			if ttl > 0 && now.Sub(end) >= time.Duration(ttl)*time.Second {
+				expired = append(expired, job)
+			}
+		}
+	}
+
+	return expired
+}
+
+// finishedAt returns the time the job finished, and false if it has not finished
+func finishedAt(job *batchv1.Job) (time.Time, bool) {
+	for _, condition := range job.Status.Conditions {
+		if (condition.Type == batchv1.JobComplete || condition.Type == batchv1.JobFailed) && condition.Status == corev1.ConditionTrue {
+			if job.Status.CompletionTime != nil {
+				return job.Status.CompletionTime.Time, true
+			}
+			return condition.LastTransitionTime.Time, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// isFailed returns true if the job failed
+func isFailed(job *batchv1.Job) bool {
+	for _, condition := range job.Status.Conditions {
+		if condition.Type == batchv1.JobFailed && condition.Status == corev1.ConditionTrue {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,154 @@
+package launcherjob
+
+import (
+	"os"
+	"sort"
+	"testing"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/sergiotejon/pipeManagerLauncher/internal/pkg/logging"
+	"github.com/sergiotejon/pipeManagerLauncher/pkg/config"
+)
+
+func TestMain(m *testing.M) {
+	if err := logging.SetupLogger("error", "text", "stderr"); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+var now = time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+// newJob returns a launcher job of the route that finished the given time ago with the given condition, or an
+// unfinished one if the condition is empty
+func newJob(name, route string, condition batchv1.JobConditionType, ago time.Duration) batchv1.Job {
+	job := batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "pipe-manager",
+			Labels: map[string]string{
+				LabelHandleBy: HandleByValue,
+				LabelRoute:    route,
+			},
+		},
+	}
+	if condition != "" {
+		completion := metav1.NewTime(now.Add(-ago))
+		job.Status.CompletionTime = &completion
+		job.Status.Conditions = []batchv1.JobCondition{{Type: condition, Status: corev1.ConditionTrue}}
+	}
+	return job
+}
+
+// names returns the sorted names of the jobs
+func names(jobs []batchv1.Job) []string {
+	result := make([]string, 0, len(jobs))
+	for _, job := range jobs {
+		result = append(result, job.Name)
+	}
+	sort.Strings(result)
+	return result
+}
+
+func TestExpired(t *testing.T) {
+	deleting := newJob("deleting", "github", batchv1.JobComplete, time.Hour)
+	deleting.DeletionTimestamp = &metav1.Time{Time: now}
+
+	tests := []struct {
+		name      string
+		jobs      []batchv1.Job
+		retention config.Retention
+		want      []string
+	}{
+		{
+			name:      "no retention",
+			jobs:      []batchv1.Job{newJob("a", "github", batchv1.JobComplete, time.Hour)},
+			retention: config.Retention{},
+			want:      []string{},
+		},
+		{
+			name: "successful and failed TTLs",
+			jobs: []batchv1.Job{
+				newJob("succeeded-old", "github", batchv1.JobComplete, 20*time.Minute),
+				newJob("succeeded-new", "github", batchv1.JobComplete, 5*time.Minute),
+				newJob("failed-old", "github", batchv1.JobFailed, 20*time.Minute),
+			},
+			retention: config.Retention{SuccessfulTTL: 600, FailedTTL: 3600},
+			want:      []string{"succeeded-old"},
+		},
+		{
+			name: "keep last per route",
+			jobs: []batchv1.Job{
+				newJob("github-1", "github", batchv1.JobComplete, 3*time.Minute),
+				newJob("github-2", "github", batchv1.JobFailed, 2*time.Minute),
+				newJob("github-3", "github", batchv1.JobComplete, time.Minute),
+				newJob("custom-1", "custom", batchv1.JobComplete, 3*time.Minute),
+			},
+			retention: config.Retention{KeepLast: 2},
+			want:      []string{"github-1"},
+		},
+		{
+			name: "unfinished and deleting jobs",
+			jobs: []batchv1.Job{
+				newJob("running", "github", "", 0),
+				deleting,
+			},
+			retention: config.Retention{SuccessfulTTL: 1, FailedTTL: 1, KeepLast: 1},
+			want:      []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := names(Expired(tt.jobs, now, func(string) config.Retention { return tt.retention }))
+			if len(got) != len(tt.want) {
+				t.Fatalf("Expired() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("Expired() = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestCleanKeepsGoingOnErrors(t *testing.T) {
+	jobs := []runtime.Object{}
+	for _, name := range []string{"gone", "forbidden", "expired"} {
+		job := newJob(name, "github", batchv1.JobComplete, 48*time.Hour)
+		jobs = append(jobs, &job)
+	}
+	client := fake.NewSimpleClientset(jobs...)
+	client.PrependReactor("delete", "jobs", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		switch action.(k8stesting.DeleteAction).GetName() {
+		case "gone":
+			return true, nil, apierrors.NewNotFound(batchv1.Resource("jobs"), "gone")
+		case "forbidden":
+			return true, nil, apierrors.NewForbidden(batchv1.Resource("jobs"), "forbidden", nil)
+		}
+		return false, nil, nil
+	})
+
+	retention := func(string) config.Retention { return config.Retention{SuccessfulTTL: 60, FailedTTL: 60} }
+	deleted, err := Clean(client, "pipe-manager", retention, false)
+	if err == nil || !apierrors.IsForbidden(err) {
+		t.Errorf("Clean() error = %v, want the forbidden error", err)
+	}
+	if got := names(deleted); len(got) != 1 || got[0] != "expired" {
+		t.Errorf("Clean() deleted = %v, want [expired]", got)
+	}
+
+	dryRun, err := Clean(fake.NewSimpleClientset(jobs...), "pipe-manager", retention, true)
+	if err != nil || len(dryRun) != 3 {
+		t.Errorf("Clean() in dry run = %v, %v, want the 3 jobs", names(dryRun), err)
+	}
+}
@@ -25,6 +25,8 @@ func SetupLogger(logLevel string, logFormat string, logDest string) error {
 	switch logDest {
 	case "stdout":
 		file = os.Stdout
+	case "stderr":
+		file = os.Stderr
 	case "":
 		file = os.Stdout
 	default:
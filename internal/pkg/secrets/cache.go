@@ -0,0 +1,59 @@
+package secrets
+
+import (
+	"sync"
+	"time"
+
+	"github.com/sergiotejon/pipeManagerLauncher/pkg/config"
+)
+
+const (
+	cacheTTL      = 5 * time.Minute  // cacheTTL is the time a secret value is cached, so the rotated secrets are read again
+	cacheErrorTTL = 10 * time.Second // cacheErrorTTL is the time a failed read is cached, so it is not retried on each request
+)
+
+// cachedSecret is a secret value read from its source, or the error reading it
+type cachedSecret struct {
+	value     []byte
+	err       error
+	expiresAt time.Time
+}
+
+// cache contains the secret values read by ReadCached, indexed by their source
+var cache = struct {
+	sync.Mutex
+	entries map[config.SecretSource]cachedSecret
+}{entries: make(map[config.SecretSource]cachedSecret)}
+
+// ReadCached returns the secret value from the given source like Read, but the value is cached for a few minutes
+// It is used on every incoming request, so they do not reach the Kubernetes API. The errors are cached too, for a
+// few seconds, so the requests cannot be used to flood the API with reads of a missing secret
+func ReadCached(source config.SecretSource) ([]byte, error) {
+	now := time.Now()
+	cache.Lock()
+	entry, ok := cache.entries[source]
+	cache.Unlock()
+	if ok && now.Before(entry.expiresAt) {
+		return entry.value, entry.err
+	}
+
+	value, err := Read(source)
+	entry = cachedSecret{value: value, err: err, expiresAt: now.Add(cacheTTL)}
+	if err != nil {
+		entry.expiresAt = now.Add(cacheErrorTTL)
+	}
+
+	cache.Lock()
+	cache.entries[source] = entry
+	cache.Unlock()
+
+	return value, err
+}
+
+// Invalidate removes the cached secret values, so they are read again. It is called when the configuration is
+// reloaded, as the sources or their values may have changed
+func Invalidate() {
+	cache.Lock()
+	defer cache.Unlock()
+	cache.entries = make(map[config.SecretSource]cachedSecret)
+}
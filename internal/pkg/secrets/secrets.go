@@ -0,0 +1,68 @@
+// Package secrets contains the functions to read secret values from local files or Kubernetes secrets.
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/sergiotejon/pipeManagerLauncher/internal/pkg/k8s"
+	"github.com/sergiotejon/pipeManagerLauncher/pkg/config"
+)
+
+// Read returns the secret value from the given source
+// It reads the file if it is set, otherwise the key of the Kubernetes secret
+// The namespace of the Kubernetes secret defaults to the launcher namespace or the current namespace of the pod
+// Trailing new lines are removed from the value, as they are usually added when creating the secret by hand
+func Read(source config.SecretSource) ([]byte, error) {
+	var value []byte
+	var err error
+
+	switch {
+	case source.File != "":
+		value, err = os.ReadFile(source.File)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read secret file %s: %w", source.File, err)
+		}
+	case source.Name != "" && source.Key != "":
+		value, err = readKubernetesSecret(source.Namespace, source.Name, source.Key)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errors.New("secret source must define a file or a Kubernetes secret name and key")
+	}
+
+	return []byte(strings.TrimRight(string(value), "\r\n")), nil
+}
+
+// readKubernetesSecret returns the value of the key inside the given Kubernetes secret
+func readKubernetesSecret(namespace, name, key string) ([]byte, error) {
+	client, err := k8s.GetKubernetesClient()
+	if err != nil {
+		return nil, err
+	}
+
+	if namespace == "" {
+		namespace = config.Launcher.Data.Namespace
+	}
+	if namespace == "" {
+		namespace, _ = k8s.GetCurrentNamespace()
+	}
+
+	secret, err := client.CoreV1().Secrets(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get secret %s from namespace %s: %w", name, namespace, err)
+	}
+
+	value, ok := secret.Data[key]
+	if !ok {
+		return nil, fmt.Errorf("key %s not found in secret %s from namespace %s", key, name, namespace)
+	}
+
+	return value, nil
+}
@@ -0,0 +1,63 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// CleanerStruct defines the cleaner configuration.
+// It captures the thresholds to delete the Pipeline resources and the namespaces managed by pipe-manager. The ages
+// are in seconds and zero disables the deletion by age.
+type CleanerStruct struct {
+	PipelineMaxAge  int64  `json:"pipelineMaxAge,omitempty"`                  // PipelineMaxAge is the age in seconds after which a pipeline is deleted
+	DeletedBranches bool   `json:"deletedBranches,omitempty"`                 // DeletedBranches deletes the pipelines whose branch no longer exists in the repository
+	RefParam        string `json:"refParam,omitempty" default:"VARIABLE-REF"` // RefParam is the parameter of the pipelines with the branch
+	NamespaceMaxAge int64  `json:"namespaceMaxAge,omitempty"`                 // NamespaceMaxAge is the age in seconds after which a namespace without pipelines is deleted
+	LauncherJobs    bool   `json:"launcherJobs,omitempty"`                    // LauncherJobs deletes the finished launcher jobs according to the launcher retention
+	Selector        string `json:"selector,omitempty"`                        // Selector is a label selector restricting the pipelines and namespaces to delete
+}
+
+// CleanerConfig defines the cleaner configuration.
+// It captures the cleaner configuration data from the root of the configuration file.
+type CleanerConfig struct {
+	Data CleanerStruct `json:"cleaner"` // Data is the cleaner configuration
+}
+
+var Cleaner CleanerConfig // Cleaner is the global cleaner configuration
+
+// LoadCleanerConfig loads the cleaner configuration from the given file.
+// It returns an error if the configuration cannot be loaded.
+// The configuration file is expected to be in YAML format if a file is provided.
+// The environment variables and --set flags override the values of the file (see SetOverrides).
+// The configuration is loaded into the global Cleaner variable.
+func LoadCleanerConfig(configFile string) error {
+	var cleaner CleanerConfig
+	err := loadConfig(configFile, "cleaner", &cleaner, func(file *FileConfig) {
+		cleaner = file.CleanerConfig
+	})
+	if err != nil {
+		return err
+	}
+
+	Cleaner = cleaner
+	return nil
+}
+
+// validate checks the cross-field rules of the cleaner configuration
+func (c *CleanerConfig) validate() error {
+	var errs []error
+
+	if c.Data.PipelineMaxAge < 0 {
+		errs = append(errs, errors.New("cleaner.pipelineMaxAge: must not be negative"))
+	}
+	if c.Data.NamespaceMaxAge < 0 {
+		errs = append(errs, errors.New("cleaner.namespaceMaxAge: must not be negative"))
+	}
+	if _, err := labels.Parse(c.Data.Selector); err != nil {
+		errs = append(errs, fmt.Errorf("cleaner.selector: %w", err))
+	}
+
+	return errors.Join(errs...)
+}
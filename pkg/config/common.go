@@ -4,9 +4,9 @@ package config
 // LogConfig defines the logging configuration.
 // It captures the logging level, file, and format.
 type LogConfig struct {
-	Level  string `json:"level"`  // Level is the logging level (e.g., "info")
-	File   string `json:"file"`   // File is the file to write the logs to
-	Format string `json:"format"` // Format is the log format (e.g., "json")
+	Level  string `json:"level" default:"info" enum:"debug,info,warn,error"` // Level is the logging level (e.g., "info")
+	File   string `json:"file" default:"stdout"`                             // File is the file to write the logs to
+	Format string `json:"format" default:"text" enum:"text,json"`            // Format is the log format (e.g., "json")
 }
 
 // CommonStruct defines the common configuration.
@@ -23,17 +23,18 @@ var Common CommonConfig // Common is the common configuration for all components
 
 // LoadCommonConfig loads the common configuration from the given file.
 // It returns an error if the configuration cannot be loaded.
-// The configuration file is expected to be in YAML format.
-// It allows to load from environment variables also.
+// The configuration file is expected to be in YAML format if a file is provided.
+// The environment variables and --set flags override the values of the file (see SetOverrides).
 // The configuration is loaded into the global Common variable.
 func LoadCommonConfig(configFile string) error {
-	if configFile == "" { // Load the configuration from the environment variables
-		loadConfigFromEnv(&Common, "COMMON")
-	} else { // Load the configuration from the file
-		err := loadConfigFromFile(configFile, &Common)
-		if err != nil {
-			return err
-		}
+	var common CommonConfig
+	err := loadConfig(configFile, "common", &common, func(file *FileConfig) {
+		common = file.CommonConfig
+	})
+	if err != nil {
+		return err
 	}
+
+	Common = common
 	return nil
 }
@@ -1,91 +1,316 @@
 package config
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"gopkg.in/yaml.v3"
 	"os"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
+
+	"gopkg.in/yaml.v3"
 )
 
-// loadConfigFromFile loads the configuration from the given file into the given configuration struct.
-func loadConfigFromFile(configFile string, config interface{}) error {
-	if configFile == "" {
-		return errors.New("the configuration file is not set")
+// FileConfig defines the configuration file.
+// It captures all the sections of the file, so unknown fields are detected in any of them. Each component only loads
+// the sections it uses.
+type FileConfig struct {
+	CommonConfig
+	WebhookConfig
+	LauncherConfig
+	CleanerConfig
+}
+
+// validator is implemented by the sections with cross-field rules
+type validator interface {
+	validate() error
+}
+
+// loadedSections are the names of the sections loaded by the component, printed by EffectiveConfig
+var loadedSections = make(map[string]bool)
+
+// loadConfig builds the configuration, copies the section with the given name from it with pick, and validates it.
+func loadConfig(configFile, name string, section interface{}, pick func(file *FileConfig)) error {
+	file, _, err := buildConfig(configFile)
+	if err != nil {
+		return err
 	}
+	pick(file)
 
+	if err := validateConfig(section); err != nil {
+		return fmt.Errorf("invalid configuration:\n%w", err)
+	}
+
+	loadedSections[name] = true
+	return nil
+}
+
+// buildConfig builds the configuration from its layers, each one overriding the previous: the defaults, the
+// configuration file (if provided), the environment variables and the --set flags.
+// It returns the names of the sections present in the file too.
+func buildConfig(configFile string) (*FileConfig, []string, error) {
+	file := &FileConfig{}
+	setDefaults(reflect.ValueOf(file).Elem())
+
+	var sections []string
+	if configFile != "" {
+		var err error
+		sections, err = readConfigFile(configFile, file)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if err := applyEnvOverrides(file); err != nil {
+		return nil, nil, err
+	}
+	if err := applyFlagOverrides(file); err != nil {
+		return nil, nil, err
+	}
+
+	return file, sections, nil
+}
+
+// readConfigFile reads the configuration file into the given FileConfig.
+// It returns the names of the sections present in the file.
+// It returns an error if the file cannot be read or it contains unknown fields.
+func readConfigFile(configFile string, file *FileConfig) ([]string, error) {
 	data, err := os.ReadFile(configFile)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	jsonData, err := convertToJson(data)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	if err := decodeStrict(jsonData, file); err != nil {
+		return nil, fmt.Errorf("%s: %w", configFile, err)
+	}
+
+	var root map[string]json.RawMessage
+	if err := json.Unmarshal(jsonData, &root); err != nil {
+		return nil, err
+	}
+	sections := make([]string, 0, len(root))
+	for name := range root {
+		sections = append(sections, name)
 	}
+	sort.Strings(sections)
+
+	return sections, nil
+}
 
-	err = json.Unmarshal(jsonData, config)
+// ValidateConfigFile checks the configuration file as the components do when they load it, including the
+// environment variables and --set flags that override it.
+// Every section present in the file is validated. It returns an error listing all the problems found.
+func ValidateConfigFile(configFile string) error {
+	file, sections, err := buildConfig(configFile)
 	if err != nil {
 		return err
 	}
+	if len(sections) == 0 {
+		return fmt.Errorf("%s: no configuration found", configFile)
+	}
 
+	var errs []error
+	for _, name := range sections {
+		switch name {
+		case "common":
+			errs = append(errs, validateConfig(&file.CommonConfig))
+		case "webhook":
+			errs = append(errs, validateConfig(&file.WebhookConfig))
+		case "launcher":
+			errs = append(errs, validateConfig(&file.LauncherConfig))
+		case "cleaner":
+			errs = append(errs, validateConfig(&file.CleanerConfig))
+		}
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("%s: invalid configuration:\n%w", configFile, err)
+	}
 	return nil
+}
+
+// decodeStrict decodes the JSON data into the given value, failing on unknown fields
+func decodeStrict(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(v)
+}
+
+// validateConfig checks the required fields and allowed values of a section, and its cross-field rules
+func validateConfig(section interface{}) error {
+	errs := validateFields(reflect.ValueOf(section).Elem(), "")
+	if v, ok := section.(validator); ok {
+		errs = append(errs, v.validate())
+	}
+
+	return errors.Join(errs...)
+}
+
+// validateFields checks the 'required' and 'enum' tags of the fields of the given struct and its nested structs.
+// The location is the path of the struct in the configuration file, used in the error messages.
+// Only the structs of this package are checked.
+func validateFields(v reflect.Value, location string) []error {
+	var errs []error
+
+	switch v.Kind() {
+	case reflect.Pointer:
+		if !v.IsNil() {
+			errs = append(errs, validateFields(v.Elem(), location)...)
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			errs = append(errs, validateFields(v.Index(i), fmt.Sprintf("%s[%d]", location, i))...)
+		}
+	case reflect.Map:
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+		for _, key := range keys {
+			errs = append(errs, validateFields(v.MapIndex(key), fmt.Sprintf("%s[%s]", location, key))...)
+		}
+	case reflect.Struct:
+		if v.Type().PkgPath() != configPkgPath {
+			return nil
+		}
+		for i := 0; i < v.NumField(); i++ {
+			fieldType := v.Type().Field(i)
+			name := jsonName(fieldType)
+			if name == "" {
+				continue
+			}
+			fieldLocation := name
+			if location != "" {
+				fieldLocation = location + "." + name
+			}
+
+			field := v.Field(i)
+			if fieldType.Tag.Get("required") == "true" && (field.IsZero() || (field.Kind() == reflect.Slice && field.Len() == 0)) {
+				errs = append(errs, fmt.Errorf("%s: required", fieldLocation))
+				continue
+			}
+			if enum := fieldType.Tag.Get("enum"); enum != "" && field.Kind() == reflect.String && field.String() != "" {
+				allowed := strings.Split(enum, ",")
+				if !contains(allowed, field.String()) {
+					errs = append(errs, fmt.Errorf("%s: invalid value '%s', allowed values are %s", fieldLocation, field.String(), strings.Join(allowed, ", ")))
+				}
+			}
+			errs = append(errs, validateFields(field, fieldLocation)...)
+		}
+	}
 
+	return errs
 }
 
-// loadConfigFromEnv loads the configuration from the environment variables into the given configuration struct.
-func loadConfigFromEnv(config interface{}, prefix string) {
-	v := reflect.ValueOf(config).Elem()
-	t := v.Type()
+// setDefaults sets the value of the 'default' tag to the zero fields of the given struct and its nested structs
+// It is applied before loading the configuration, so the values set explicitly are kept
+func setDefaults(v reflect.Value) {
+	if v.Kind() != reflect.Struct || v.Type().PkgPath() != configPkgPath {
+		return
+	}
 
 	for i := 0; i < v.NumField(); i++ {
 		field := v.Field(i)
-		fieldType := t.Field(i)
-		envKey := prefix + "_" + fieldType.Name
-
-		if field.Kind() == reflect.Struct {
-			loadConfigFromEnv(field.Addr().Interface(), envKey)
-		} else {
-			envValue := getEnv(strings.ToUpper(envKey), "")
-			if envValue != "" {
-				setField(field, envValue)
-			}
+		if def, ok := v.Type().Field(i).Tag.Lookup("default"); ok && field.IsZero() {
+			// The default values are defined in this package, so they are known to be valid
+			_ = setField(field, def)
 		}
+		setDefaults(field)
+	}
+}
+
+// jsonName returns the name of the field in the configuration file, or an empty string if it is not part of it
+// Embedded structs have no name, their fields are part of the parent struct
+func jsonName(field reflect.StructField) string {
+	if field.Anonymous || !field.IsExported() {
+		return ""
+	}
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "-" {
+		return ""
+	}
+	if name == "" {
+		return field.Name
 	}
+	return name
 }
 
-// getEnv returns the value of the environment variable if it exists, otherwise it returns the default value.
-func getEnv(key, defaultValue string) string {
-	if value, exists := os.LookupEnv(key); exists {
-		return value
+// contains returns true if the value is in the list
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
 	}
-	return defaultValue
+	return false
 }
 
 // setField sets the value of the field based on the field type.
-func setField(field reflect.Value, value string) {
+// Maps, structs and slices are parsed as YAML (e.g., "{key: value}" or "[a, b]"). Slices of strings can be written as
+// comma-separated values too (e.g., "a,b").
+// It returns an error if the value cannot be converted or the field type is not supported.
+func setField(field reflect.Value, value string) error {
 	switch field.Kind() {
 	case reflect.String:
 		field.SetString(value)
-	case reflect.Int:
-		if intValue, err := strconv.Atoi(value); err == nil {
-			field.SetInt(int64(intValue))
+	case reflect.Bool:
+		boolValue, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
 		}
-	case reflect.Map:
-		// Convert the string to a map
-		mapValue := map[string]string{}
-		if err := yaml.Unmarshal([]byte(value), &mapValue); err != nil {
-			panic(err)
+		field.SetBool(boolValue)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		intValue, err := strconv.ParseInt(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
 		}
-		field.Set(reflect.ValueOf(mapValue))
+		field.SetInt(intValue)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		uintValue, err := strconv.ParseUint(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetUint(uintValue)
+	case reflect.Float32, reflect.Float64:
+		floatValue, err := strconv.ParseFloat(value, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetFloat(floatValue)
+	case reflect.Slice:
+		if field.Type().Elem().Kind() == reflect.String && !strings.HasPrefix(strings.TrimSpace(value), "[") {
+			items := strings.Split(value, ",")
+			for i := range items {
+				items[i] = strings.TrimSpace(items[i])
+			}
+			field.Set(reflect.ValueOf(items).Convert(field.Type()))
+			return nil
+		}
+		fallthrough
+	case reflect.Map, reflect.Struct, reflect.Pointer:
+		jsonData, err := convertToJson([]byte(value))
+		if err != nil {
+			return err
+		}
+		newValue := reflect.New(field.Type())
+		if err := decodeStrict(jsonData, newValue.Interface()); err != nil {
+			return err
+		}
+		field.Set(newValue.Elem())
 	default:
-		panic("unhandled default case")
+		return fmt.Errorf("unsupported field type %s", field.Type())
 	}
+
+	return nil
 }
 
+// convertToJson converts the YAML data into JSON, so the configuration is decoded with the json tags of the structs
 func convertToJson(yamlData []byte) ([]byte, error) {
 	var data interface{}
 
@@ -104,6 +329,7 @@ func convertToJson(yamlData []byte) ([]byte, error) {
 	return jsonData, nil
 }
 
+// convertMapKeysToString converts the keys of the YAML maps into strings, as JSON only supports string keys
 func convertMapKeysToString(i interface{}) interface{} {
 	switch x := i.(type) {
 	case map[interface{}]interface{}:
@@ -112,6 +338,10 @@ func convertMapKeysToString(i interface{}) interface{} {
 			m[fmt.Sprintf("%v", k)] = convertMapKeysToString(v)
 		}
 		return m
+	case map[string]interface{}:
+		for k, v := range x {
+			x[k] = convertMapKeysToString(v)
+		}
 	case []interface{}:
 		for i, v := range x {
 			x[i] = convertMapKeysToString(v)
@@ -0,0 +1,231 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeConfig writes the given YAML to a configuration file in a temporary directory and returns its path
+func writeConfig(t *testing.T, data string) string {
+	t.Helper()
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(configFile, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return configFile
+}
+
+func TestBuildConfigDefaults(t *testing.T) {
+	file, sections, err := buildConfig("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sections) != 0 {
+		t.Errorf("sections = %v, want none", sections)
+	}
+
+	tests := []struct {
+		name string
+		got  interface{}
+		want interface{}
+	}{
+		{"common.log.level", file.CommonConfig.Data.Log.Level, "info"},
+		{"common.log.file", file.CommonConfig.Data.Log.File, "stdout"},
+		{"common.log.format", file.CommonConfig.Data.Log.Format, "text"},
+		{"launcher.pullPolicy", file.LauncherConfig.Data.PullPolicy, "IfNotPresent"},
+		{"launcher.jobNamePrefix", file.LauncherConfig.Data.JobNamePrefix, "pipeline-launcher"},
+		{"launcher.timeout", file.LauncherConfig.Data.Timeout, int64(600)},
+		{"webhook.workers", file.WebhookConfig.Data.Workers, 4},
+		{"webhook.queue.type", file.WebhookConfig.Data.Queue.Type, "memory"},
+		{"webhook.deduplication.ttl", file.WebhookConfig.Data.Deduplication.TTL, 3600},
+		{"cleaner.refParam", file.CleanerConfig.Data.RefParam, "VARIABLE-REF"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.got != tt.want {
+				t.Errorf("%s = %v, want %v", tt.name, tt.got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadConfigFile(t *testing.T) {
+	tests := []struct {
+		name         string
+		data         string
+		wantSections []string
+		wantErr      string
+	}{
+		{
+			name:         "sections",
+			data:         "launcher:\n  imageName: launcher\ncommon:\n  log:\n    level: debug\n",
+			wantSections: []string{"common", "launcher"},
+		},
+		{
+			name:    "unknown section",
+			data:    "launchr:\n  imageName: launcher\n",
+			wantErr: `unknown field "launchr"`,
+		},
+		{
+			name:    "unknown field",
+			data:    "launcher:\n  imageNam: launcher\n",
+			wantErr: `unknown field "imageNam"`,
+		},
+		{
+			name:    "unknown field of a variable",
+			data:    "webhook:\n  routes:\n    - name: github\n      events:\n        - variables:\n            REF:\n              expresion: data.body.ref\n",
+			wantErr: `unknown field "expresion"`,
+		},
+		{
+			name:    "wrong type",
+			data:    "launcher:\n  timeout: forever\n",
+			wantErr: "cannot unmarshal",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := &FileConfig{}
+			sections, err := readConfigFile(writeConfig(t, tt.data), file)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("readConfigFile() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Join(sections, ",") != strings.Join(tt.wantSections, ",") {
+				t.Errorf("readConfigFile() sections = %v, want %v", sections, tt.wantSections)
+			}
+		})
+	}
+}
+
+func TestValidateConfigFile(t *testing.T) {
+	const route = "webhook:\n  routes:\n    - name: github\n      path: /github\n      eventType: data.headers['X-Github-Event'][0]\n      events:\n        - type: push\n          repository: data.body.repository.clone_url\n"
+
+	tests := []struct {
+		name    string
+		data    string
+		wantErr []string
+	}{
+		{
+			name: "valid",
+			data: "launcher:\n  imageName: launcher\n" + route,
+		},
+		{
+			name:    "empty",
+			data:    "",
+			wantErr: []string{"no configuration found"},
+		},
+		{
+			name:    "required",
+			data:    "launcher:\n  namespace: pipelines\n",
+			wantErr: []string{"launcher.imageName"},
+		},
+		{
+			name:    "enum",
+			data:    "launcher:\n  imageName: launcher\n  pullPolicy: Sometimes\ncommon:\n  log:\n    format: xml\n",
+			wantErr: []string{"launcher.pullPolicy", "common.log.format"},
+		},
+		{
+			name:    "cross-field rules",
+			data:    "webhook:\n  workers: 0\n  queue:\n    type: bolt\n",
+			wantErr: []string{"webhook.workers", "webhook.queue.path"},
+		},
+		{
+			name:    "duplicated routes",
+			data:    route + "    - name: github\n      path: github\n      eventType: push\n      events: []\n",
+			wantErr: []string{"duplicated route name 'github'", "must start with '/'"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateConfigFile(writeConfig(t, tt.data))
+			if len(tt.wantErr) == 0 {
+				if err != nil {
+					t.Errorf("ValidateConfigFile() error = %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("ValidateConfigFile() error = nil, want %v", tt.wantErr)
+			}
+			for _, want := range tt.wantErr {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("ValidateConfigFile() error = %v, want %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestExpressionCompiler(t *testing.T) {
+	configFile := writeConfig(t, "webhook:\n  routes:\n    - name: github\n      path: /github\n      eventType: invalid\n      events:\n        - type: push\n          repository: data.body.repository.clone_url\n")
+
+	if err := ValidateConfigFile(configFile); err != nil {
+		t.Fatalf("ValidateConfigFile() without compiler error = %v", err)
+	}
+
+	SetExpressionCompiler(func(expression string) error {
+		if expression == "invalid" {
+			return errors.New("syntax error")
+		}
+		return nil
+	}, nil)
+	defer SetExpressionCompiler(nil, nil)
+
+	err := ValidateConfigFile(configFile)
+	if err == nil || !strings.Contains(err.Error(), "webhook.routes[github].eventType 'invalid': syntax error") {
+		t.Errorf("ValidateConfigFile() error = %v, want the invalid expression", err)
+	}
+}
+
+func TestLegacyEnvKey(t *testing.T) {
+	tests := []struct {
+		name      string
+		env       string
+		want      string
+		wantFound bool
+	}{
+		{"launcher field", "LAUNCHER_DATA_IMAGENAME", "LAUNCHER_IMAGE_NAME", true},
+		{"launcher nested field", "LAUNCHER_DATA_ARTIFACTSBUCKET_URL", "LAUNCHER_ARTIFACTS_BUCKET_URL", true},
+		{"common nested field", "COMMON_DATA_LOG_LEVEL", "COMMON_LOG_LEVEL", true},
+		{"unknown field", "LAUNCHER_DATA_UNKNOWN", "", false},
+		{"field of a scalar", "LAUNCHER_DATA_IMAGENAME_TAG", "", false},
+		{"new prefix", "PIPE_MANAGER_LAUNCHER_IMAGE_NAME", "", false},
+		{"other variable", "HOME", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, found := legacyEnvKey(tt.env)
+			if got != tt.want || found != tt.wantFound {
+				t.Errorf("legacyEnvKey(%s) = %q, %v, want %q, %v", tt.env, got, found, tt.want, tt.wantFound)
+			}
+		})
+	}
+}
+
+func TestLegacyEnvOverrides(t *testing.T) {
+	t.Setenv("LAUNCHER_DATA_IMAGENAME", "legacy")
+	t.Setenv("LAUNCHER_DATA_NAMESPACE", "legacy")
+	t.Setenv(EnvPrefix+"LAUNCHER_NAMESPACE", "pipelines")
+
+	file, _, err := buildConfig("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := file.LauncherConfig.Data.ImageName; got != "legacy" {
+		t.Errorf("imageName = %s, want the legacy variable", got)
+	}
+	if got := file.LauncherConfig.Data.Namespace; got != "pipelines" {
+		t.Errorf("namespace = %s, want the variable with the new prefix", got)
+	}
+}
@@ -1,7 +1,10 @@
 package config
 
 import (
+	"errors"
 	"fmt"
+	"net/url"
+
 	corev1 "k8s.io/api/core/v1"
 
 	"github.com/sergiotejon/pipeManagerLauncher/internal/pkg/version"
@@ -9,18 +12,60 @@ import (
 
 // LauncherStruct defines the launcher configuration.
 // It captures the image name, pull policy, tag, namespace, job name prefix, and timeout.
+// The pod template allows to customize the pods of the launcher jobs (e.g., resources, node selector, tolerations,
+// security context or service account).
 type LauncherStruct struct {
-	ImageName       string       `json:"imageName"`       // ImageName is the name of the Docker image to be used
-	PullPolicy      string       `json:"pullPolicy"`      // PullPolicy is the policy to use when pulling the image
-	Tag             string       `json:"tag"`             // Tag is the tag of the Docker image to be used
-	Namespace       string       `json:"namespace"`       // Namespace is the Kubernetes namespace to deploy the job
-	JobNamePrefix   string       `json:"jobNamePrefix"`   // JobNamePrefix is the prefix to use for the job name
-	Timeout         int64        `json:"timeout"`         // Timeout is the maximum time in seconds to wait for the job to complete
-	BackoffLimit    int32        `json:"backoffLimit"`    // BackoffLimit is the number of retries before considering the job as failed
-	ConfigmapName   string       `json:"configmapName"`   // ConfigmapName is the name of the ConfigMap to use
-	CloneDepth      int          `json:"cloneDepth"`      // CloneDepth is the depth to use when cloning the Git repository
-	RolesBinding    []string     `json:"rolesBinding"`    // RolesBinding is the list of roles to bind to the Service Account
-	ArtifactsBucket BucketConfig `json:"artifactsBucket"` // ArtifactsBucket is the bucket configuration for storing the artifacts
+	ImageName       string       `json:"imageName" required:"true"`                                          // ImageName is the name of the Docker image to be used
+	PullPolicy      string       `json:"pullPolicy" default:"IfNotPresent" enum:"Always,IfNotPresent,Never"` // PullPolicy is the policy to use when pulling the image
+	Tag             string       `json:"tag"`                                                                // Tag is the tag of the Docker image to be used
+	Namespace       string       `json:"namespace"`                                                          // Namespace is the Kubernetes namespace to deploy the job
+	JobNamePrefix   string       `json:"jobNamePrefix" default:"pipeline-launcher"`                          // JobNamePrefix is the prefix to use for the job name
+	Timeout         int64        `json:"timeout" default:"600"`                                              // Timeout is the maximum time in seconds to wait for the job to complete
+	BackoffLimit    int32        `json:"backoffLimit"`                                                       // BackoffLimit is the number of retries before considering the job as failed
+	ConfigmapName   string       `json:"configmapName"`                                                      // ConfigmapName is the name of the ConfigMap to use
+	CloneDepth      int          `json:"cloneDepth"`                                                         // CloneDepth is the depth to use when cloning the Git repository
+	SparseCheckout  bool         `json:"sparseCheckout"`                                                     // SparseCheckout fetches only the commit and checks out only the pipeline definitions
+	Submodules      bool         `json:"submodules"`                                                         // Submodules initializes and checks out the submodules recursively
+	LFS             LFS          `json:"lfs,omitempty"`                                                      // LFS is how the Git LFS objects are downloaded
+	RolesBinding    []string     `json:"rolesBinding"`                                                       // RolesBinding is the list of roles to bind to the Service Account
+	ArtifactsBucket BucketConfig `json:"artifactsBucket"`                                                    // ArtifactsBucket is the bucket configuration for storing the artifacts
+	Retention       Retention    `json:"retention,omitempty"`                                                // Retention is how long the finished jobs are kept
+	GitAuth         *GitAuth     `json:"gitAuth,omitempty"`                                                  // GitAuth is the authentication to clone the repositories
+
+	PodTemplate *corev1.PodTemplateSpec `json:"podTemplate,omitempty"` // PodTemplate is strategically merged over the pod template of the launcher jobs
+}
+
+// Retention defines how long the finished launcher jobs and their pods are kept.
+// The TTLs are counted from the end of the job, and zero keeps the jobs. Besides, only the last finished jobs of each
+// route are kept if KeepLast is set.
+type Retention struct {
+	SuccessfulTTL int32 `json:"successfulTTL,omitempty"` // SuccessfulTTL is the time in seconds a succeeded job is kept
+	FailedTTL     int32 `json:"failedTTL,omitempty"`     // FailedTTL is the time in seconds a failed job is kept
+	KeepLast      int   `json:"keepLast,omitempty"`      // KeepLast is the number of finished jobs kept for each route
+}
+
+// Git authentication types
+const (
+	GitAuthSSH       = "ssh"       // GitAuthSSH authenticates with a private key and verifies the host with known_hosts
+	GitAuthBasic     = "basic"     // GitAuthBasic authenticates over HTTPS with a username and a password
+	GitAuthToken     = "token"     // GitAuthToken authenticates over HTTPS with an access token
+	GitAuthGitHubApp = "githubApp" // GitAuthGitHubApp authenticates over HTTPS with an installation token of a GitHub App
+)
+
+// GitAuth defines how the launcher authenticates to clone the repository.
+// The credentials are never part of the configuration: the paths point to the files of the git secret of the route,
+// mounted in the launcher container. Relative paths are resolved from the mount directory of the secret.
+type GitAuth struct {
+	Type           string `json:"type" required:"true" enum:"ssh,basic,token,githubApp"` // Type is "ssh", "basic", "token" or "githubApp"
+	Username       string `json:"username,omitempty"`                                    // Username of the basic and token authentications (e.g., "oauth2" for GitLab)
+	PasswordFile   string `json:"passwordFile,omitempty"`                                // PasswordFile is the file with the password of the basic authentication
+	TokenFile      string `json:"tokenFile,omitempty"`                                   // TokenFile is the file with the access token of the token authentication
+	SSHKeyFile     string `json:"sshKeyFile,omitempty"`                                  // SSHKeyFile is the file with the SSH private key. Defaults to "id_rsa"
+	KnownHostsFile string `json:"knownHostsFile,omitempty"`                              // KnownHostsFile is the known_hosts file to verify the SSH host. Defaults to "known_hosts"
+	AppID          int64  `json:"appID,omitempty"`                                       // AppID is the ID of the GitHub App
+	InstallationID int64  `json:"installationID,omitempty"`                              // InstallationID of the GitHub App. Looked up from the repository if not set
+	PrivateKeyFile string `json:"privateKeyFile,omitempty"`                              // PrivateKeyFile is the file with the private key of the GitHub App
+	APIURL         string `json:"apiURL,omitempty"`                                      // APIURL is the base URL of the GitHub API. Defaults to "https://api.github.com"
 }
 
 // BucketConfig defines the bucket configuration.
@@ -53,21 +98,137 @@ var Launcher LauncherConfig // Launcher is the global launcher configuration
 // LoadLauncherConfig loads the launcher configuration from the given file.
 // It returns an error if the configuration cannot be loaded.
 // The configuration file is expected to be in YAML format if a file is provided.
-// If the configuration file is not provided, the configuration is loaded from the environment variables.
+// The environment variables and --set flags override the values of the file (see SetOverrides).
 // The configuration loads into the global Launcher variable.
 func LoadLauncherConfig(configFile string) error {
-	if configFile == "" { // Load the configuration from the environment variables
-		loadConfigFromEnv(&Launcher, "LAUNCHER")
-	} else { // Load the configuration from the file
-		err := loadConfigFromFile(configFile, &Launcher)
-		if err != nil {
-			return err
-		}
+	var launcher LauncherConfig
+	err := loadConfig(configFile, "launcher", &launcher, func(file *FileConfig) {
+		launcher = file.LauncherConfig
+	})
+	if err != nil {
+		return err
 	}
 
+	Launcher = launcher
 	return nil
 }
 
+// LFS defines how the Git LFS objects of the repositories are downloaded.
+// The pointer files of the checkout are replaced with their objects by git-lfs. If the endpoint is not set, git-lfs
+// reads it from the .lfsconfig of the repository or derives it from the URL of the repository.
+type LFS struct {
+	Enabled  bool   `json:"enabled"`            // Enabled downloads the LFS objects of the checkout
+	Endpoint string `json:"endpoint,omitempty"` // Endpoint is the URL of the LFS server (e.g., https://github.com/owner/name.git/info/lfs)
+}
+
+// validate checks the cross-field rules of the launcher configuration
+func (l *LauncherConfig) validate() error {
+	var errs []error
+
+	if l.Data.Timeout <= 0 {
+		errs = append(errs, errors.New("launcher.timeout: must be greater than 0"))
+	}
+	if l.Data.BackoffLimit < 0 {
+		errs = append(errs, errors.New("launcher.backoffLimit: must not be negative"))
+	}
+	if l.Data.CloneDepth < 0 {
+		errs = append(errs, errors.New("launcher.cloneDepth: must not be negative"))
+	}
+
+	bucket := l.Data.ArtifactsBucket
+	bucketConfigured := bucket.BasePath != "" || bucket.SecretName != "" || len(bucket.Parameters) > 0 ||
+		len(bucket.Credentials.Env) > 0 || len(bucket.Credentials.Volumes) > 0 || len(bucket.Credentials.VolumeMounts) > 0
+	if bucket.URL == "" && bucketConfigured {
+		errs = append(errs, errors.New("launcher.artifactsBucket.url: required when the artifacts bucket is configured"))
+	}
+
+	errs = append(errs, validatePodTemplate("launcher.podTemplate", l.Data.PodTemplate))
+	errs = append(errs, l.Data.Retention.validate("launcher.retention"))
+	errs = append(errs, l.Data.LFS.validate("launcher.lfs"))
+	if l.Data.GitAuth != nil {
+		errs = append(errs, l.Data.GitAuth.validate("launcher.gitAuth"))
+	}
+
+	return errors.Join(errs...)
+}
+
+// validate checks the retention values are not negative
+func (r *Retention) validate(location string) error {
+	var errs []error
+	if r.SuccessfulTTL < 0 {
+		errs = append(errs, fmt.Errorf("%s.successfulTTL: must not be negative", location))
+	}
+	if r.FailedTTL < 0 {
+		errs = append(errs, fmt.Errorf("%s.failedTTL: must not be negative", location))
+	}
+	if r.KeepLast < 0 {
+		errs = append(errs, fmt.Errorf("%s.keepLast: must not be negative", location))
+	}
+	return errors.Join(errs...)
+}
+
+// validate checks the endpoint of the LFS server is an HTTP URL
+func (l *LFS) validate(location string) error {
+	if l.Endpoint == "" {
+		return nil
+	}
+	endpoint, err := url.Parse(l.Endpoint)
+	if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+		return fmt.Errorf("%s.endpoint: must be an http or https URL", location)
+	}
+	return nil
+}
+
+// validate checks the fields required by the type of the git authentication are set
+func (g *GitAuth) validate(location string) error {
+	var errs []error
+	switch g.Type {
+	case GitAuthBasic:
+		if g.Username == "" {
+			errs = append(errs, fmt.Errorf("%s.username: required by the basic authentication", location))
+		}
+		if g.PasswordFile == "" {
+			errs = append(errs, fmt.Errorf("%s.passwordFile: required by the basic authentication", location))
+		}
+	case GitAuthToken:
+		if g.TokenFile == "" {
+			errs = append(errs, fmt.Errorf("%s.tokenFile: required by the token authentication", location))
+		}
+	case GitAuthGitHubApp:
+		if g.AppID <= 0 {
+			errs = append(errs, fmt.Errorf("%s.appID: required by the githubApp authentication", location))
+		}
+		if g.InstallationID < 0 {
+			errs = append(errs, fmt.Errorf("%s.installationID: must not be negative", location))
+		}
+		if g.PrivateKeyFile == "" {
+			errs = append(errs, fmt.Errorf("%s.privateKeyFile: required by the githubApp authentication", location))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// validatePodTemplate checks the pod template can be used by the pods of a Kubernetes Job
+func validatePodTemplate(location string, template *corev1.PodTemplateSpec) error {
+	if template == nil {
+		return nil
+	}
+
+	var errs []error
+	switch template.Spec.RestartPolicy {
+	case "", corev1.RestartPolicyNever, corev1.RestartPolicyOnFailure:
+	default:
+		errs = append(errs, fmt.Errorf("%s.spec.restartPolicy: must be 'Never' or 'OnFailure' for a job", location))
+	}
+	for i, container := range template.Spec.Containers {
+		if container.Name == "" {
+			errs = append(errs, fmt.Errorf("%s.spec.containers[%d].name: required to merge the container", location, i))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
 // GetLauncherImage returns the image name and tag for the launcher image if format "name:tag"
 func (l *LauncherStruct) GetLauncherImage() string {
 	return fmt.Sprintf("%s:%s", l.ImageName, func() string {
@@ -0,0 +1,400 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EnvPrefix is the prefix of the environment variables that override the configuration.
+// The rest of the name is the path of the field in upper snake case, with the indexes of the lists as numbers
+// (e.g., PIPE_MANAGER_LAUNCHER_IMAGE_NAME or PIPE_MANAGER_WEBHOOK_ROUTES_0_PATH).
+const EnvPrefix = "PIPE_MANAGER_"
+
+// redacted replaces the secret values in the effective configuration
+const redacted = "<redacted>"
+
+// flagOverrides are the values given with the --set flags, indexed by key
+var flagOverrides map[string]string
+
+// sensitiveWords identify the bucket parameters whose values are redacted in the effective configuration
+var sensitiveWords = []string{"password", "secret", "token", "credential", "accesskey", "privatekey"}
+
+// SetOverrides sets the values given with the --set flags. They are applied over the configuration file and the
+// environment variables when the configuration is loaded.
+// Each value has the format "path=value", where the path is the path of the field in the configuration file with the
+// indexes of the lists in brackets or as another element (e.g., "launcher.imageName=launcher" or
+// "webhook.routes[0].path=/github"). Lists, maps and structs can be set as a whole with a YAML value.
+// It returns an error if a value does not have the expected format.
+func SetOverrides(values []string) error {
+	overrides := make(map[string]string)
+	for _, value := range values {
+		path, fieldValue, found := strings.Cut(value, "=")
+		if !found || strings.TrimSpace(path) == "" {
+			return fmt.Errorf("invalid override '%s', expected 'path=value'", value)
+		}
+		overrides[pathToKey(strings.TrimSpace(path))] = fieldValue
+	}
+
+	flagOverrides = overrides
+	return nil
+}
+
+// legacyEnvSections are the prefixes of the environment variables of the previous format, named after the Go fields
+// of the common and launcher sections (e.g., LAUNCHER_DATA_IMAGENAME), and the keys of the sections they override
+var legacyEnvSections = []struct {
+	prefix  string
+	key     string
+	section reflect.Type
+}{
+	{"COMMON_DATA_", "COMMON", reflect.TypeOf(CommonStruct{})},
+	{"LAUNCHER_DATA_", "LAUNCHER", reflect.TypeOf(LauncherStruct{})},
+}
+
+// warnedEnv are the deprecated environment variables already warned about, as each component loads several sections
+var warnedEnv sync.Map
+
+// applyEnvOverrides sets the values of the environment variables with the EnvPrefix to the configuration
+// The variables of the previous format are still applied, with a deprecation warning, unless the same field is set
+// with the EnvPrefix. Empty variables and variables that do not match any field are ignored
+func applyEnvOverrides(file *FileConfig) error {
+	overrides := make(map[string]string)
+	legacy := make(map[string]string)
+	for _, env := range os.Environ() {
+		name, value, _ := strings.Cut(env, "=")
+		if value == "" {
+			continue
+		}
+		if key, found := strings.CutPrefix(name, EnvPrefix); found {
+			overrides[key] = value
+			continue
+		}
+		if key, found := legacyEnvKey(name); found {
+			if _, warned := warnedEnv.LoadOrStore(name, true); !warned {
+				log.Printf("Warning: the environment variable %s is deprecated, use %s%s instead", name, EnvPrefix, key)
+			}
+			legacy[key] = value
+		}
+	}
+	for key, value := range legacy {
+		if _, ok := overrides[key]; !ok {
+			overrides[key] = value
+		}
+	}
+
+	return applyOverrides(reflect.ValueOf(file).Elem(), "", overrides, make(map[string]bool))
+}
+
+// legacyEnvKey returns the override key of an environment variable of the previous format, where each part of the
+// name is the upper case name of a Go field (e.g., LAUNCHER_DATA_ARTIFACTSBUCKET_URL is LAUNCHER_ARTIFACTS_BUCKET_URL)
+// It returns false if the variable does not have the previous format or does not match any field
+func legacyEnvKey(name string) (string, bool) {
+	for _, legacySection := range legacyEnvSections {
+		rest, found := strings.CutPrefix(name, legacySection.prefix)
+		if !found {
+			continue
+		}
+		key := legacySection.key
+		t := legacySection.section
+		for _, part := range strings.Split(rest, "_") {
+			if t.Kind() != reflect.Struct {
+				return "", false
+			}
+			field, ok := t.FieldByNameFunc(func(fieldName string) bool { return strings.ToUpper(fieldName) == part })
+			if !ok || jsonName(field) == "" {
+				return "", false
+			}
+			key += "_" + toUpperSnake(jsonName(field))
+			t = field.Type
+		}
+		return key, true
+	}
+	return "", false
+}
+
+// applyFlagOverrides sets the values of the --set flags to the configuration
+// It returns an error if a flag does not match any field
+func applyFlagOverrides(file *FileConfig) error {
+	used := make(map[string]bool)
+	if err := applyOverrides(reflect.ValueOf(file).Elem(), "", flagOverrides, used); err != nil {
+		return err
+	}
+
+	var unknown []string
+	for key := range flagOverrides {
+		if !used[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("unknown configuration fields in --set flags: %s", strings.Join(unknown, ", "))
+	}
+
+	return nil
+}
+
+// applyOverrides sets the override of the given key to the value, if any, and the overrides of its nested fields
+// The keys of the used overrides are added to used
+func applyOverrides(v reflect.Value, key string, overrides map[string]string, used map[string]bool) error {
+	if value, ok := overrides[key]; ok && key != "" {
+		used[key] = true
+		if err := setField(v, value); err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+	}
+
+	return applyNestedOverrides(v, key, overrides, used)
+}
+
+// applyNestedOverrides sets the overrides of the fields and elements nested in the given value
+// Structs and lists of this package and the existing entries of the maps are walked. New map entries and external
+// types can only be set as a whole
+func applyNestedOverrides(v reflect.Value, key string, overrides map[string]string, used map[string]bool) error {
+	prefix := ""
+	if key != "" {
+		prefix = key + "_"
+	}
+	if !hasKeyWithPrefix(overrides, prefix) {
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.Type().Elem().Kind() != reflect.Struct || v.Type().Elem().PkgPath() != configPkgPath {
+			return nil
+		}
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+			setDefaults(v.Elem())
+		}
+		return applyNestedOverrides(v.Elem(), key, overrides, used)
+	case reflect.Struct:
+		if v.Type().PkgPath() != configPkgPath {
+			return nil
+		}
+		var errs []error
+		for i := 0; i < v.NumField(); i++ {
+			fieldType := v.Type().Field(i)
+			if fieldType.Anonymous {
+				errs = append(errs, applyNestedOverrides(v.Field(i), key, overrides, used))
+				continue
+			}
+			name := jsonName(fieldType)
+			if name == "" {
+				continue
+			}
+			errs = append(errs, applyOverrides(v.Field(i), prefix+toUpperSnake(name), overrides, used))
+		}
+		return errors.Join(errs...)
+	case reflect.Slice:
+		indexes, err := overrideIndexes(overrides, prefix, v.Len())
+		if err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+		if len(indexes) == 0 {
+			return nil
+		}
+		if size := indexes[len(indexes)-1] + 1; size > v.Len() {
+			grown := reflect.MakeSlice(v.Type(), size, size)
+			reflect.Copy(grown, v)
+			for i := v.Len(); i < size; i++ {
+				setDefaults(grown.Index(i))
+			}
+			v.Set(grown)
+		}
+		var errs []error
+		for _, i := range indexes {
+			errs = append(errs, applyOverrides(v.Index(i), prefix+strconv.Itoa(i), overrides, used))
+		}
+		return errors.Join(errs...)
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return nil
+		}
+		var errs []error
+		for _, mapKey := range v.MapKeys() {
+			entryKey := prefix + toUpperSnake(mapKey.String())
+			if _, ok := overrides[entryKey]; !ok && !hasKeyWithPrefix(overrides, entryKey+"_") {
+				continue
+			}
+			// The map entries are not addressable, so the override is applied to a copy
+			entry := reflect.New(v.Type().Elem()).Elem()
+			entry.Set(v.MapIndex(mapKey))
+			errs = append(errs, applyOverrides(entry, entryKey, overrides, used))
+			v.SetMapIndex(mapKey, entry)
+		}
+		return errors.Join(errs...)
+	}
+
+	return nil
+}
+
+// overrideIndexes returns the sorted indexes of the list elements with overrides under the given prefix
+// It returns an error if the new elements would leave a gap after the current length of the list
+func overrideIndexes(overrides map[string]string, prefix string, length int) ([]int, error) {
+	seen := make(map[int]bool)
+	for key := range overrides {
+		rest, found := strings.CutPrefix(key, prefix)
+		if !found {
+			continue
+		}
+		indexValue, _, _ := strings.Cut(rest, "_")
+		if index, err := strconv.Atoi(indexValue); err == nil && index >= 0 {
+			seen[index] = true
+		}
+	}
+
+	indexes := make([]int, 0, len(seen))
+	for index := range seen {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+
+	next := length
+	for _, index := range indexes {
+		if index < length {
+			continue
+		}
+		if index != next {
+			return nil, fmt.Errorf("index %d leaves a gap after the %d elements of the list", index, next)
+		}
+		next++
+	}
+
+	return indexes, nil
+}
+
+// hasKeyWithPrefix returns true if any override key starts with the prefix
+func hasKeyWithPrefix(overrides map[string]string, prefix string) bool {
+	for key := range overrides {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// pathToKey converts the path of a field (e.g., "webhook.routes[0].eventType") into its override key
+// (e.g., "WEBHOOK_ROUTES_0_EVENT_TYPE")
+func pathToKey(path string) string {
+	path = strings.NewReplacer("[", ".", "]", "").Replace(path)
+	parts := strings.Split(path, ".")
+	for i, part := range parts {
+		parts[i] = toUpperSnake(part)
+	}
+	return strings.Join(parts, "_")
+}
+
+// toUpperSnake converts a camel case name into upper snake case (e.g., "deliveryID" -> "DELIVERY_ID")
+func toUpperSnake(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) {
+			previous := runes[i-1]
+			nextIsLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(previous) || unicode.IsDigit(previous) || (unicode.IsUpper(previous) && nextIsLower) {
+				b.WriteRune('_')
+			}
+		}
+		b.WriteRune(unicode.ToUpper(r))
+	}
+	return b.String()
+}
+
+// EffectiveConfig returns the sections of the configuration loaded by the component in YAML format, with the
+// defaults, environment variables and --set flags applied.
+// The values of the environment variables of the containers and the sensitive bucket parameters are redacted.
+func EffectiveConfig() ([]byte, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	sections := map[string]interface{}{
+		"common":   Common.Data,
+		"webhook":  Webhook.Data,
+		"launcher": Launcher.Data,
+		"cleaner":  Cleaner.Data,
+	}
+
+	root := make(map[string]interface{})
+	for name, section := range sections {
+		if !loadedSections[name] {
+			continue
+		}
+		jsonData, err := json.Marshal(section)
+		if err != nil {
+			return nil, err
+		}
+		var data interface{}
+		if err := json.Unmarshal(jsonData, &data); err != nil {
+			return nil, err
+		}
+		redact(data)
+		root[name] = data
+	}
+
+	var out bytes.Buffer
+	encoder := yaml.NewEncoder(&out)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(root); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// redact replaces the secret values of the configuration in its generic form
+// The secrets can be in the value of the environment variables of the containers (e.g., the bucket credentials) and
+// in the parameters of the bucket
+func redact(data interface{}) {
+	switch x := data.(type) {
+	case map[string]interface{}:
+		for key, value := range x {
+			switch key {
+			case "env":
+				if envVars, ok := value.([]interface{}); ok {
+					for _, envVar := range envVars {
+						if m, ok := envVar.(map[string]interface{}); ok && m["value"] != nil && m["value"] != "" {
+							m["value"] = redacted
+						}
+					}
+				}
+			case "parameters":
+				if parameters, ok := value.(map[string]interface{}); ok {
+					for name := range parameters {
+						if isSensitive(name) {
+							parameters[name] = redacted
+						}
+					}
+				}
+			}
+			redact(value)
+		}
+	case []interface{}:
+		for _, item := range x {
+			redact(item)
+		}
+	}
+}
+
+// isSensitive returns true if the name of a parameter suggests its value is a secret
+func isSensitive(name string) bool {
+	name = strings.ToLower(strings.NewReplacer("_", "", "-", "").Replace(name))
+	for _, word := range sensitiveWords {
+		if strings.Contains(name, word) {
+			return true
+		}
+	}
+	return false
+}
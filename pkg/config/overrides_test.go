@@ -0,0 +1,160 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPathToKey(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"launcher.imageName", "LAUNCHER_IMAGE_NAME"},
+		{"webhook.routes[0].eventType", "WEBHOOK_ROUTES_0_EVENT_TYPE"},
+		{"webhook.routes.1.path", "WEBHOOK_ROUTES_1_PATH"},
+		{"webhook.routes[0].deliveryID", "WEBHOOK_ROUTES_0_DELIVERY_ID"},
+		{"launcher.artifactsBucket.url", "LAUNCHER_ARTIFACTS_BUCKET_URL"},
+		{"launcher.gitAuth.sshKeyFile", "LAUNCHER_GIT_AUTH_SSH_KEY_FILE"},
+		{"webhook.admin.token.file", "WEBHOOK_ADMIN_TOKEN_FILE"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := pathToKey(tt.path); got != tt.want {
+				t.Errorf("pathToKey(%s) = %s, want %s", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestToUpperSnake(t *testing.T) {
+	tests := map[string]string{
+		"imageName":   "IMAGE_NAME",
+		"deliveryID":  "DELIVERY_ID",
+		"TTL":         "TTL",
+		"s3Bucket":    "S3_BUCKET",
+		"HTTPTimeout": "HTTP_TIMEOUT",
+		"level":       "LEVEL",
+	}
+
+	for name, want := range tests {
+		if got := toUpperSnake(name); got != want {
+			t.Errorf("toUpperSnake(%s) = %s, want %s", name, got, want)
+		}
+	}
+}
+
+func TestEnvOverrides(t *testing.T) {
+	configFile := writeConfig(t, "launcher:\n  imageName: launcher\n  namespace: pipelines\n"+
+		"webhook:\n  routes:\n    - name: github\n      path: /github\n      eventType: push\n      events: []\n")
+	t.Setenv(EnvPrefix+"LAUNCHER_NAMESPACE", "other")
+	t.Setenv(EnvPrefix+"LAUNCHER_TIMEOUT", "60")
+	t.Setenv(EnvPrefix+"LAUNCHER_SUBMODULES", "true")
+	t.Setenv(EnvPrefix+"WEBHOOK_ROUTES_0_PATH", "/gh")
+	t.Setenv(EnvPrefix+"WEBHOOK_ROUTES_1_NAME", "gitlab")
+	t.Setenv(EnvPrefix+"WEBHOOK_QUEUE_TYPE", "")
+	t.Setenv(EnvPrefix+"UNKNOWN_FIELD", "ignored")
+
+	file, _, err := buildConfig(configFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	launcher := file.LauncherConfig.Data
+	if launcher.ImageName != "launcher" || launcher.Namespace != "other" || launcher.Timeout != 60 || !launcher.Submodules {
+		t.Errorf("launcher = %+v, want the file values overridden by the environment", launcher)
+	}
+	routes := file.WebhookConfig.Data.Routes
+	if len(routes) != 2 || routes[0].Path != "/gh" || routes[0].Name != "github" || routes[1].Name != "gitlab" {
+		t.Errorf("routes = %+v, want the first path overridden and a second route", routes)
+	}
+	if got := file.WebhookConfig.Data.Queue.Type; got != "memory" {
+		t.Errorf("queue type = %s, want the default as the empty variables are ignored", got)
+	}
+}
+
+func TestFlagOverrides(t *testing.T) {
+	configFile := writeConfig(t, "launcher:\n  imageName: launcher\n  namespace: pipelines\n")
+	t.Setenv(EnvPrefix+"LAUNCHER_NAMESPACE", "env")
+	defer func() { flagOverrides = nil }()
+
+	tests := []struct {
+		name    string
+		values  []string
+		check   func(file *FileConfig) bool
+		wantErr string
+	}{
+		{
+			name:   "flag over environment",
+			values: []string{"launcher.namespace=flag"},
+			check:  func(file *FileConfig) bool { return file.LauncherConfig.Data.Namespace == "flag" },
+		},
+		{
+			name:   "value with equal sign",
+			values: []string{"cleaner.selector=team=ci"},
+			check:  func(file *FileConfig) bool { return file.CleanerConfig.Data.Selector == "team=ci" },
+		},
+		{
+			name:   "list as YAML",
+			values: []string{"launcher.rolesBinding=[view, edit]"},
+			check: func(file *FileConfig) bool {
+				return strings.Join(file.LauncherConfig.Data.RolesBinding, ",") == "view,edit"
+			},
+		},
+		{
+			name:   "pointer struct",
+			values: []string{"launcher.gitAuth.type=token", "launcher.gitAuth.tokenFile=token"},
+			check: func(file *FileConfig) bool {
+				gitAuth := file.LauncherConfig.Data.GitAuth
+				return gitAuth != nil && gitAuth.Type == "token" && gitAuth.TokenFile == "token"
+			},
+		},
+		{
+			name:    "unknown field",
+			values:  []string{"launcher.imageNam=launcher"},
+			wantErr: "unknown configuration fields in --set flags: LAUNCHER_IMAGE_NAM",
+		},
+		{
+			name:    "invalid value",
+			values:  []string{"launcher.timeout=forever"},
+			wantErr: "LAUNCHER_TIMEOUT",
+		},
+		{
+			name:    "gap in a list",
+			values:  []string{"webhook.routes[2].name=github"},
+			wantErr: "index 2 leaves a gap",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := SetOverrides(tt.values); err != nil {
+				t.Fatal(err)
+			}
+			file, _, err := buildConfig(configFile)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("buildConfig() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !tt.check(file) {
+				t.Errorf("buildConfig() = %+v, want the --set values applied", file.LauncherConfig.Data)
+			}
+		})
+	}
+}
+
+func TestSetOverridesFormat(t *testing.T) {
+	defer func() { flagOverrides = nil }()
+
+	for _, value := range []string{"launcher.imageName", "=launcher", " =launcher"} {
+		if err := SetOverrides([]string{value}); err == nil {
+			t.Errorf("SetOverrides(%q) error = nil, want an error", value)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// mu protects the global configuration while it is reloaded
+var mu sync.RWMutex
+
+// RLock locks the global configuration for reading, so it is not swapped by ReloadConfig while it is being used.
+// It must be released with RUnlock.
+func RLock() {
+	mu.RLock()
+}
+
+// RUnlock releases the read lock of the global configuration.
+func RUnlock() {
+	mu.RUnlock()
+}
+
+// ReloadDiff describes the changes applied by ReloadConfig.
+// It captures the names of the added, removed and changed routes, and the settings that changed but are not applied
+// until the process restarts.
+type ReloadDiff struct {
+	Added   []string // Added are the names of the new routes
+	Removed []string // Removed are the names of the routes no longer configured
+	Changed []string // Changed are the names of the routes whose definition changed
+	Ignored []string // Ignored are the settings that changed but require a restart to be applied
+}
+
+// ReloadConfig builds the webhook and launcher configuration from the given file, the environment variables and the
+// --set flags and, only if it is valid, swaps the routes, the de-duplication settings and the launcher configuration
+// of the global variables.
+// It returns an error and keeps the current configuration if the new one cannot be loaded.
+// The compiled CEL expressions are rebuilt with the new routes (see SetExpressionCompiler).
+// The workers, processing mode, queue and administration settings are not reloaded. They are listed as ignored in the
+// returned diff if they changed.
+func ReloadConfig(configFile string) (ReloadDiff, error) {
+	var diff ReloadDiff
+
+	file, _, err := buildConfig(configFile)
+	if err != nil {
+		return diff, err
+	}
+	webhook, launcher := file.WebhookConfig, file.LauncherConfig
+	if err := errors.Join(validateConfig(&webhook), validateConfig(&launcher)); err != nil {
+		return diff, fmt.Errorf("invalid configuration:\n%w", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	diff = diffRoutes(Webhook.Data.Routes, webhook.Data.Routes)
+	ignored := map[string]bool{
+		"workers": Webhook.Data.Workers != webhook.Data.Workers,
+		"async":   Webhook.Data.Async != webhook.Data.Async,
+		"queue":   Webhook.Data.Queue != webhook.Data.Queue,
+		"admin":   !reflect.DeepEqual(Webhook.Data.Admin, webhook.Data.Admin),
+	}
+	for setting, changed := range ignored {
+		if changed {
+			diff.Ignored = append(diff.Ignored, setting)
+		}
+	}
+	sort.Strings(diff.Ignored)
+
+	Webhook.Data.Routes = webhook.Data.Routes
+	Webhook.Data.Deduplication = webhook.Data.Deduplication
+	Launcher = launcher
+
+	// The compiled expressions are rebuilt with the ones of the new routes, so the expressions no longer configured are
+	// released. They were compiled by the validation, so it does not fail
+	if expressionReset != nil {
+		expressionReset()
+		_ = webhook.Data.compileExpressions()
+	}
+
+	return diff, nil
+}
+
+// diffRoutes compares the routes by name and returns the added, removed and changed ones
+func diffRoutes(current, next []Route) ReloadDiff {
+	var diff ReloadDiff
+
+	currentRoutes := make(map[string]Route)
+	for _, route := range current {
+		currentRoutes[route.Name] = route
+	}
+
+	for _, route := range next {
+		old, ok := currentRoutes[route.Name]
+		switch {
+		case !ok:
+			diff.Added = append(diff.Added, route.Name)
+		case !reflect.DeepEqual(old, route):
+			diff.Changed = append(diff.Changed, route.Name)
+		}
+		delete(currentRoutes, route.Name)
+	}
+	for name := range currentRoutes {
+		diff.Removed = append(diff.Removed, name)
+	}
+	sort.Strings(diff.Removed)
+
+	return diff
+}
@@ -0,0 +1,160 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// reloadConfigData returns a configuration file with the given launcher image, workers, de-duplication TTL and routes
+// Each route is a name and a path
+func reloadConfigData(image string, workers, ttl int, routes ...[2]string) string {
+	var data strings.Builder
+	fmt.Fprintf(&data, "launcher:\n  imageName: %s\n", image)
+	fmt.Fprintf(&data, "webhook:\n  workers: %d\n  deduplication:\n    ttl: %d\n  routes:\n", workers, ttl)
+	for _, route := range routes {
+		fmt.Fprintf(&data, "    - name: %s\n      path: %s\n      eventType: data.headers['X-Github-Event'][0]\n"+
+			"      events:\n        - type: push\n          repository: data.body.repository.clone_url\n", route[0], route[1])
+	}
+	return data.String()
+}
+
+// routeNames returns the names of the routes of the global webhook configuration
+func routeNames() string {
+	names := make([]string, 0, len(Webhook.Data.Routes))
+	for _, route := range Webhook.Data.Routes {
+		names = append(names, route.Name)
+	}
+	return strings.Join(names, ",")
+}
+
+func TestReloadConfig(t *testing.T) {
+	initial := writeConfig(t, reloadConfigData("launcher:v1", 4, 3600, [2]string{"github", "/github"}, [2]string{"gitlab", "/gitlab"}))
+	if err := LoadWebhookConfig(initial); err != nil {
+		t.Fatal(err)
+	}
+	if err := LoadLauncherConfig(initial); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		Webhook = WebhookConfig{}
+		Launcher = LauncherConfig{}
+	})
+
+	steps := []struct {
+		name        string
+		data        string
+		wantErr     bool
+		wantAdded   string
+		wantRemoved string
+		wantChanged string
+		wantIgnored string
+		wantRoutes  string
+		wantImage   string
+		wantTTL     int
+		wantWorkers int
+	}{
+		{
+			name:        "routes, de-duplication and launcher swapped",
+			data:        reloadConfigData("launcher:v2", 4, 60, [2]string{"github", "/github/v2"}, [2]string{"bitbucket", "/bitbucket"}),
+			wantAdded:   "bitbucket",
+			wantRemoved: "gitlab",
+			wantChanged: "github",
+			wantRoutes:  "github,bitbucket",
+			wantImage:   "launcher:v2",
+			wantTTL:     60,
+			wantWorkers: 4,
+		},
+		{
+			name:        "invalid configuration keeps the current one",
+			data:        reloadConfigData("launcher:v3", 4, 60, [2]string{"github", "github"}),
+			wantErr:     true,
+			wantRoutes:  "github,bitbucket",
+			wantImage:   "launcher:v2",
+			wantTTL:     60,
+			wantWorkers: 4,
+		},
+		{
+			name:        "unknown field keeps the current one",
+			data:        reloadConfigData("launcher:v3", 4, 60) + "  unknown: true\n",
+			wantErr:     true,
+			wantRoutes:  "github,bitbucket",
+			wantImage:   "launcher:v2",
+			wantTTL:     60,
+			wantWorkers: 4,
+		},
+		{
+			name: "settings that require a restart ignored",
+			data: reloadConfigData("launcher:v2", 8, 60, [2]string{"github", "/github/v2"}, [2]string{"bitbucket", "/bitbucket"}) +
+				"  async: true\n  queue:\n    type: memory\n    size: 16\n  admin:\n    token:\n      name: webhook-admin\n      key: token\n",
+			wantIgnored: "admin,async,queue,workers",
+			wantRoutes:  "github,bitbucket",
+			wantImage:   "launcher:v2",
+			wantTTL:     60,
+			wantWorkers: 4,
+		},
+	}
+
+	for _, step := range steps {
+		diff, err := ReloadConfig(writeConfig(t, step.data))
+		if (err != nil) != step.wantErr {
+			t.Fatalf("%s: ReloadConfig() error = %v, want error %v", step.name, err, step.wantErr)
+		}
+
+		got := []string{strings.Join(diff.Added, ","), strings.Join(diff.Removed, ","), strings.Join(diff.Changed, ","), strings.Join(diff.Ignored, ",")}
+		want := []string{step.wantAdded, step.wantRemoved, step.wantChanged, step.wantIgnored}
+		if strings.Join(got, ";") != strings.Join(want, ";") {
+			t.Errorf("%s: diff (added;removed;changed;ignored) = %s, want %s", step.name, strings.Join(got, ";"), strings.Join(want, ";"))
+		}
+		if routeNames() != step.wantRoutes {
+			t.Errorf("%s: routes = %s, want %s", step.name, routeNames(), step.wantRoutes)
+		}
+		if Launcher.Data.ImageName != step.wantImage {
+			t.Errorf("%s: launcher image = %s, want %s", step.name, Launcher.Data.ImageName, step.wantImage)
+		}
+		if Webhook.Data.Deduplication.TTL != step.wantTTL {
+			t.Errorf("%s: de-duplication TTL = %d, want %d", step.name, Webhook.Data.Deduplication.TTL, step.wantTTL)
+		}
+		if Webhook.Data.Workers != step.wantWorkers || Webhook.Data.Async || Webhook.Data.Admin.Token != nil {
+			t.Errorf("%s: settings that require a restart applied", step.name)
+		}
+	}
+}
+
+func TestReloadConfigExpressions(t *testing.T) {
+	// The compiled expressions are kept in a set, as the cache of the webhook listener
+	compiled := make(map[string]bool)
+	SetExpressionCompiler(func(expression string) error {
+		compiled[expression] = true
+		return nil
+	}, func() {
+		compiled = make(map[string]bool)
+	})
+	defer SetExpressionCompiler(nil, nil)
+
+	initial := writeConfig(t, reloadConfigData("launcher", 4, 3600, [2]string{"github", "/github"}))
+	if err := LoadWebhookConfig(initial); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { Webhook = WebhookConfig{} })
+	if !compiled["data.body.repository.clone_url"] {
+		t.Fatalf("expressions of the initial configuration not compiled: %v", compiled)
+	}
+
+	data := reloadConfigData("launcher", 4, 3600, [2]string{"github", "/github"})
+	data = strings.Replace(data, "data.body.repository.clone_url", "data.body.project.git_http_url", 1)
+	if _, err := ReloadConfig(writeConfig(t, data)); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"data.body.project.git_http_url", "data.headers['X-Github-Event'][0]"}
+	got := make([]string, 0, len(compiled))
+	for expression := range compiled {
+		got = append(got, expression)
+	}
+	sort.Strings(got)
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("compiled expressions = %v, want %v", got, want)
+	}
+}
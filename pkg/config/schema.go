@@ -0,0 +1,127 @@
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// configPkgPath is the import path of this package. Only its structs are described in detail by the schema and
+// checked by the validation, the external ones (e.g., Kubernetes types) are validated by their own decoders
+var configPkgPath = reflect.TypeOf(FileConfig{}).PkgPath()
+
+// Schema returns the JSON Schema of the configuration file
+// It is generated from the configuration structs: the json tags name the properties, and the 'required', 'default'
+// and 'enum' tags add the constraints
+func Schema() ([]byte, error) {
+	schema := typeSchema(reflect.TypeOf(FileConfig{}))
+	schema["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	schema["title"] = "Pipe Manager configuration"
+
+	return json.MarshalIndent(schema, "", "  ")
+}
+
+// typeSchema returns the JSON Schema of the given type
+func typeSchema(t reflect.Type) map[string]interface{} {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	// A variable can be written as the CEL expression alone or as a map with the expression and the type
+	if t == reflect.TypeOf(Variable{}) {
+		return map[string]interface{}{
+			"oneOf": []interface{}{
+				map[string]interface{}{"type": "string"},
+				structSchema(t),
+			},
+		}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer", "minimum": 0}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": typeSchema(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": typeSchema(t.Elem())}
+	case reflect.Struct:
+		if t.PkgPath() != configPkgPath {
+			return map[string]interface{}{"type": "object"}
+		}
+		return structSchema(t)
+	default:
+		return map[string]interface{}{}
+	}
+}
+
+// structSchema returns the JSON Schema of a struct of this package
+// The fields of the embedded structs are properties of the struct itself
+func structSchema(t reflect.Type) map[string]interface{} {
+	properties := make(map[string]interface{})
+	var required []string
+
+	var addFields func(t reflect.Type)
+	addFields = func(t reflect.Type) {
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if field.Anonymous {
+				addFields(field.Type)
+				continue
+			}
+			name := jsonName(field)
+			if name == "" {
+				continue
+			}
+
+			property := typeSchema(field.Type)
+			if enum := field.Tag.Get("enum"); enum != "" {
+				property["enum"] = strings.Split(enum, ",")
+			}
+			if def, ok := field.Tag.Lookup("default"); ok {
+				property["default"] = defaultValue(field.Type, def)
+			}
+			if field.Tag.Get("required") == "true" {
+				required = append(required, name)
+				if field.Type.Kind() == reflect.Slice {
+					property["minItems"] = 1
+				}
+			}
+			properties[name] = property
+		}
+	}
+	addFields(t)
+
+	schema := map[string]interface{}{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// defaultValue converts the value of a 'default' tag to the type of its field
+func defaultValue(t reflect.Type, value string) interface{} {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if v, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return v
+		}
+	case reflect.Bool:
+		if v, err := strconv.ParseBool(value); err == nil {
+			return v
+		}
+	}
+	return value
+}
@@ -1,65 +1,297 @@
 package config
 
 import (
+	"encoding/json"
 	"errors"
-	"gopkg.in/yaml.v3"
-	"os"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
 )
 
 // Route defines a single webhook route configuration.
 // It captures the name, path, event type, and a list of event handlers.
 type Route struct {
-	Name          string  `yaml:"name"`                    // Name of the route (e.g., "github")
-	Path          string  `yaml:"path"`                    // Path endpoint (e.g., "/github")
-	EventType     string  `yaml:"eventType"`               // EventType is a CEL expression to determine the event
-	GitSecretName string  `yaml:"gitSecretName,omitempty"` // GitSecretName is the name of the secret containing the Git credentials
-	Events        []Event `yaml:"events"`                  // Events is a list of event handlers for this route
+	Name          string       `json:"name" required:"true"`      // Name of the route (e.g., "github")
+	Path          string       `json:"path" required:"true"`      // Path endpoint (e.g., "/github")
+	EventType     string       `json:"eventType" required:"true"` // EventType is a CEL expression to determine the event
+	When          string       `json:"when,omitempty"`            // When is a CEL condition that must be true to launch a pipeline (optional)
+	GitSecretName string       `json:"gitSecretName,omitempty"`   // GitSecretName is the name of the secret containing the Git credentials
+	DeliveryID    string       `json:"deliveryID,omitempty"`      // DeliveryID is a CEL expression to identify the delivery for de-duplication (optional)
+	Signature     *Signature   `json:"signature,omitempty"`       // Signature is the verification of the incoming requests (optional)
+	Concurrency   *Concurrency `json:"concurrency,omitempty"`     // Concurrency is the policy of the jobs of the route with the same key (optional)
+	Retention     *Retention   `json:"retention,omitempty"`       // Retention replaces the retention of the launcher for the jobs of the route (optional)
+	GitAuth       *GitAuth     `json:"gitAuth,omitempty"`         // GitAuth replaces the git authentication of the launcher for the jobs of the route (optional)
+	Events        []Event      `json:"events" required:"true"`    // Events is a list of event handlers for this route
+
+	PodTemplate *corev1.PodTemplateSpec `json:"podTemplate,omitempty"` // PodTemplate is merged over the launcher pod template for the jobs of this route (optional)
+}
+
+// Signature defines how the incoming requests of a route are verified.
+// It captures the verification scheme and the secret shared with the webhook provider. Header, algorithm, prefix and
+// encoding are only used by the generic "hmac" scheme.
+type Signature struct {
+	Type      string       `json:"type" required:"true" enum:"github,gitlab,bitbucket,hmac"` // Type is the verification scheme: "github", "gitlab", "bitbucket" or "hmac"
+	Header    string       `json:"header,omitempty"`                                         // Header is the request header containing the signature (e.g., "X-Signature")
+	Algorithm string       `json:"algorithm,omitempty" enum:"sha1,sha256,sha512"`            // Algorithm is the HMAC hash algorithm: "sha1", "sha256" (default) or "sha512"
+	Prefix    string       `json:"prefix,omitempty"`                                         // Prefix is removed from the header value before comparing (e.g., "sha256=")
+	Encoding  string       `json:"encoding,omitempty" enum:"hex,base64"`                     // Encoding of the signature: "hex" (default) or "base64"
+	Secret    SecretSource `json:"secret" required:"true"`                                   // Secret is where the shared secret is read from
+}
+
+// SecretSource defines where a secret value is read from.
+// Either a local file or a key inside a Kubernetes secret. The file takes precedence if both are set.
+type SecretSource struct {
+	File      string `json:"file,omitempty"`      // File is the path to a file containing the secret value
+	Name      string `json:"name,omitempty"`      // Name is the name of the Kubernetes secret
+	Key       string `json:"key,omitempty"`       // Key is the key of the value inside the Kubernetes secret
+	Namespace string `json:"namespace,omitempty"` // Namespace of the Kubernetes secret. Defaults to the launcher namespace
 }
 
 // Event represents a single event handler within a route.
-// It captures the event type, repository, commit, and variables.
+// It captures the event type, condition, repository, commit, and variables.
 type Event struct {
-	Type       string            `yaml:"type"`                 // Type of the event (e.g., "push")
-	Repository string            `yaml:"repository"`           // Repository name
-	Commit     string            `yaml:"commit,omitempty"`     // Commit hash (optional)
-	DiffCommit string            `yaml:"diffCommit,omitempty"` // Commit hash to compare with the current commit (optional)
-	Variables  map[string]string `yaml:"variables,omitempty"`  // Variables to be used in the event handler with its associated CEL expression (optional)
+	Type        string              `json:"type" required:"true"`       // Type of the event (e.g., "push")
+	When        string              `json:"when,omitempty"`             // When is a CEL condition that must be true to launch a pipeline (optional)
+	Repository  string              `json:"repository" required:"true"` // Repository name
+	Commit      string              `json:"commit,omitempty"`           // Commit hash (optional)
+	DiffCommit  string              `json:"diffCommit,omitempty"`       // Commit hash to compare with the current commit (optional)
+	Ref         string              `json:"ref,omitempty"`              // Ref is the branch, tag or ref to fetch the commit from (e.g., refs/pull/1/head) (optional)
+	MergeTarget string              `json:"mergeTarget,omitempty"`      // MergeTarget is the branch the commit is merged with before reading the pipelines (optional)
+	Variables   map[string]Variable `json:"variables,omitempty"`        // Variables to be used in the event handler with its associated CEL expression (optional)
+	Concurrency *Concurrency        `json:"concurrency,omitempty"`      // Concurrency overrides the concurrency policy of the route for this event (optional)
+}
+
+// Concurrency policies of the jobs with the same key
+const (
+	ConcurrencyAllow            = "allow"              // ConcurrencyAllow launches the job regardless of the running ones
+	ConcurrencyQueue            = "queue"              // ConcurrencyQueue waits for the running jobs to finish before starting the job
+	ConcurrencyCancelInProgress = "cancel-in-progress" // ConcurrencyCancelInProgress deletes the running jobs and their pipelines before launching the job
+)
+
+// Concurrency defines how the jobs with the same key run at the same time.
+// The key is a CEL expression (e.g., the repository and the ref), so the jobs of the same branch can be queued or
+// superseded by the newest one. The keys of different routes never match.
+type Concurrency struct {
+	Policy string `json:"policy" required:"true" enum:"allow,queue,cancel-in-progress"` // Policy is "allow", "queue" or "cancel-in-progress"
+	Key    string `json:"key" required:"true"`                                          // Key is a CEL expression identifying the jobs that cannot run at the same time
+}
+
+// Supported types of the variables
+const (
+	VariableTypeString = "string"
+	VariableTypeBool   = "bool"
+	VariableTypeInt    = "int"
+	VariableTypeDouble = "double"
+	VariableTypeList   = "list"
+	VariableTypeMap    = "map"
+)
+
+// Variable represents a variable of an event handler.
+// It captures the CEL expression and, optionally, the type the expression must return. It can be written as the CEL
+// expression alone or as a map with the expression and the type.
+type Variable struct {
+	Expression string `json:"expression" required:"true"`                            // Expression is the CEL expression to retrieve the value
+	Type       string `json:"type,omitempty" enum:"string,bool,int,double,list,map"` // Type is the type of the value: string, bool, int, double, list or map (optional)
+}
+
+// UnmarshalJSON allows to define a variable as the CEL expression alone
+func (v *Variable) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		v.Type = ""
+		return json.Unmarshal(data, &v.Expression)
+	}
+
+	type plainVariable Variable
+	return decodeStrict(data, (*plainVariable)(v))
+}
+
+// QueueConfig defines the queue where the accepted requests wait to be processed by the workers.
+type QueueConfig struct {
+	Type string `json:"type,omitempty" default:"memory" enum:"memory,bolt"` // Type of the queue: "memory" (default) or "bolt" for a durable queue on disk
+	Path string `json:"path,omitempty"`                                     // Path is the database file of the durable queue
+	Size int    `json:"size,omitempty"`                                     // Size is the capacity of the memory queue. Defaults to the number of workers
+}
+
+// DeduplicationConfig defines how long the delivery IDs are remembered to discard the retried deliveries.
+type DeduplicationConfig struct {
+	TTL int `json:"ttl,omitempty" default:"3600"` // TTL is the time in seconds a delivery ID is remembered. Defaults to 3600
+}
+
+// AdminConfig defines the access to the administration endpoints of the webhook listener.
+type AdminConfig struct {
+	Token *SecretSource `json:"token,omitempty"` // Token is the bearer token required by the endpoints. They are disabled if not set
 }
 
 // WebhookStruct defines the webhook configuration.
-// It captures the number of workers, logging configuration, and a list of routes.
+// It captures the number of workers, the processing mode, the queue, de-duplication and administration configuration,
+// and a list of routes.
 type WebhookStruct struct {
-	Workers int     `yaml:"workers"` // Workers is the number of workers to process the incoming requests
-	Routes  []Route `yaml:"routes"`  // Routes is a list of webhook routes
+	Workers       int                 `json:"workers" default:"4"`     // Workers is the number of workers to process the incoming requests. Defaults to 4
+	Async         bool                `json:"async,omitempty"`         // Async answers 202 as soon as the request is queued instead of waiting for the job
+	Queue         QueueConfig         `json:"queue,omitempty"`         // Queue is the configuration of the queue of incoming requests
+	Deduplication DeduplicationConfig `json:"deduplication,omitempty"` // Deduplication is the configuration of the delivery de-duplication
+	Admin         AdminConfig         `json:"admin,omitempty"`         // Admin is the configuration of the administration endpoints
+	Routes        []Route             `json:"routes"`                  // Routes is a list of webhook routes
 }
 
 // WebhookConfig defines the webhook configuration.
 // It captures the webhook configuration data from the root of the configuration file.
 type WebhookConfig struct {
-	Data WebhookStruct `yaml:"webhook"` // Data is the webhook configuration
+	Data WebhookStruct `json:"webhook"` // Data is the webhook configuration
 }
 
 var Webhook WebhookConfig // Webhook is the global webhook configuration
 
 // LoadWebhookConfig loads the webhook configuration from the given file.
-// It returns an error if the configuration cannot be loaded.
-// The configuration file is expected to be in YAML format.
+// It returns an error if the configuration cannot be loaded, it is not valid or any of its CEL expressions cannot be
+// compiled by the expression compiler (see SetExpressionCompiler).
+// The configuration file is expected to be in YAML format if a file is provided.
+// The environment variables and --set flags override the values of the file (see SetOverrides).
 // The configuration is loaded into the global Webhook variable.
 func LoadWebhookConfig(configFile string) error {
-	yamlFile, err := os.ReadFile(configFile)
+	var webhook WebhookConfig
+	err := loadConfig(configFile, "webhook", &webhook, func(file *FileConfig) {
+		webhook = file.WebhookConfig
+	})
 	if err != nil {
 		return err
 	}
 
-	if err := yaml.Unmarshal(yamlFile, &Webhook); err != nil {
-		return err
+	Webhook = webhook
+	return nil
+}
+
+// validate checks the cross-field rules of the webhook configuration and compiles its CEL expressions
+func (w *WebhookConfig) validate() error {
+	var errs []error
+
+	if w.Data.Workers <= 0 {
+		errs = append(errs, errors.New("webhook.workers: must be greater than 0"))
+	}
+	if w.Data.Queue.Type == "bolt" && w.Data.Queue.Path == "" {
+		errs = append(errs, errors.New("webhook.queue.path: required for queue type 'bolt'"))
 	}
 
-	if Webhook.Data.Workers == 0 {
-		return errors.New("the configuration had not been loaded correctly")
+	names := make(map[string]bool)
+	paths := make(map[string]bool)
+	for i, route := range w.Data.Routes {
+		location := fmt.Sprintf("webhook.routes[%d]", i)
+		if names[route.Name] {
+			errs = append(errs, fmt.Errorf("%s.name: duplicated route name '%s'", location, route.Name))
+		}
+		names[route.Name] = true
+		if paths[route.Path] {
+			errs = append(errs, fmt.Errorf("%s.path: duplicated route path '%s'", location, route.Path))
+		}
+		paths[route.Path] = true
+		if route.Path != "" && !strings.HasPrefix(route.Path, "/") {
+			errs = append(errs, fmt.Errorf("%s.path: must start with '/'", location))
+		}
+		if route.Signature != nil {
+			if route.Signature.Type == "hmac" && route.Signature.Header == "" {
+				errs = append(errs, fmt.Errorf("%s.signature.header: required for signature type 'hmac'", location))
+			}
+			if route.Signature.Secret != (SecretSource{}) {
+				errs = append(errs, route.Signature.Secret.validate(location+".signature.secret"))
+			}
+		}
+		errs = append(errs, validatePodTemplate(location+".podTemplate", route.PodTemplate))
+		if route.GitAuth != nil {
+			errs = append(errs, route.GitAuth.validate(location+".gitAuth"))
+		}
+		if route.Retention != nil {
+			errs = append(errs, route.Retention.validate(location+".retention"))
+		}
+	}
+	if w.Data.Admin.Token != nil {
+		errs = append(errs, w.Data.Admin.Token.validate("webhook.admin.token"))
 	}
 
+	errs = append(errs, w.Data.compileExpressions())
+
+	return errors.Join(errs...)
+}
+
+// validate checks the secret is read from a file or from a key of a Kubernetes secret
+func (s *SecretSource) validate(location string) error {
+	if s.File == "" && (s.Name == "" || s.Key == "") {
+		return fmt.Errorf("%s: either file or name and key are required", location)
+	}
 	return nil
 }
 
-// TODO: Normnalize config loader for webhook like other components (e.g., launcher, common)
+var (
+	expressionCompiler func(expression string) error // expressionCompiler compiles a CEL expression of the webhook configuration (see SetExpressionCompiler)
+	expressionReset    func()                        // expressionReset drops the compiled CEL expressions (see SetExpressionCompiler)
+)
+
+// SetExpressionCompiler sets the function that compiles the CEL expressions of the webhook configuration when it is
+// loaded, validated or reloaded, so the invalid expressions are reported along with where they are defined.
+// The reset function drops the compiled expressions when the configuration is reloaded, so the ones of the previous
+// configuration are not kept. It can be nil if the compiled expressions are not cached.
+// The CEL environment belongs to the webhook listener, so the expressions are not checked if it is not set.
+func SetExpressionCompiler(compile func(expression string) error, reset func()) {
+	expressionCompiler = compile
+	expressionReset = reset
+}
+
+// compileExpressions compiles all the CEL expressions of the webhook configuration with the expression compiler, so
+// they are ready to be evaluated when a request arrives.
+// It returns an error listing every invalid expression along with where it is defined.
+func (w *WebhookStruct) compileExpressions() error {
+	if expressionCompiler == nil {
+		return nil
+	}
+	var errs []error
+
+	compile := func(location, expression string) {
+		if expression == "" {
+			return
+		}
+		if err := expressionCompiler(expression); err != nil {
+			errs = append(errs, fmt.Errorf("%s '%s': %w", location, expression, err))
+		}
+	}
+
+	for _, route := range w.Routes {
+		routeLocation := fmt.Sprintf("webhook.routes[%s]", route.Name)
+		compile(routeLocation+".eventType", route.EventType)
+		compile(routeLocation+".when", route.When)
+		compile(routeLocation+".gitSecretName", route.GitSecretName)
+		compile(routeLocation+".deliveryID", route.DeliveryID)
+		if route.Concurrency != nil {
+			compile(routeLocation+".concurrency.key", route.Concurrency.Key)
+		}
+
+		for _, event := range route.Events {
+			eventLocation := fmt.Sprintf("%s.events[%s]", routeLocation, event.Type)
+			compile(eventLocation+".when", event.When)
+			compile(eventLocation+".repository", event.Repository)
+			compile(eventLocation+".commit", event.Commit)
+			compile(eventLocation+".diffCommit", event.DiffCommit)
+			compile(eventLocation+".ref", event.Ref)
+			compile(eventLocation+".mergeTarget", event.MergeTarget)
+			if event.Concurrency != nil {
+				compile(eventLocation+".concurrency.key", event.Concurrency.Key)
+			}
+
+			// Sort the variables to report the errors always in the same order
+			names := make([]string, 0, len(event.Variables))
+			for name := range event.Variables {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				variable := event.Variables[name]
+				compile(fmt.Sprintf("%s.variables[%s]", eventLocation, name), variable.Expression)
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid CEL expressions in the webhook configuration:\n%w", errors.Join(errs...))
+	}
+	return nil
+}